@@ -0,0 +1,179 @@
+// Parses Estonian addresses written in the various formats used
+// by pharmacy chains into structured and consistently named parts
+package address
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Common abbreviations of Estonian street types
+var streetAbbreviations = map[string]string{
+	"mnt": "maantee",
+	"pst": "puiestee",
+	"tn":  "tänav",
+	"pk":  "põik",
+	"al":  "allee",
+}
+
+// Settlement type suffixes, which are not always present in the addresses
+var settlementSuffixes = []string{" linn", " vald", " alevik", " alev", " küla"}
+
+// Matches a street followed by a house number, e.g. "Nõmme tee 23a" or "Pikk 2/4"
+var houseNumberRegex = regexp.MustCompile(`^(.*\S)\s+(\d+[a-zA-Z]?(?:[/-]\d+[a-zA-Z]?)?)$`)
+
+var postalCodeRegex = regexp.MustCompile(`^\d{5}$`)
+
+// Structured Estonian address
+type Address struct {
+	// Street name, e.g. "Linnamäe tee"
+	Street string
+	// House number, e.g. "3" or "23a"
+	HouseNumber string
+	// City district, e.g. "Lasnamäe"
+	District string
+	// Settlement (city, town or village), e.g. "Tallinn"
+	Settlement string
+	// Official name of the municipality, e.g. "Tallinna linn" or "Saue vald"
+	Municipality string
+	// Canonical name of the county, e.g. "Harjumaa"
+	County     string
+	PostalCode string
+}
+
+// Parses an address from one or more comma separated address strings, e.g.
+// Parse("Linnamäe tee 3, Lasnamäe", "Tallinn", "Harju maakond"). Parts given
+// earlier take precedence, while missing settlement, municipality and county
+// are inferred from the known municipalities and settlements where possible
+func Parse(parts ...string) Address {
+	var addr Address
+	first := true
+	for _, part := range parts {
+		for _, component := range strings.Split(part, ",") {
+			component = strings.Join(strings.Fields(component), " ")
+			if component == "" {
+				continue
+			}
+
+			addr.addComponent(component, first)
+			first = false
+		}
+	}
+
+	addr.infer()
+	return addr
+}
+
+// Assigns a single component of the address to the first matching empty field
+func (addr *Address) addComponent(component string, first bool) {
+	key := strings.ToLower(component)
+	if postalCodeRegex.MatchString(component) {
+		setOnce(&addr.PostalCode, component)
+		return
+	}
+
+	if county, ok := countiesByKey[key]; ok {
+		setOnce(&addr.County, county)
+		return
+	}
+
+	if district, ok := districtsByKey[strings.TrimSuffix(key, " linnaosa")]; ok {
+		setOnce(&addr.District, district)
+		return
+	} else if strings.HasSuffix(key, " linnaosa") {
+		setOnce(&addr.District, component[:len(component)-len(" linnaosa")])
+		return
+	}
+
+	if m, ok := municipalitiesByKey[key]; ok {
+		setOnce(&addr.Municipality, m.Name)
+		return
+	} else if strings.HasSuffix(key, " vald") || strings.HasSuffix(key, " linn") {
+		setOnce(&addr.Municipality, component)
+		return
+	}
+
+	if groups := houseNumberRegex.FindStringSubmatch(component); groups != nil && addr.Street == "" {
+		addr.Street = groups[1]
+		addr.HouseNumber = groups[2]
+		return
+	}
+
+	if _, ok := settlementsByKey[key]; !ok && first {
+		addr.Street = component
+		return
+	}
+
+	for _, suffix := range []string{" küla", " alevik", " alev"} {
+		if strings.HasSuffix(key, suffix) {
+			component = component[:len(component)-len(suffix)]
+			break
+		}
+	}
+	setOnce(&addr.Settlement, component)
+}
+
+// Fills in the settlement, municipality and county implied by other fields
+func (addr *Address) infer() {
+	if addr.Settlement == "" && addr.District != "" && districtsByKey[strings.ToLower(addr.District)] != "" {
+		addr.Settlement = "Tallinn"
+	}
+
+	if addr.Municipality == "" {
+		if m, ok := settlementsByKey[strings.ToLower(addr.Settlement)]; ok {
+			addr.Municipality = m.Name
+		}
+	}
+
+	m, ok := municipalitiesByKey[strings.ToLower(addr.Municipality)]
+	if !ok {
+		return
+	}
+
+	if addr.Settlement == "" {
+		addr.Settlement = m.Settlement
+	}
+	if addr.County == "" {
+		addr.County = m.County
+	}
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// Returns the street and house number, e.g. "Linnamäe tee 3"
+func (addr Address) StreetAddress() string {
+	return strings.TrimSpace(addr.Street + " " + addr.HouseNumber)
+}
+
+// Returns the settlement or, if the settlement is unknown, the municipality
+func (addr Address) City() string {
+	if addr.Settlement != "" {
+		return addr.Settlement
+	}
+	return addr.Municipality
+}
+
+// Normalizes street address, e.g. "Tallinna mnt. 41" into "tallinna maantee 41"
+// so that the same address written differently would map to the same key
+func StreetKey(address string) string {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(address, ".", " ")))
+	for i := range fields {
+		if full, ok := streetAbbreviations[fields[i]]; ok {
+			fields[i] = full
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// Normalizes settlement name, e.g. "Narva linn" into "narva"
+func SettlementKey(settlement string) string {
+	key := strings.Join(strings.Fields(strings.ToLower(settlement)), " ")
+	for _, suffix := range settlementSuffixes {
+		key = strings.TrimSuffix(key, suffix)
+	}
+	return key
+}
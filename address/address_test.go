@@ -0,0 +1,111 @@
+package address_test
+
+import (
+	"pharmafinder/address"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_FullAddress(t *testing.T) {
+	addr := address.Parse("Akadeemia tee 35, Mustamäe linnaosa, Tallinn, Harju maakond, 12618")
+
+	assert.Equal(t, address.Address{
+		Street:       "Akadeemia tee",
+		HouseNumber:  "35",
+		District:     "Mustamäe",
+		Settlement:   "Tallinn",
+		Municipality: "Tallinna linn",
+		County:       "Harjumaa",
+		PostalCode:   "12618",
+	}, addr)
+	assert.Equal(t, "Akadeemia tee 35", addr.StreetAddress())
+	assert.Equal(t, "Tallinn", addr.City())
+}
+
+func TestParse_SeparateFields(t *testing.T) {
+	addr := address.Parse("Nõmme tee 23a", "Tallinn", "Harjumaa")
+
+	assert.Equal(t, "Nõmme tee", addr.Street)
+	assert.Equal(t, "23a", addr.HouseNumber)
+	assert.Equal(t, "Tallinn", addr.Settlement)
+	assert.Equal(t, "Harjumaa", addr.County)
+}
+
+func TestParse_EarlierPartsTakePrecedence(t *testing.T) {
+	addr := address.Parse("Tallinna mnt 41, Narva, Ida-Viru maakond", "Narva linn", "Harjumaa")
+
+	assert.Equal(t, "Tallinna mnt 41", addr.StreetAddress())
+	assert.Equal(t, "Narva", addr.Settlement)
+	assert.Equal(t, "Narva linn", addr.Municipality)
+	assert.Equal(t, "Ida-Virumaa", addr.County)
+}
+
+func TestParse_DistrictImpliesTallinn(t *testing.T) {
+	addr := address.Parse("Linnamäe tee 3", "Lasnamäe")
+
+	assert.Equal(t, "Lasnamäe", addr.District)
+	assert.Equal(t, "Tallinn", addr.City())
+	assert.Equal(t, "Harjumaa", addr.County)
+}
+
+func TestParse_UrbanMunicipality(t *testing.T) {
+	addr := address.Parse("Riia 2, Tartu linn")
+
+	assert.Equal(t, "Tartu", addr.Settlement)
+	assert.Equal(t, "Tartu linn", addr.Municipality)
+	assert.Equal(t, "Tartumaa", addr.County)
+}
+
+func TestParse_RuralSettlement(t *testing.T) {
+	addr := address.Parse("Kihelkonna mnt 3, Kuressaare")
+
+	assert.Equal(t, "Kuressaare", addr.Settlement)
+	assert.Equal(t, "Saaremaa vald", addr.Municipality)
+	assert.Equal(t, "Saaremaa", addr.County)
+}
+
+func TestParse_MunicipalityWithoutSettlement(t *testing.T) {
+	addr := address.Parse("Instituudi tee 132, Saue vald")
+
+	assert.Equal(t, "", addr.Settlement)
+	assert.Equal(t, "Saue vald", addr.Municipality)
+	assert.Equal(t, "Saue vald", addr.City())
+	assert.Equal(t, "Harjumaa", addr.County)
+}
+
+func TestParse_VillageSuffix(t *testing.T) {
+	addr := address.Parse("Pargi tee 1, Alliku küla, Saue vald, Harju mk")
+
+	assert.Equal(t, "Alliku", addr.Settlement)
+	assert.Equal(t, "Saue vald", addr.Municipality)
+	assert.Equal(t, "Harjumaa", addr.County)
+}
+
+func TestParse_SettlementBeforeStreet(t *testing.T) {
+	addr := address.Parse("Kohila, Lõuna 2")
+
+	assert.Equal(t, "Lõuna 2", addr.StreetAddress())
+	assert.Equal(t, "Kohila", addr.Settlement)
+	assert.Equal(t, "Raplamaa", addr.County)
+}
+
+func TestParse_UnknownLocation(t *testing.T) {
+	addr := address.Parse("Turu plats", "Atlantis")
+
+	assert.Equal(t, "Turu plats", addr.Street)
+	assert.Equal(t, "", addr.HouseNumber)
+	assert.Equal(t, "Atlantis", addr.Settlement)
+	assert.Equal(t, "", addr.Municipality)
+	assert.Equal(t, "", addr.County)
+}
+
+func TestStreetKey(t *testing.T) {
+	assert.Equal(t, "tallinna maantee 41", address.StreetKey("Tallinna mnt. 41"))
+	assert.Equal(t, "kotzebue tänav 9", address.StreetKey("Kotzebue  tn 9"))
+}
+
+func TestSettlementKey(t *testing.T) {
+	assert.Equal(t, "narva", address.SettlementKey("Narva linn"))
+	assert.Equal(t, "alliku", address.SettlementKey(" Alliku  küla"))
+}
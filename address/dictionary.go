@@ -0,0 +1,240 @@
+package address
+
+import "strings"
+
+type county struct {
+	// Canonical name of the county, e.g. "Harjumaa"
+	Name string
+	// Stem of the official name, e.g. "Harju" for "Harju maakond"
+	Stem string
+}
+
+type municipality struct {
+	// Official name of the municipality, e.g. "Saue vald" or "Tallinna linn"
+	Name string
+	// Canonical name of the county the municipality belongs to
+	County string
+	// Settlement of an urban municipality, e.g. "Tallinn" for "Tallinna linn"
+	Settlement string
+}
+
+// Estonian counties
+var counties = []county{
+	{Name: "Harjumaa", Stem: "Harju"},
+	{Name: "Hiiumaa", Stem: "Hiiu"},
+	{Name: "Ida-Virumaa", Stem: "Ida-Viru"},
+	{Name: "Jõgevamaa", Stem: "Jõgeva"},
+	{Name: "Järvamaa", Stem: "Järva"},
+	{Name: "Läänemaa", Stem: "Lääne"},
+	{Name: "Lääne-Virumaa", Stem: "Lääne-Viru"},
+	{Name: "Põlvamaa", Stem: "Põlva"},
+	{Name: "Pärnumaa", Stem: "Pärnu"},
+	{Name: "Raplamaa", Stem: "Rapla"},
+	{Name: "Saaremaa", Stem: "Saare"},
+	{Name: "Tartumaa", Stem: "Tartu"},
+	{Name: "Valgamaa", Stem: "Valga"},
+	{Name: "Viljandimaa", Stem: "Viljandi"},
+	{Name: "Võrumaa", Stem: "Võru"},
+}
+
+// Estonian municipalities since the administrative reform of 2017
+var municipalities = []municipality{
+	{Name: "Tallinna linn", County: "Harjumaa", Settlement: "Tallinn"},
+	{Name: "Keila linn", County: "Harjumaa", Settlement: "Keila"},
+	{Name: "Loksa linn", County: "Harjumaa", Settlement: "Loksa"},
+	{Name: "Maardu linn", County: "Harjumaa", Settlement: "Maardu"},
+	{Name: "Anija vald", County: "Harjumaa"},
+	{Name: "Harku vald", County: "Harjumaa"},
+	{Name: "Jõelähtme vald", County: "Harjumaa"},
+	{Name: "Kiili vald", County: "Harjumaa"},
+	{Name: "Kose vald", County: "Harjumaa"},
+	{Name: "Kuusalu vald", County: "Harjumaa"},
+	{Name: "Lääne-Harju vald", County: "Harjumaa"},
+	{Name: "Raasiku vald", County: "Harjumaa"},
+	{Name: "Rae vald", County: "Harjumaa"},
+	{Name: "Saku vald", County: "Harjumaa"},
+	{Name: "Saue vald", County: "Harjumaa"},
+	{Name: "Viimsi vald", County: "Harjumaa"},
+	{Name: "Hiiumaa vald", County: "Hiiumaa"},
+	{Name: "Narva linn", County: "Ida-Virumaa", Settlement: "Narva"},
+	{Name: "Narva-Jõesuu linn", County: "Ida-Virumaa", Settlement: "Narva-Jõesuu"},
+	{Name: "Kohtla-Järve linn", County: "Ida-Virumaa", Settlement: "Kohtla-Järve"},
+	{Name: "Sillamäe linn", County: "Ida-Virumaa", Settlement: "Sillamäe"},
+	{Name: "Alutaguse vald", County: "Ida-Virumaa"},
+	{Name: "Jõhvi vald", County: "Ida-Virumaa"},
+	{Name: "Lüganuse vald", County: "Ida-Virumaa"},
+	{Name: "Toila vald", County: "Ida-Virumaa"},
+	{Name: "Jõgeva vald", County: "Jõgevamaa"},
+	{Name: "Mustvee vald", County: "Jõgevamaa"},
+	{Name: "Põltsamaa vald", County: "Jõgevamaa"},
+	{Name: "Paide linn", County: "Järvamaa", Settlement: "Paide"},
+	{Name: "Järva vald", County: "Järvamaa"},
+	{Name: "Türi vald", County: "Järvamaa"},
+	{Name: "Haapsalu linn", County: "Läänemaa", Settlement: "Haapsalu"},
+	{Name: "Lääne-Nigula vald", County: "Läänemaa"},
+	{Name: "Vormsi vald", County: "Läänemaa"},
+	{Name: "Rakvere linn", County: "Lääne-Virumaa", Settlement: "Rakvere"},
+	{Name: "Haljala vald", County: "Lääne-Virumaa"},
+	{Name: "Kadrina vald", County: "Lääne-Virumaa"},
+	{Name: "Rakvere vald", County: "Lääne-Virumaa"},
+	{Name: "Tapa vald", County: "Lääne-Virumaa"},
+	{Name: "Vinni vald", County: "Lääne-Virumaa"},
+	{Name: "Viru-Nigula vald", County: "Lääne-Virumaa"},
+	{Name: "Väike-Maarja vald", County: "Lääne-Virumaa"},
+	{Name: "Kanepi vald", County: "Põlvamaa"},
+	{Name: "Põlva vald", County: "Põlvamaa"},
+	{Name: "Räpina vald", County: "Põlvamaa"},
+	{Name: "Pärnu linn", County: "Pärnumaa", Settlement: "Pärnu"},
+	{Name: "Häädemeeste vald", County: "Pärnumaa"},
+	{Name: "Kihnu vald", County: "Pärnumaa"},
+	{Name: "Lääneranna vald", County: "Pärnumaa"},
+	{Name: "Põhja-Pärnumaa vald", County: "Pärnumaa"},
+	{Name: "Saarde vald", County: "Pärnumaa"},
+	{Name: "Tori vald", County: "Pärnumaa"},
+	{Name: "Kehtna vald", County: "Raplamaa"},
+	{Name: "Kohila vald", County: "Raplamaa"},
+	{Name: "Märjamaa vald", County: "Raplamaa"},
+	{Name: "Rapla vald", County: "Raplamaa"},
+	{Name: "Muhu vald", County: "Saaremaa"},
+	{Name: "Ruhnu vald", County: "Saaremaa"},
+	{Name: "Saaremaa vald", County: "Saaremaa"},
+	{Name: "Tartu linn", County: "Tartumaa", Settlement: "Tartu"},
+	{Name: "Elva vald", County: "Tartumaa"},
+	{Name: "Kastre vald", County: "Tartumaa"},
+	{Name: "Luunja vald", County: "Tartumaa"},
+	{Name: "Nõo vald", County: "Tartumaa"},
+	{Name: "Peipsiääre vald", County: "Tartumaa"},
+	{Name: "Tartu vald", County: "Tartumaa"},
+	{Name: "Otepää vald", County: "Valgamaa"},
+	{Name: "Tõrva vald", County: "Valgamaa"},
+	{Name: "Valga vald", County: "Valgamaa"},
+	{Name: "Viljandi linn", County: "Viljandimaa", Settlement: "Viljandi"},
+	{Name: "Mulgi vald", County: "Viljandimaa"},
+	{Name: "Põhja-Sakala vald", County: "Viljandimaa"},
+	{Name: "Viljandi vald", County: "Viljandimaa"},
+	{Name: "Võru linn", County: "Võrumaa", Settlement: "Võru"},
+	{Name: "Antsla vald", County: "Võrumaa"},
+	{Name: "Rõuge vald", County: "Võrumaa"},
+	{Name: "Setomaa vald", County: "Võrumaa"},
+	{Name: "Võru vald", County: "Võrumaa"},
+}
+
+// Towns and boroughs of rural municipalities, which are commonly written without
+// their municipality. Settlements of urban municipalities are not listed here
+var settlements = map[string]string{
+	"Abja-Paluoja":  "Mulgi vald",
+	"Antsla":        "Antsla vald",
+	"Aruküla":       "Raasiku vald",
+	"Aseri":         "Viru-Nigula vald",
+	"Elva":          "Elva vald",
+	"Haabneeme":     "Viimsi vald",
+	"Haljala":       "Haljala vald",
+	"Häädemeeste":   "Häädemeeste vald",
+	"Iisaku":        "Alutaguse vald",
+	"Järva-Jaani":   "Järva vald",
+	"Jõelähtme":     "Jõelähtme vald",
+	"Jõgeva":        "Jõgeva vald",
+	"Jõhvi":         "Jõhvi vald",
+	"Jüri":          "Rae vald",
+	"Kadrina":       "Kadrina vald",
+	"Kallaste":      "Peipsiääre vald",
+	"Kanepi":        "Kanepi vald",
+	"Karksi-Nuia":   "Mulgi vald",
+	"Kehra":         "Anija vald",
+	"Kehtna":        "Kehtna vald",
+	"Keila-Joa":     "Lääne-Harju vald",
+	"Kihnu":         "Kihnu vald",
+	"Kiili":         "Kiili vald",
+	"Kilingi-Nõmme": "Saarde vald",
+	"Kiviõli":       "Lüganuse vald",
+	"Kohila":        "Kohila vald",
+	"Kohtla-Nõmme":  "Toila vald",
+	"Kose":          "Kose vald",
+	"Kose-Uuemõisa": "Kose vald",
+	"Kunda":         "Viru-Nigula vald",
+	"Kuressaare":    "Saaremaa vald",
+	"Kuusalu":       "Kuusalu vald",
+	"Kärdla":        "Hiiumaa vald",
+	"Laagri":        "Saue vald",
+	"Lihula":        "Lääneranna vald",
+	"Loo":           "Jõelähtme vald",
+	"Luunja":        "Luunja vald",
+	"Lüganuse":      "Lüganuse vald",
+	"Muhu":          "Muhu vald",
+	"Mustvee":       "Mustvee vald",
+	"Märjamaa":      "Märjamaa vald",
+	"Mõisaküla":     "Mulgi vald",
+	"Nõo":           "Nõo vald",
+	"Orissaare":     "Saaremaa vald",
+	"Otepää":        "Otepää vald",
+	"Paldiski":      "Lääne-Harju vald",
+	"Peetri":        "Rae vald",
+	"Pärnu-Jaagupi": "Põhja-Pärnumaa vald",
+	"Põltsamaa":     "Põltsamaa vald",
+	"Põlva":         "Põlva vald",
+	"Püssi":         "Lüganuse vald",
+	"Raasiku":       "Raasiku vald",
+	"Rapla":         "Rapla vald",
+	"Risti":         "Lääne-Nigula vald",
+	"Ruhnu":         "Ruhnu vald",
+	"Räpina":        "Räpina vald",
+	"Rõuge":         "Rõuge vald",
+	"Saku":          "Saku vald",
+	"Saue":          "Saue vald",
+	"Sindi":         "Tori vald",
+	"Suure-Jaani":   "Põhja-Sakala vald",
+	"Tabasalu":      "Harku vald",
+	"Taebla":        "Lääne-Nigula vald",
+	"Tamsalu":       "Tapa vald",
+	"Tapa":          "Tapa vald",
+	"Toila":         "Toila vald",
+	"Tori":          "Tori vald",
+	"Tõrva":         "Tõrva vald",
+	"Türi":          "Türi vald",
+	"Valga":         "Valga vald",
+	"Vastseliina":   "Võru vald",
+	"Viimsi":        "Viimsi vald",
+	"Vinni":         "Vinni vald",
+	"Vormsi":        "Vormsi vald",
+	"Väike-Maarja":  "Väike-Maarja vald",
+	"Vändra":        "Põhja-Pärnumaa vald",
+	"Võhma":         "Põhja-Sakala vald",
+	"Ülenurme":      "Kastre vald",
+}
+
+// Lookup tables keyed by lowercased names
+var (
+	countiesByKey       = make(map[string]string)
+	municipalitiesByKey = make(map[string]municipality)
+	settlementsByKey    = make(map[string]municipality)
+	districtsByKey      = make(map[string]string)
+)
+
+// Districts of Tallinn, which are often written in place of the city
+var tallinnDistricts = []string{
+	"Haabersti", "Kesklinn", "Kristiine", "Lasnamäe", "Mustamäe", "Nõmme", "Pirita", "Põhja-Tallinn",
+}
+
+func init() {
+	for _, c := range counties {
+		stem := strings.ToLower(c.Stem)
+		for _, key := range []string{stem + "maa", stem + " maakond", stem + " mk", stem + " mk."} {
+			countiesByKey[key] = c.Name
+		}
+	}
+
+	for _, m := range municipalities {
+		municipalitiesByKey[strings.ToLower(m.Name)] = m
+		if m.Settlement != "" {
+			settlementsByKey[strings.ToLower(m.Settlement)] = m
+		}
+	}
+
+	for settlement, name := range settlements {
+		settlementsByKey[strings.ToLower(settlement)] = municipalitiesByKey[strings.ToLower(name)]
+	}
+
+	for _, district := range tallinnDistricts {
+		districtsByKey[strings.ToLower(district)] = district
+	}
+}
@@ -0,0 +1,200 @@
+package chains
+
+import (
+	"net/http"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+
+	"github.com/rs/zerolog"
+)
+
+type ChainAdminController struct {
+	repo       db.ChainRepository
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvideChainAdminController(repo db.ChainRepository, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &ChainAdminController{
+		repo:       repo,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *ChainAdminController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[ChainAdminController](handler.GetChains, "/admin/chains", []string{"GET"}),
+		web.NewRequestsHandler[ChainAdminController](handler.PostChain, "/admin/chains", []string{"POST"}),
+		web.NewRequestsHandler[ChainAdminController](handler.PutChain, "/admin/chains/{id}", []string{"PUT"}),
+		web.NewRequestsHandler[ChainAdminController](handler.DeleteChain, "/admin/chains/{id}", []string{"DELETE"}),
+	}
+}
+
+// Get all pharmacy chains including inactive ones
+//
+// Path: `GET /api/v1/admin/chains`
+//
+// @Summary			Query all pharmacy chains
+// @Description		Endpoint for querying all pharmacy chains including inactive ones
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Success 		200 {array} dto.ChainDTO
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/chains [get]
+func (handler *ChainAdminController) GetChains(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	chains, err := handler.repo.FindChains(true).QueryAll()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, chains, nil
+}
+
+// Create a new pharmacy chain
+//
+// Path: `POST /api/v1/admin/chains`
+//
+// @Summary			Create a new pharmacy chain
+// @Description		Endpoint for adding a new pharmacy chain into the chain registry
+// @Tags			Admin
+// @Accepts 		json
+// @Produce 		json
+// @Security		Bearer
+// @Param			request body dto.ChainCreationDTO true "Chain creation request body"
+// @Success 		201 {object} entity.Chain
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/chains [post]
+func (handler *ChainAdminController) PostChain(details *web.HttpRequestDetails[dto.ChainCreationDTO]) (int, interface{}, error) {
+	name, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	chain := entity.Chain{}
+	applyChainDTO(&chain, details.Body)
+	err := handler.repo.Store(&chain)
+	if db.IsUniqueViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Chain with given name already exists"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	handler.logger.Info().Msgf("Chain %s was created by %s", chain.Name, name)
+	return http.StatusCreated, chain, nil
+}
+
+// Modify an existing pharmacy chain
+//
+// Path: `PUT /api/v1/admin/chains/{id}`
+//
+// @Summary			Modify an existing pharmacy chain
+// @Description		Endpoint for modifying an existing pharmacy chain. Renaming a chain renames it for all of its pharmacies
+// @Tags			Admin
+// @Accepts 		json
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Chain ID"
+// @Param			request body dto.ChainCreationDTO true "Chain modification request body"
+// @Success 		200 {object} entity.Chain
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/chains/{id} [put]
+func (handler *ChainAdminController) PutChain(details *web.HttpRequestDetails[dto.ChainCreationDTO]) (int, interface{}, error) {
+	name, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	chain, err := handler.repo.FindChainByID(id).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if chain == nil {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	}
+
+	applyChainDTO(chain, details.Body)
+	err = handler.repo.Store(chain)
+	if db.IsUniqueViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Chain with given name already exists"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	handler.logger.Info().Msgf("Chain %s was modified by %s", chain.Name, name)
+	return http.StatusOK, chain, nil
+}
+
+// Delete a pharmacy chain
+//
+// Path: `DELETE /api/v1/admin/chains/{id}`
+//
+// @Summary			Delete a pharmacy chain
+// @Description		Endpoint for deleting a pharmacy chain without any pharmacies. Chains with pharmacies can only be deactivated
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Chain ID"
+// @Success 		200 {object} entity.Chain
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/chains/{id} [delete]
+func (handler *ChainAdminController) DeleteChain(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	name, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	chain, err := handler.repo.Delete(id).Query()
+	if db.IsForeignKeyViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Chain still has pharmacies"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if chain == nil {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	}
+
+	handler.logger.Info().Msgf("Chain %s was deleted by %s", chain.Name, name)
+	return http.StatusOK, chain, nil
+}
+
+func applyChainDTO(chain *entity.Chain, body dto.ChainCreationDTO) {
+	chain.Name = body.Name
+	chain.DisplayName = body.DisplayName
+	chain.Website = body.Website
+	chain.LogoURL = body.LogoURL
+	chain.ScraperKind = body.ScraperKind
+	chain.Active = body.Active
+}
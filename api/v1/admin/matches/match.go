@@ -0,0 +1,235 @@
+package matches
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type PharmacyMatchController struct {
+	repo       db.PharmacyMatchRepository
+	pharmacies db.PharmacyRepository
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvidePharmacyMatchController(repo db.PharmacyMatchRepository, pharmacies db.PharmacyRepository, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &PharmacyMatchController{
+		repo:       repo,
+		pharmacies: pharmacies,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *PharmacyMatchController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[PharmacyMatchController](handler.GetMatches, "/admin/matches", []string{"GET"}),
+		web.NewRequestsHandler[PharmacyMatchController](handler.ConfirmMatch, "/admin/matches/{id}/confirm", []string{"POST"}),
+		web.NewRequestsHandler[PharmacyMatchController](handler.RejectMatch, "/admin/matches/{id}/reject", []string{"POST"}),
+	}
+}
+
+// Returns a context, which attributes pharmacy changes to given admin
+func adminContext(admin string) context.Context {
+	return db.WithRevisionOrigin(context.Background(), db.RevisionOrigin{Source: entity.REVISION_SOURCE_ADMIN, Name: admin})
+}
+
+// Finds a pending match by the ID path variable
+func (handler *PharmacyMatchController) findPendingMatch(idStr string) (*entity.PharmacyMatch, int, interface{}, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return nil, http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	match, err := handler.repo.FindMatchByID(id).Query()
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	} else if match == nil {
+		return nil, http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	} else if match.Status != string(entity.PHARMACY_MATCH_PENDING) {
+		return nil, http.StatusConflict, types.NewHttpError(http.StatusConflict, "Match has already been resolved"), nil
+	}
+
+	return match, http.StatusOK, nil, nil
+}
+
+// Get paged resultset of pending pharmacy matches
+//
+// Path: `GET /api/v1/admin/matches`
+//
+// @Summary			Query pending pharmacy matches
+// @Description		Endpoint for querying possible matches between scraped pharmacies without a known identity and existing pharmacies, which await manual confirmation
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			uk query int false "ID of the latest match in previous query set"
+// @Param			k query int false "Creation timestamp of the latest match in previous query set (unix millis)"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Param			desc query boolean false "Reverse the order of matches (default false)"
+// @Success 		200 {array} dto.PharmacyMatchDTO
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/matches [get]
+func (handler *PharmacyMatchController) GetMatches(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	ukStr, kStr, l, desc := db.ExtractPagerQueryParameters(details.Params)
+	uk, _ := strconv.ParseInt(ukStr, 10, 64)
+	k, _ := strconv.ParseInt(kStr, 10, 64)
+
+	var matches []entity.PharmacyMatch
+	var err error
+	if uk == 0 || k == 0 {
+		matches, err = handler.repo.FindPendingMatches().Page(nil, nil, l, desc)
+	} else {
+		matches, err = handler.repo.FindPendingMatches().Page(uk, types.Time(time.UnixMilli(k)), l, desc)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	result := make([]dto.PharmacyMatchDTO, len(matches))
+	for i := range matches {
+		candidate, err := handler.pharmacies.FindPharmacyByID(matches[i].CandidateID).Query()
+		if err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		result[i] = dto.PharmacyMatchDTO{PharmacyMatch: matches[i], Candidate: candidate}
+	}
+
+	return http.StatusOK, result, nil
+}
+
+// Confirm a pending pharmacy match
+//
+// Path: `POST /api/v1/admin/matches/{id}/confirm`
+//
+// @Summary			Confirm a pharmacy match
+// @Description		Endpoint for confirming that the scraped pharmacy is the existing candidate pharmacy. The candidate takes over the identity and the scraped state of the pharmacy, while other pending matches of the scraped pharmacy and of the candidate are rejected
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Match ID"
+// @Success 		200 {object} entity.Pharmacy
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/matches/{id}/confirm [post]
+func (handler *PharmacyMatchController) ConfirmMatch(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	match, code, resp, err := handler.findPendingMatch(details.PathVars["id"])
+	if match == nil {
+		return code, resp, err
+	}
+
+	candidate, err := handler.pharmacies.FindPharmacyByID(match.CandidateID).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if candidate == nil {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Candidate pharmacy not found"), nil
+	}
+
+	pharmacy := entity.Pharmacy(match.Pharmacy)
+	pharmacy.ID = candidate.ID
+	pharmacy.PharmacyID = match.PharmacyID
+	pharmacy.ClosedAt = nil
+	if pharmacy.PostalCode == "" {
+		pharmacy.PostalCode = candidate.PostalCode
+	}
+
+	err = handler.repo.Confirm(adminContext(admin), *match, pharmacy, admin, types.Time(time.Now().UTC()))
+	if db.IsUniqueViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Scraped pharmacy already exists"), nil
+	} else if errors.Is(err, db.ErrMatchResolved) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Match has already been resolved"), nil
+	} else if errors.Is(err, db.ErrScraperRunning) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Scraper of the pharmacy's chain is running, try again later"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	handler.logger.Info().Msgf("Pharmacy match %d of %s was confirmed by %s", match.ID, pharmacy.Name, admin)
+	return http.StatusOK, pharmacy, nil
+}
+
+// Reject a pending pharmacy match
+//
+// Path: `POST /api/v1/admin/matches/{id}/reject`
+//
+// @Summary			Reject a pharmacy match
+// @Description		Endpoint for rejecting that the scraped pharmacy is the existing candidate pharmacy. Once all matches of the scraped pharmacy are rejected, it is inserted as a new pharmacy
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Match ID"
+// @Success 		200 {object} entity.PharmacyMatch
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/matches/{id}/reject [post]
+func (handler *PharmacyMatchController) RejectMatch(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	match, code, resp, err := handler.findPendingMatch(details.PathVars["id"])
+	if match == nil {
+		return code, resp, err
+	}
+
+	now := types.Time(time.Now().UTC())
+	if err := handler.repo.Resolve([]int64{match.ID}, entity.PHARMACY_MATCH_REJECTED, admin, now); err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	match.Status = string(entity.PHARMACY_MATCH_REJECTED)
+	match.ResolvedAt = &now
+	match.ResolvedBy = &admin
+
+	siblings, err := handler.repo.FindPendingMatchesByPharmacy(match.Chain, match.PharmacyID).QueryAll()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if len(siblings) > 0 {
+		return http.StatusOK, match, nil
+	}
+
+	// the scraped pharmacy is not any of the candidates, thus it is a new
+	// pharmacy, which is matched by its source ID from now on
+	ctx := adminContext(admin)
+	existing, err := handler.pharmacies.FindPharmacyByChainAndPharmacyID(ctx, match.PharmacyID, match.Chain).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if existing == nil {
+		pharmacy := entity.Pharmacy(match.Pharmacy)
+		pharmacy.ID = 0
+		pharmacy.PharmacyID = match.PharmacyID
+		if err := handler.pharmacies.StoreAll(ctx, []entity.Pharmacy{pharmacy}); err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+	}
+
+	handler.logger.Info().Msgf("Pharmacy match %d of %s was rejected by %s", match.ID, match.Pharmacy.Name, admin)
+	return http.StatusOK, match, nil
+}
@@ -0,0 +1,139 @@
+package merges
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type PharmacyMergeController struct {
+	repo       db.PharmacyRepository
+	merges     db.PharmacyMergeRepository
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvidePharmacyMergeController(repo db.PharmacyRepository, merges db.PharmacyMergeRepository, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &PharmacyMergeController{
+		repo:       repo,
+		merges:     merges,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *PharmacyMergeController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[PharmacyMergeController](handler.GetMerges, "/admin/merges", []string{"GET"}),
+		web.NewRequestsHandler[PharmacyMergeController](handler.PostMerge, "/admin/pharmacies/{id}/merge", []string{"POST"}),
+	}
+}
+
+// Get paged resultset of pharmacy merges
+//
+// Path: `GET /api/v1/admin/merges`
+//
+// @Summary			Query the audit log of pharmacy merges
+// @Description		Endpoint for querying paged resultset of pharmacy merges, optionally limited to merges into or out of given pharmacy
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			pharmacy query int false "ID of the merged or the target pharmacy"
+// @Param			uk query int false "ID of the latest merge in previous query set"
+// @Param			k query int false "Timestamp of the latest merge in previous query set (unix millis)"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Param			desc query boolean false "Reverse the order of merges (default false)"
+// @Success 		200 {array} entity.PharmacyMerge
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/merges [get]
+func (handler *PharmacyMergeController) GetMerges(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	query := handler.merges.FindMerges()
+	if pharmacyStr := details.Params.Get("pharmacy"); pharmacyStr != "" {
+		pharmacyID, err := strconv.ParseInt(pharmacyStr, 10, 64)
+		if err != nil {
+			return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed pharmacy query parameter"), nil
+		}
+		query = handler.merges.FindMergesByPharmacy(pharmacyID)
+	}
+
+	ukStr, kStr, l, desc := db.ExtractPagerQueryParameters(details.Params)
+	uk, _ := strconv.ParseInt(ukStr, 10, 64)
+	k, _ := strconv.ParseInt(kStr, 10, 64)
+
+	var merges []entity.PharmacyMerge
+	var err error
+	if uk == 0 || k == 0 {
+		merges, err = query.Page(nil, nil, l, desc)
+	} else {
+		merges, err = query.Page(uk, types.Time(time.UnixMilli(k)), l, desc)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, merges, nil
+}
+
+// Merge a pharmacy into another pharmacy
+//
+// Path: `POST /api/v1/admin/pharmacies/{id}/merge`
+//
+// @Summary			Merge a pharmacy into another pharmacy
+// @Description		Endpoint for merging duplicate pharmacies. Reviews and revisions of the merged pharmacy are moved to the target pharmacy, the merged pharmacy is closed for good and its ID keeps redirecting to the target pharmacy. Merging is refused while the scraper of either chain is running
+// @Tags			Admin
+// @Accepts 		json
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "ID of the pharmacy to merge"
+// @Param			request body dto.PharmacyMergeDTO true "Merge request body"
+// @Success 		200 {object} entity.PharmacyMerge
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies/{id}/merge [post]
+func (handler *PharmacyMergeController) PostMerge(details *web.HttpRequestDetails[dto.PharmacyMergeDTO]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	merge, err := bg.MergePharmacies(context.Background(), handler.repo, handler.merges, id, details.Body.TargetID, admin)
+	if errors.Is(err, bg.ErrMergeIntoItself) {
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Pharmacy can't be merged into itself"), nil
+	} else if errors.Is(err, bg.ErrMergeNotFound) {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	} else if errors.Is(err, db.ErrScraperRunning) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Scraper of the pharmacy's chain is running, try again later"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, merge, nil
+}
@@ -0,0 +1,350 @@
+package pharmacies
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"pharmafinder/address"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/phone"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type IndependentPharmacyController struct {
+	repo       db.PharmacyRepository
+	chains     db.ChainRepository
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvideIndependentPharmacyController(repo db.PharmacyRepository, chains db.ChainRepository, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &IndependentPharmacyController{
+		repo:       repo,
+		chains:     chains,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *IndependentPharmacyController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[IndependentPharmacyController](handler.GetPharmacies, "/admin/pharmacies", []string{"GET"}),
+		web.NewRequestsHandler[IndependentPharmacyController](handler.PostPharmacy, "/admin/pharmacies", []string{"POST"}),
+		web.NewRequestsHandler[IndependentPharmacyController](handler.PutPharmacy, "/admin/pharmacies/{id}", []string{"PUT"}),
+		web.NewRequestsHandler[IndependentPharmacyController](handler.ClosePharmacy, "/admin/pharmacies/{id}/close", []string{"POST"}),
+		web.NewRequestsHandler[IndependentPharmacyController](handler.ReopenPharmacy, "/admin/pharmacies/{id}/reopen", []string{"POST"}),
+	}
+}
+
+// Returns a context, which attributes pharmacy changes to given admin
+func adminContext(admin string) context.Context {
+	return db.WithRevisionOrigin(context.Background(), db.RevisionOrigin{Source: entity.REVISION_SOURCE_ADMIN, Name: admin})
+}
+
+// Checks that pharmacies of the chain with given name are managed manually
+func (handler *IndependentPharmacyController) checkChain(name string) (*types.HttpError, error) {
+	chain, err := handler.chains.FindChainByName(context.Background(), name).Query()
+	if err != nil {
+		return nil, err
+	} else if chain == nil {
+		httpErr := types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("Unknown chain '%s'", name))
+		return &httpErr, nil
+	} else if chain.ScraperKind != nil {
+		httpErr := types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("Pharmacies of chain %s are populated by %s scraper", chain.Name, *chain.ScraperKind))
+		return &httpErr, nil
+	}
+
+	return nil, nil
+}
+
+// Finds an independent pharmacy by the ID path variable
+func (handler *IndependentPharmacyController) findPharmacy(idStr string) (*entity.Pharmacy, int, interface{}, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return nil, http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	pharmacy, err := handler.repo.FindPharmacyByID(id).Query()
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	} else if pharmacy == nil {
+		return nil, http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	}
+
+	if httpErr, err := handler.checkChain(pharmacy.Chain); err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	} else if httpErr != nil {
+		return nil, httpErr.StatusCode, httpErr, nil
+	}
+
+	return pharmacy, http.StatusOK, nil, nil
+}
+
+// Parses phone numbers of the request body, each of which must be a single valid number
+func parsePhoneNumbers(raw []string) (entity.PhoneNumbers, *types.HttpError) {
+	numbers := make(entity.PhoneNumbers, 0, len(raw))
+	for _, number := range raw {
+		parsed, err := phone.Parse(number)
+		if err != nil || len(parsed) != 1 {
+			httpErr := types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("Invalid phone number '%s'", number))
+			return nil, &httpErr
+		}
+		numbers = append(numbers, parsed[0])
+	}
+
+	return numbers, nil
+}
+
+func applyPharmacyDTO(pharmacy *entity.Pharmacy, body dto.IndependentPharmacyDTO, numbers entity.PhoneNumbers) {
+	pharmacy.Chain = body.Chain
+	pharmacy.Name = body.Name
+	// addresses are normalized the same way as the addresses of scraped pharmacies
+	addr := address.Parse(body.Address, body.City, body.County)
+	pharmacy.Address = addr.StreetAddress()
+	pharmacy.City = addr.City()
+	pharmacy.County = addr.County
+	pharmacy.PostalCode = body.PostalCode
+	pharmacy.Email = body.Email
+	pharmacy.PhoneNumbers = numbers
+	pharmacy.Latitude = body.Latitude
+	pharmacy.Longitude = body.Longitude
+	pharmacy.OpeningHours = body.OpeningHours
+	pharmacy.ModTime = types.Time(time.Now().UTC())
+}
+
+// Get all independent pharmacies
+//
+// Path: `GET /api/v1/admin/pharmacies`
+//
+// @Summary			Query independent pharmacies
+// @Description		Endpoint for querying paged resultset of pharmacies of chains, which are not populated by any scraper. Closed pharmacies are included
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			uk query int false "ID of the latest pharmacy in previous query set"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Param			desc query boolean false "Reverse the order of pharmacies (default false)"
+// @Success 		200 {array} entity.Pharmacy
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies [get]
+func (handler *IndependentPharmacyController) GetPharmacies(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	ukStr, _, l, desc := db.ExtractPagerQueryParameters(details.Params)
+	uk, _ := strconv.ParseInt(ukStr, 10, 64)
+
+	var pharmacies []entity.Pharmacy
+	var err error
+	if uk == 0 {
+		pharmacies, err = handler.repo.FindIndependentPharmacies().Page(nil, nil, l, desc)
+	} else {
+		pharmacies, err = handler.repo.FindIndependentPharmacies().Page(uk, uk, l, desc)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, pharmacies, nil
+}
+
+// Create a new independent pharmacy
+//
+// Path: `POST /api/v1/admin/pharmacies`
+//
+// @Summary			Create a new independent pharmacy
+// @Description		Endpoint for creating a pharmacy of a chain, which is not populated by any scraper
+// @Tags			Admin
+// @Accepts 		json
+// @Produce 		json
+// @Security		Bearer
+// @Param			request body dto.IndependentPharmacyDTO true "Pharmacy creation request body"
+// @Success 		201 {object} entity.Pharmacy
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies [post]
+func (handler *IndependentPharmacyController) PostPharmacy(details *web.HttpRequestDetails[dto.IndependentPharmacyDTO]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	if httpErr, err := handler.checkChain(details.Body.Chain); err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+
+	numbers, httpErr := parsePhoneNumbers(details.Body.PhoneNumbers)
+	if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+
+	ctx := adminContext(admin)
+	pharmacyID := bg.IndependentPharmacyID(details.Body.Name)
+	existing, err := handler.repo.FindPharmacyByChainAndPharmacyID(ctx, pharmacyID, details.Body.Chain).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if existing != nil {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Pharmacy with given name already exists in the chain"), nil
+	}
+
+	pharmacy := entity.Pharmacy{PharmacyID: pharmacyID}
+	applyPharmacyDTO(&pharmacy, details.Body, numbers)
+	err = handler.repo.StoreAll(ctx, []entity.Pharmacy{pharmacy})
+	if db.IsUniqueViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Pharmacy with given name already exists in the chain"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	created, err := handler.repo.FindPharmacyByChainAndPharmacyID(ctx, pharmacyID, pharmacy.Chain).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	handler.logger.Info().Msgf("Independent pharmacy %s was created by %s", pharmacy.Name, admin)
+	return http.StatusCreated, created, nil
+}
+
+// Modify an existing independent pharmacy
+//
+// Path: `PUT /api/v1/admin/pharmacies/{id}`
+//
+// @Summary			Modify an independent pharmacy
+// @Description		Endpoint for modifying a pharmacy of a chain, which is not populated by any scraper. The identity of the pharmacy is kept when it gets renamed
+// @Tags			Admin
+// @Accepts 		json
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Pharmacy ID"
+// @Param			request body dto.IndependentPharmacyDTO true "Pharmacy modification request body"
+// @Success 		200 {object} entity.Pharmacy
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Failure			409 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies/{id} [put]
+func (handler *IndependentPharmacyController) PutPharmacy(details *web.HttpRequestDetails[dto.IndependentPharmacyDTO]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	pharmacy, code, resp, err := handler.findPharmacy(details.PathVars["id"])
+	if pharmacy == nil {
+		return code, resp, err
+	}
+
+	if httpErr, err := handler.checkChain(details.Body.Chain); err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+
+	numbers, httpErr := parsePhoneNumbers(details.Body.PhoneNumbers)
+	if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+
+	applyPharmacyDTO(pharmacy, details.Body, numbers)
+	err = handler.repo.StoreAll(adminContext(admin), []entity.Pharmacy{*pharmacy})
+	if db.IsUniqueViolation(err) {
+		return http.StatusConflict, types.NewHttpError(http.StatusConflict, "Pharmacy with the same identity already exists in the chain"), nil
+	} else if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	handler.logger.Info().Msgf("Independent pharmacy %s was modified by %s", pharmacy.Name, admin)
+	return http.StatusOK, pharmacy, nil
+}
+
+// Close an independent pharmacy
+//
+// Path: `POST /api/v1/admin/pharmacies/{id}/close`
+//
+// @Summary			Close an independent pharmacy
+// @Description		Endpoint for marking a pharmacy of a chain, which is not populated by any scraper, as closed
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Pharmacy ID"
+// @Success 		200 {object} entity.Pharmacy
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies/{id}/close [post]
+func (handler *IndependentPharmacyController) ClosePharmacy(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	pharmacy, code, resp, err := handler.findPharmacy(details.PathVars["id"])
+	if pharmacy == nil {
+		return code, resp, err
+	}
+
+	if pharmacy.ClosedAt == nil {
+		closedAt := types.Time(time.Now().UTC())
+		if err := handler.repo.CloseAll(adminContext(admin), []int64{pharmacy.ID}, closedAt); err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		pharmacy.ClosedAt = &closedAt
+		handler.logger.Info().Msgf("Independent pharmacy %s was closed by %s", pharmacy.Name, admin)
+	}
+
+	return http.StatusOK, pharmacy, nil
+}
+
+// Reopen a closed independent pharmacy
+//
+// Path: `POST /api/v1/admin/pharmacies/{id}/reopen`
+//
+// @Summary			Reopen an independent pharmacy
+// @Description		Endpoint for reopening a closed pharmacy of a chain, which is not populated by any scraper
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Pharmacy ID"
+// @Success 		200 {object} entity.Pharmacy
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Router			/api/v1/admin/pharmacies/{id}/reopen [post]
+func (handler *IndependentPharmacyController) ReopenPharmacy(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	admin, ok := handler.authorizer.Authorize(details.Header)
+	if !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	pharmacy, code, resp, err := handler.findPharmacy(details.PathVars["id"])
+	if pharmacy == nil {
+		return code, resp, err
+	}
+
+	if pharmacy.ClosedAt != nil {
+		pharmacy.ClosedAt = nil
+		if err := handler.repo.StoreAll(adminContext(admin), []entity.Pharmacy{*pharmacy}); err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		handler.logger.Info().Msgf("Independent pharmacy %s was reopened by %s", pharmacy.Name, admin)
+	}
+
+	return http.StatusOK, pharmacy, nil
+}
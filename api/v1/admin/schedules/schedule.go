@@ -0,0 +1,54 @@
+package schedules
+
+import (
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+
+	"github.com/rs/zerolog"
+)
+
+type ScrapeScheduleController struct {
+	cronJob    *bg.CronJob
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvideScrapeScheduleController(cronJob *bg.CronJob, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &ScrapeScheduleController{
+		cronJob:    cronJob,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *ScrapeScheduleController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[ScrapeScheduleController](handler.GetScrapeSchedules, "/admin/schedules", []string{"GET"}),
+	}
+}
+
+// Get effective scraper schedules
+//
+// Path: `GET /api/v1/admin/schedules`
+//
+// @Summary			Query scraper schedules
+// @Description		Endpoint for querying effective schedules of all scrapers along with their next run times.
+// @Description		Scheduled runs are delayed randomly by up to the jitter duration.
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Success 		200 {array} bg.ScrapeSchedule
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/schedules [get]
+func (handler *ScrapeScheduleController) GetScrapeSchedules(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	return http.StatusOK, handler.cronJob.Schedules(), nil
+}
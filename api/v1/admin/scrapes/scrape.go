@@ -0,0 +1,131 @@
+package scrapes
+
+import (
+	"net/http"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/service"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ScrapeRunController struct {
+	repo       db.ScrapeRunRepository
+	authorizer service.AdminAuthorizer
+	logger     zerolog.Logger
+}
+
+func ProvideScrapeRunController(repo db.ScrapeRunRepository, authorizer service.AdminAuthorizer) []web.Route {
+	controller := &ScrapeRunController{
+		repo:       repo,
+		authorizer: authorizer,
+		logger:     utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *ScrapeRunController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[ScrapeRunController](handler.GetScrapeRuns, "/admin/scrapes", []string{"GET"}),
+		web.NewRequestsHandler[ScrapeRunController](handler.GetScrapeRun, "/admin/scrapes/{id}", []string{"GET"}),
+	}
+}
+
+// Get paged resultset of scrape runs
+//
+// Path: `GET /api/v1/admin/scrapes`
+//
+// @Summary			Query scrape run history
+// @Description		Endpoint for querying paged resultset of scraper runs and their outcomes
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			scraper query string false "Name of the scraper to filter runs by"
+// @Param			uk query int false "ID of the latest run in previous query set"
+// @Param			k query int false "Start timestamp of the latest run in previous query set (unix millis)"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Param			desc query boolean false "Reverse the order of runs (default false)"
+// @Success 		200 {array} entity.ScrapeRun
+// @Failure			401 {object} types.HttpError
+// @Router			/api/v1/admin/scrapes [get]
+func (handler *ScrapeRunController) GetScrapeRuns(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	ukStr, kStr, l, desc := db.ExtractPagerQueryParameters(details.Params)
+	uk, _ := strconv.ParseInt(ukStr, 10, 64)
+	k, _ := strconv.ParseInt(kStr, 10, 64)
+
+	var query db.Query[entity.ScrapeRun]
+	if scraper := details.Params.Get("scraper"); scraper != "" {
+		query = handler.repo.FindScrapeRunsByScraper(scraper)
+	} else {
+		query = handler.repo.FindScrapeRuns()
+	}
+
+	var runs []entity.ScrapeRun
+	var err error
+	if uk == 0 || k == 0 {
+		runs, err = query.Page(nil, nil, l, desc)
+	} else {
+		runs, err = query.Page(uk, types.Time(time.UnixMilli(k)), l, desc)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, runs, nil
+}
+
+// Get a single scrape run with its per-pharmacy changes
+//
+// Path: `GET /api/v1/admin/scrapes/{id}`
+//
+// @Summary			Query scrape run details
+// @Description		Endpoint for querying a single scrape run along with field-level changes made to pharmacies
+// @Tags			Admin
+// @Produce 		json
+// @Security		Bearer
+// @Param			id path integer true "Scrape run ID"
+// @Success 		200 {object} dto.ScrapeRunDetailsDTO
+// @Failure			400 {object} types.HttpError
+// @Failure			401 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Router			/api/v1/admin/scrapes/{id} [get]
+func (handler *ScrapeRunController) GetScrapeRun(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	if _, ok := handler.authorizer.Authorize(details.Header); !ok {
+		return http.StatusUnauthorized, types.NewHttpError(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	run, err := handler.repo.FindScrapeRunByID(id).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if run == nil {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	}
+
+	changes, err := handler.repo.FindScrapeRunChanges(id).QueryAll()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, dto.ScrapeRunDetailsDTO{
+		ScrapeRun: *run,
+		Changes:   changes,
+	}, nil
+}
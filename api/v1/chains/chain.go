@@ -0,0 +1,48 @@
+package chains
+
+import (
+	"net/http"
+	"pharmafinder/db"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+
+	"github.com/rs/zerolog"
+)
+
+type ChainController struct {
+	repo   db.ChainRepository
+	logger zerolog.Logger
+}
+
+func ProvideChainController(repo db.ChainRepository) []web.Route {
+	controller := &ChainController{
+		repo:   repo,
+		logger: utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *ChainController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[ChainController](handler.GetChains, "/chains", []string{"GET"}),
+	}
+}
+
+// Get all active pharmacy chains
+//
+// Path: `GET /api/v1/chains`
+//
+// @Summary			Query pharmacy chains
+// @Description		Endpoint for querying all active pharmacy chains along with the number of their open pharmacies
+// @Tags			Chains
+// @Produce 		json
+// @Success 		200 {array} dto.ChainDTO
+// @Router			/api/v1/chains [get]
+func (handler *ChainController) GetChains(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	chains, err := handler.repo.FindChains(false).QueryAll()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, chains, nil
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"net/http"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"pharmafinder/web"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type PharmacyHistoryController struct {
+	repo   db.PharmacyRevisionRepository
+	logger zerolog.Logger
+}
+
+func ProvidePharmacyHistoryController(repo db.PharmacyRevisionRepository) []web.Route {
+	controller := &PharmacyHistoryController{
+		repo:   repo,
+		logger: utils.GetLogger("API"),
+	}
+	return controller.GetRoutes()
+}
+
+func (handler *PharmacyHistoryController) GetRoutes() []web.Route {
+	return []web.Route{
+		web.NewRequestsHandler[PharmacyHistoryController](handler.GetPharmacyHistory, "/pharmacies/{id}/history", []string{"GET"}),
+	}
+}
+
+// Get paged resultset of revisions of given pharmacy
+//
+// Path: `GET /api/v1/pharmacies/{id}/history`
+//
+// @Summary			Query revision history of pharmacy
+// @Description		Endpoint for querying paged resultset of changes made to given pharmacy along with their previous values and origin (scraper, admin or import)
+// @Tags			Pharmacy
+// @Produce 		json
+// @Param			id path integer true "Pharmacy ID"
+// @Param			uk query int false "ID of the latest revision in previous query set"
+// @Param			k query int false "Timestamp of the latest revision in previous query set (unix millis)"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Param			desc query boolean false "Reverse the order of revisions (default false)"
+// @Success 		200 {array} entity.PharmacyRevision
+// @Failure			400 {object} types.HttpError
+// @Router			/api/v1/pharmacies/{id}/history [get]
+func (handler *PharmacyHistoryController) GetPharmacyHistory(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	ukStr, kStr, l, desc := db.ExtractPagerQueryParameters(details.Params)
+	uk, _ := strconv.ParseInt(ukStr, 10, 64)
+	k, _ := strconv.ParseInt(kStr, 10, 64)
+
+	var revisions []entity.PharmacyRevision
+	if uk == 0 || k == 0 {
+		revisions, err = handler.repo.FindRevisionsByPharmacy(id).Page(nil, nil, l, desc)
+	} else {
+		revisions, err = handler.repo.FindRevisionsByPharmacy(id).Page(uk, types.Time(time.UnixMilli(k)), l, desc)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	// names of admins are not disclosed publicly
+	for i := range revisions {
+		if revisions[i].Source == string(entity.REVISION_SOURCE_ADMIN) {
+			revisions[i].SourceName = nil
+		}
+	}
+
+	return http.StatusOK, revisions, nil
+}
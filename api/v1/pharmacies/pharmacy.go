@@ -1,17 +1,32 @@
 package pharmacies
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
 	"pharmafinder/types"
 	"pharmafinder/utils"
 	"pharmafinder/web"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/rs/zerolog"
 )
 
+const (
+	DEFAULT_NEAREST_LIMIT = 10
+	MAX_NEAREST_LIMIT     = 100
+
+	MAX_SEARCH_QUERY_LENGTH = 128
+	MAX_SEARCH_TERMS        = 8
+)
+
 type PharmaciesController struct {
 	repo   db.PharmacyRepository
 	logger zerolog.Logger
@@ -28,59 +43,356 @@ func ProvidePharmacyController(repo db.PharmacyRepository) []web.Route {
 func (handler *PharmaciesController) GetRoutes() []web.Route {
 	return []web.Route{
 		web.NewRequestsHandler[PharmaciesController](handler.GetPharmacies, "/pharmacies", []string{"GET"}),
+		web.NewRequestsHandler[PharmaciesController](handler.GetNearestPharmacies, "/pharmacies/nearest", []string{"GET"}),
+		web.NewRequestsHandler[PharmaciesController](handler.SearchPharmacies, "/pharmacies/search", []string{"GET"}),
+		// numeric IDs only, so that the route doesn't shadow /pharmacies/ratings
+		web.NewRequestsHandler[PharmaciesController](handler.GetPharmacy, "/pharmacies/{id:[0-9]+}", []string{"GET"}),
 	}
 }
 
 // Pharmacy retriever endpoint
 //
-// GET /api/v1/phamacies?sw=lat,lng&ne=lat,lng
+// GET /api/v1/phamacies?sw=lat,lng&ne=lat,lng&includeClosed=false&openAt=ts&openNow=false&chain=&city=&county=&minRating=&hrtKind=&prescriptionType=&hasReviews=&sort=&desc=false
 //
 // @Summary			Get all pharmacies in coordinate bounds
-// @Description 	Endpoint for querying all pharmacies in specified coordinate bounds
+// @Description 	Endpoint for querying all pharmacies in specified coordinate bounds. Filters are combined, e.g. hrtKind=e&minRating=4 lists pharmacies with estrogen based HRT reviews averaging at least 4 stars
 // @Tags			Pharmacy
 // @Produce 		json
 // @Success 		200 {array} entity.Pharmacy
 // @Failure			400 {object} types.HttpError
 // @Param			sw query string true "South-west coordinates of the bound, syntax: lat,lng"
 // @Param			ne query string true "North-east coordinates of the bound, syntax: lat,lng"
+// @Param			includeClosed query boolean false "Include pharmacies which have disappeared from their chain's listing (default false)"
+// @Param			openAt query string false "Only include pharmacies open at given moment (unix millis or RFC3339 timestamp). Public holidays follow the weekly opening hours unless the pharmacy has published holiday opening hours for the date"
+// @Param			openNow query boolean false "Only include pharmacies which are currently open (default false)"
+// @Param			chain query string false "Comma separated list of chains"
+// @Param			city query string false "City of the pharmacy (case and accent insensitive)"
+// @Param			county query string false "County of the pharmacy (case and accent insensitive)"
+// @Param			minRating query number false "Minimum average rating (1-5)"
+// @Param			hrtKind query string false "Only include pharmacies with reviews of given HRT kind, ratings are computed over these reviews" Enums(e, t)
+// @Param			prescriptionType query string false "Only include pharmacies with reviews of prescriptions from given provider, ratings are computed over these reviews" Enums(Imago, GenderGP, National)
+// @Param			hasReviews query boolean false "Only include pharmacies with (true) or without (false) reviews"
+// @Param			sort query string false "Sort order of pharmacies (default by ID)" Enums(name, city, rating, reviews)
+// @Param			desc query boolean false "Reverse the sort order (default false)"
 // @Router			/api/v1/pharmacies [get]
 func (handler *PharmaciesController) GetPharmacies(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
-	swText := details.Params.Get("sw")
-	neText := details.Params.Get("ne")
+	bounds, httpErr := handler.parseBounds(details.Params.Get("sw"), details.Params.Get("ne"))
+	if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+
+	filter, httpErr := parsePharmacyFilter(details.Params)
+	if httpErr != nil {
+		return httpErr.StatusCode, httpErr, nil
+	}
+	filter.Bounds = bounds
+
+	var openAt *time.Time
+	if openAtStr := details.Params.Get("openAt"); openAtStr != "" {
+		ts, err := parseTimestamp(openAtStr)
+		if err != nil {
+			return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Open at timestamp is malformed"), nil
+		}
+		openAt = &ts
+	} else if openNow, _ := strconv.ParseBool(details.Params.Get("openNow")); openNow {
+		openAt = utils.Ptr(time.Now())
+	}
+
+	data, err := handler.repo.FindPharmacies(filter).QueryAll()
+	if err != nil {
+		handler.logger.Warn().Msgf("Failed to query pharmacies in coordinate bounds")
+		return http.StatusInternalServerError, nil, err
+	}
+
+	if openAt != nil {
+		data = slices.DeleteFunc(data, func(pharmacy entity.Pharmacy) bool {
+			return !pharmacy.OpeningHours.IsOpenAt(*openAt)
+		})
+	}
+
+	return http.StatusOK, data, nil
+}
+
+// Nearest pharmacies endpoint
+//
+// GET /api/v1/pharmacies/nearest?lat=&lng=&limit=10&maxKm=&includeClosed=false&uk=&k=
+//
+// @Summary			Get pharmacies nearest to a point
+// @Description 	Endpoint for querying pharmacies ordered by their great-circle distance from given point. The next page is queried by passing the ID and distance of the last pharmacy in the previous page as uk and k
+// @Tags			Pharmacy
+// @Produce 		json
+// @Success 		200 {array} dto.PharmacyDistanceDTO
+// @Failure			400 {object} types.HttpError
+// @Param			lat query number true "Latitude of the point"
+// @Param			lng query number true "Longitude of the point"
+// @Param			limit query int false "Limit of the query set (defaults to 10, at most 100)"
+// @Param			maxKm query number false "Maximum distance from the point in kilometers"
+// @Param			includeClosed query boolean false "Include pharmacies which have disappeared from their chain's listing (default false)"
+// @Param			uk query int false "ID of the last pharmacy in previous query set"
+// @Param			k query number false "Distance of the last pharmacy in previous query set (meters)"
+// @Router			/api/v1/pharmacies/nearest [get]
+func (handler *PharmaciesController) GetNearestPharmacies(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	lat, err := strconv.ParseFloat(details.Params.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Latitude is missing or malformed"), nil
+	}
+	lng, err := strconv.ParseFloat(details.Params.Get("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Longitude is missing or malformed"), nil
+	}
+	point := types.Point{Lat: lat, Lng: lng}
+
+	limit := DEFAULT_NEAREST_LIMIT
+	if limitStr := details.Params.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MAX_NEAREST_LIMIT {
+			return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", MAX_NEAREST_LIMIT)), nil
+		}
+	}
+
+	var maxMeters *float64
+	if maxKmStr := details.Params.Get("maxKm"); maxKmStr != "" {
+		maxKm, err := strconv.ParseFloat(maxKmStr, 64)
+		if err != nil || maxKm <= 0 {
+			return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Maximum distance is malformed"), nil
+		}
+		maxMeters = utils.Ptr(maxKm * 1000)
+	}
+
+	includeClosed, err := strconv.ParseBool(details.Params.Get("includeClosed"))
+	if err != nil {
+		includeClosed = false
+	}
+
+	query := handler.repo.FindNearestPharmacies(point, maxMeters, includeClosed)
+
+	var data []dto.PharmacyDistanceDTO
+	uk, ukErr := strconv.ParseInt(details.Params.Get("uk"), 10, 64)
+	k, kErr := strconv.ParseFloat(details.Params.Get("k"), 64)
+	if ukErr != nil || kErr != nil {
+		data, err = query.Page(nil, nil, limit, false)
+	} else {
+		data, err = query.Page(uk, k, limit, false)
+	}
+
+	if err != nil {
+		handler.logger.Warn().Msgf("Failed to query nearest pharmacies")
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, data, nil
+}
+
+// Pharmacy search endpoint
+//
+// GET /api/v1/pharmacies/search?q=&sw=lat,lng&ne=lat,lng&includeClosed=false&uk=&k=&l=
+//
+// @Summary			Search pharmacies
+// @Description 	Endpoint for searching pharmacies by their chain, name, address, city and county. Every word of the query must match, while accents (e.g. 'parnu' matches 'Pärnu') and small typos are tolerated. Results are ordered by their rank, the next page is queried by passing the ID and rank of the last pharmacy in the previous page as uk and k
+// @Tags			Pharmacy
+// @Produce 		json
+// @Success 		200 {array} dto.PharmacySearchResultDTO
+// @Failure			400 {object} types.HttpError
+// @Param			q query string true "Search query"
+// @Param			sw query string false "South-west coordinates of the bound, within which pharmacies are ranked higher, syntax: lat,lng"
+// @Param			ne query string false "North-east coordinates of the bound, within which pharmacies are ranked higher, syntax: lat,lng"
+// @Param			includeClosed query boolean false "Include pharmacies which have disappeared from their chain's listing (default false)"
+// @Param			uk query int false "ID of the last pharmacy in previous query set"
+// @Param			k query number false "Rank of the last pharmacy in previous query set"
+// @Param			l query int false "Limit of the query set (defaults to 50)"
+// @Router			/api/v1/pharmacies/search [get]
+func (handler *PharmaciesController) SearchPharmacies(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	q := strings.TrimSpace(details.Params.Get("q"))
+	if len(q) > MAX_SEARCH_QUERY_LENGTH {
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("Search query must not be longer than %d characters", MAX_SEARCH_QUERY_LENGTH)), nil
+	}
+
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Missing search query"), nil
+	}
+
+	var bounds *types.Bounds
+	if details.Params.Has("sw") || details.Params.Has("ne") {
+		var httpErr *types.HttpError
+		if bounds, httpErr = handler.parseBounds(details.Params.Get("sw"), details.Params.Get("ne")); httpErr != nil {
+			return httpErr.StatusCode, httpErr, nil
+		}
+	}
+
+	includeClosed, err := strconv.ParseBool(details.Params.Get("includeClosed"))
+	if err != nil {
+		includeClosed = false
+	}
+
+	query := handler.repo.SearchPharmacies(terms, bounds, includeClosed)
+
+	ukStr, kStr, l, _ := db.ExtractPagerQueryParameters(details.Params)
+	var data []dto.PharmacySearchResultDTO
+	uk, ukErr := strconv.ParseInt(ukStr, 10, 64)
+	k, kErr := strconv.ParseFloat(kStr, 64)
+	if ukErr != nil || kErr != nil {
+		data, err = query.Page(nil, nil, l, true)
+	} else {
+		data, err = query.Page(uk, k, l, true)
+	}
+
+	if err != nil {
+		handler.logger.Warn().Msgf("Failed to search pharmacies")
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, data, nil
+}
+
+// Splits search query into distinct words, leaving out punctuation,
+// which would otherwise be interpreted as LIKE wildcards
+func searchTerms(q string) []string {
+	terms := make([]string, 0)
+	for _, term := range strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms[:min(len(terms), MAX_SEARCH_TERMS)]
+}
+
+// Pharmacy details endpoint
+//
+// GET /api/v1/pharmacies/{id}
+//
+// @Summary			Get pharmacy details
+// @Description 	Endpoint for querying a single pharmacy along with its average ratings, review count, time of the latest review and chain metadata. IDs of merged pharmacies resolve to the pharmacy they were merged into
+// @Tags			Pharmacy
+// @Produce 		json
+// @Success 		200 {object} dto.PharmacyDetailsDTO
+// @Failure			400 {object} types.HttpError
+// @Failure			404 {object} types.HttpError
+// @Param			id path integer true "Pharmacy ID"
+// @Router			/api/v1/pharmacies/{id} [get]
+func (handler *PharmaciesController) GetPharmacy(details *web.HttpRequestDetails[web.EmptyBody]) (int, interface{}, error) {
+	idStr := details.PathVars["id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		handler.logger.Warn().Msgf("Malformed ID path variable '%s'", idStr)
+		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Malformed ID path variable"), nil
+	}
+
+	pharmacy, err := handler.repo.FindPharmacyDetailsByID(id).Query()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	} else if pharmacy == nil {
+		return http.StatusNotFound, types.NewHttpError(http.StatusNotFound, "Not found"), nil
+	}
+
+	return http.StatusOK, pharmacy, nil
+}
+
+// Parses filters of the pharmacy listing except for the coordinate bounds
+func parsePharmacyFilter(params url.Values) (db.PharmacyFilter, *types.HttpError) {
+	badRequest := func(message string) (db.PharmacyFilter, *types.HttpError) {
+		httpErr := types.NewHttpError(http.StatusBadRequest, message)
+		return db.PharmacyFilter{}, &httpErr
+	}
+
+	filter := db.PharmacyFilter{
+		City:   strings.TrimSpace(params.Get("city")),
+		County: strings.TrimSpace(params.Get("county")),
+	}
+
+	filter.IncludeClosed, _ = strconv.ParseBool(params.Get("includeClosed"))
+	filter.Desc, _ = strconv.ParseBool(params.Get("desc"))
+
+	for _, chain := range strings.Split(params.Get("chain"), ",") {
+		if chain = strings.TrimSpace(chain); chain != "" {
+			filter.Chains = append(filter.Chains, chain)
+		}
+	}
+
+	if minRatingStr := params.Get("minRating"); minRatingStr != "" {
+		minRating, err := strconv.ParseFloat(minRatingStr, 64)
+		if err != nil || minRating < 1 || minRating > 5 {
+			return badRequest("Minimum rating must be between 1 and 5")
+		}
+		filter.MinRating = &minRating
+	}
+
+	if hrtKind := params.Get("hrtKind"); hrtKind != "" {
+		if !slices.Contains([]entity.HRTKind{entity.HRT_KIND_ESTROGEN_BASED, entity.HRT_KIND_TESTOSTERONE_BASED}, entity.HRTKind(hrtKind)) {
+			return badRequest(fmt.Sprintf("Unknown HRT kind '%s'", hrtKind))
+		}
+		filter.HRTKind = hrtKind
+	}
+
+	if prescriptionType := params.Get("prescriptionType"); prescriptionType != "" {
+		if !slices.Contains([]entity.PrescriptionType{entity.PRESCRIPTION_IMAGO, entity.PRESCRIPTION_GENDERGP, entity.PRESCRIPTION_NATIONAL}, entity.PrescriptionType(prescriptionType)) {
+			return badRequest(fmt.Sprintf("Unknown prescription type '%s'", prescriptionType))
+		}
+		filter.PrescriptionType = prescriptionType
+	}
 
+	if hasReviewsStr := params.Get("hasReviews"); hasReviewsStr != "" {
+		hasReviews, err := strconv.ParseBool(hasReviewsStr)
+		if err != nil {
+			return badRequest("Has reviews flag is malformed")
+		}
+		filter.HasReviews = &hasReviews
+	}
+
+	filter.Sort = db.PharmacySort(params.Get("sort"))
+	if !slices.Contains(db.PHARMACY_SORTS, filter.Sort) {
+		return badRequest(fmt.Sprintf("Unknown sort order '%s'", filter.Sort))
+	}
+
+	return filter, nil
+}
+
+// Parses coordinate bounds given in 'lat,lng' syntax
+func (handler *PharmaciesController) parseBounds(swText string, neText string) (*types.Bounds, *types.HttpError) {
 	swCoords := strings.Split(swText, ",")
 	neCoords := strings.Split(neText, ",")
 
+	badRequest := func(message string) *types.HttpError {
+		httpErr := types.NewHttpError(http.StatusBadRequest, message)
+		return &httpErr
+	}
+
 	if len(swCoords) != 2 || len(neCoords) != 2 {
 		handler.logger.Warn().Msg("Could not extract latitude and longitude from bounds")
-		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "Missing coordinate bounds"), nil
+		return nil, badRequest("Missing coordinate bounds")
 	}
 
 	lat, err := strconv.ParseFloat(swCoords[0], 64)
 	if err != nil {
-		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "South-west bound latitude is malformed"), nil
+		return nil, badRequest("South-west bound latitude is malformed")
 	}
 	lng, err := strconv.ParseFloat(swCoords[1], 64)
 	if err != nil {
-		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "South-west bound longitude is malformed"), nil
+		return nil, badRequest("South-west bound longitude is malformed")
 	}
-	sw := types.Point{Lat: float32(lat), Lng: float32(lng)}
+	sw := types.Point{Lat: lat, Lng: lng}
 
 	lat, err = strconv.ParseFloat(neCoords[0], 64)
 	if err != nil {
-		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "North-east bound latitude is malformed"), nil
+		return nil, badRequest("North-east bound latitude is malformed")
 	}
 	lng, err = strconv.ParseFloat(neCoords[1], 64)
 	if err != nil {
-		return http.StatusBadRequest, types.NewHttpError(http.StatusBadRequest, "North-east bound longitude is malformed"), nil
+		return nil, badRequest("North-east bound longitude is malformed")
 	}
-	ne := types.Point{Lat: float32(lat), Lng: float32(lng)}
+	ne := types.Point{Lat: lat, Lng: lng}
 
-	data, err := handler.repo.FindPharmaciesInCoordinateBounds(sw, ne).QueryAll()
-	if err != nil {
-		handler.logger.Warn().Msgf("Failed to query pharmacies in coordinate bounds")
-		return http.StatusInternalServerError, nil, err
+	return &types.Bounds{SW: sw, NE: ne}, nil
+}
+
+// Parses timestamps given either as unix milliseconds or in RFC3339 format
+func parseTimestamp(value string) (time.Time, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), nil
 	}
 
-	return http.StatusOK, data, nil
+	return time.Parse(time.RFC3339, value)
 }
@@ -81,19 +81,19 @@ func (handler *PharmacyRatingController) GetAllPharmacyRatings(details *web.Http
 	ne := types.Point{Lat: 90, Lng: 90}
 	if len(swStrCoords) == 2 {
 		if v, err := strconv.ParseFloat(strings.TrimSpace(swStrCoords[0]), 64); err == nil {
-			sw.Lat = float32(v)
+			sw.Lat = v
 		}
 		if v, err := strconv.ParseFloat(strings.TrimSpace(swStrCoords[1]), 64); err == nil {
-			sw.Lng = float32(v)
+			sw.Lng = v
 		}
 	}
 
 	if len(neStrCoords) == 2 {
 		if v, err := strconv.ParseFloat(strings.TrimSpace(neStrCoords[0]), 64); err == nil {
-			ne.Lat = float32(v)
+			ne.Lat = v
 		}
 		if v, err := strconv.ParseFloat(strings.TrimSpace(neStrCoords[1]), 64); err == nil {
-			ne.Lng = float32(v)
+			ne.Lng = v
 		}
 	}
 
@@ -0,0 +1,131 @@
+package bg
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"pharmafinder/address"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"strings"
+)
+
+// Recognized column names of the address dataset, the first
+// name is the canonical one and the rest are accepted aliases
+var addressColumns = map[string][]string{
+	"street_address": {"street_address", "address", "aadress"},
+	"street":         {"street", "tanav", "tänav"},
+	"house":          {"house", "house_number", "maja", "maja_nr"},
+	"settlement":     {"settlement", "city", "asula", "linn"},
+	"county":         {"county", "maakond"},
+	"postal_code":    {"postal_code", "postcode", "zip", "zip_code", "sihtnumber", "indeks"},
+}
+
+// Parses the address dataset from a CSV file with a header row.
+// Both comma and semicolon delimited files are accepted.
+//
+// The file must contain a postal code column and either a street address column or
+// separate street and house number columns, settlement and county columns are optional.
+// Column names are matched case-insensitively, see addressColumns for accepted names
+func parseAddressCSV(r io.Reader) ([]entity.Address, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read address dataset header: %v", err)
+	}
+	header = strings.TrimPrefix(header, "\ufeff")
+
+	delimiter := ','
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		delimiter = ';'
+	}
+
+	csvReader := csv.NewReader(io.MultiReader(strings.NewReader(header), reader))
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	columns, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address dataset header: %v", err)
+	}
+
+	indices := make(map[string]int)
+	for i, column := range columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		for name, aliases := range addressColumns {
+			for _, alias := range aliases {
+				if _, ok := indices[name]; !ok && column == alias {
+					indices[name] = i
+				}
+			}
+		}
+	}
+
+	_, hasAddress := indices["street_address"]
+	_, hasStreet := indices["street"]
+	if _, ok := indices["postal_code"]; !ok || (!hasAddress && !hasStreet) {
+		return nil, fmt.Errorf("address dataset must contain a postal code column and either a street address or a street column")
+	}
+
+	get := func(record []string, name string) string {
+		i, ok := indices[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	addresses := make([]entity.Address, 0)
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse address dataset line %d: %v", line, err)
+		}
+
+		streetAddress := get(record, "street_address")
+		if !hasAddress {
+			streetAddress = strings.TrimSpace(get(record, "street") + " " + get(record, "house"))
+		}
+
+		postalCode := get(record, "postal_code")
+		if streetAddress == "" || postalCode == "" {
+			continue
+		}
+
+		settlement := get(record, "settlement")
+		addresses = append(addresses, entity.Address{
+			StreetAddress: streetAddress,
+			Settlement:    settlement,
+			County:        get(record, "county"),
+			PostalCode:    postalCode,
+			AddressKey:    address.StreetKey(streetAddress),
+			SettlementKey: address.SettlementKey(settlement),
+		})
+	}
+
+	return addresses, nil
+}
+
+// Replaces the offline address dataset with addresses parsed from
+// provided CSV file and returns the amount of imported addresses
+func ImportAddresses(ctx context.Context, r io.Reader, repo db.AddressRepository) (int, error) {
+	addresses, err := parseAddressCSV(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(addresses) == 0 {
+		return 0, fmt.Errorf("address dataset contains no addresses")
+	}
+
+	if err := repo.ReplaceAll(ctx, addresses); err != nil {
+		return 0, fmt.Errorf("failed to store addresses: %v", err)
+	}
+
+	return len(addresses), nil
+}
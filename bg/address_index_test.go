@@ -0,0 +1,46 @@
+package bg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAddressCSV_StreetAddress(t *testing.T) {
+	data := "\ufeffAddress,City,County,Postal_Code\n" +
+		"Akadeemia tee 35,Tallinn,Harju maakond,12618\n" +
+		"\"Tallinna mnt. 41\",Narva linn,Ida-Viru maakond,20605\n" +
+		"Nowhere 1,Tallinn,Harju maakond,\n"
+
+	addresses, err := parseAddressCSV(strings.NewReader(data))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(addresses))
+	assert.Equal(t, "akadeemia tee 35", addresses[0].AddressKey)
+	assert.Equal(t, "tallinn", addresses[0].SettlementKey)
+	assert.Equal(t, "12618", addresses[0].PostalCode)
+	assert.Equal(t, "Tallinna mnt. 41", addresses[1].StreetAddress)
+	assert.Equal(t, "tallinna maantee 41", addresses[1].AddressKey)
+	assert.Equal(t, "narva", addresses[1].SettlementKey)
+	assert.Equal(t, "Ida-Viru maakond", addresses[1].County)
+}
+
+func TestParseAddressCSV_StreetAndHouse(t *testing.T) {
+	data := "MAAKOND;ASULA;TANAV;MAJA;SIHTNUMBER\n" +
+		"Tartu maakond;Tartu linn;Riia;2;51004\n"
+
+	addresses, err := parseAddressCSV(strings.NewReader(data))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(addresses))
+	assert.Equal(t, "Riia 2", addresses[0].StreetAddress)
+	assert.Equal(t, "riia 2", addresses[0].AddressKey)
+	assert.Equal(t, "tartu", addresses[0].SettlementKey)
+	assert.Equal(t, "51004", addresses[0].PostalCode)
+}
+
+func TestParseAddressCSV_MissingColumns(t *testing.T) {
+	_, err := parseAddressCSV(strings.NewReader("city,county\nTallinn,Harju maakond\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package bg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pharmafinder/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Alerter notifies operators of problems, which need immediate attention
+type Alerter interface {
+	Alert(ctx context.Context, subject string, message string) error
+}
+
+// Alerter, which only writes alerts into the log
+type LogAlerter struct {
+	logger zerolog.Logger
+}
+
+func NewLogAlerter() Alerter {
+	return LogAlerter{logger: utils.GetLogger("ALERT")}
+}
+
+func (alerter LogAlerter) Alert(ctx context.Context, subject string, message string) error {
+	alerter.logger.Error().Msgf("ALERT %s: %s", subject, message)
+	return nil
+}
+
+// Alerter, which posts alerts as JSON to a webhook (e.g. Slack or Discord
+// compatible incoming webhook) in addition to writing them into the log
+type WebhookAlerter struct {
+	url    string
+	client utils.HttpClient
+	logger zerolog.Logger
+}
+
+type webhookAlert struct {
+	// Plain text of the alert understood by most chat webhooks
+	Text    string    `json:"text"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func (alerter WebhookAlerter) Alert(ctx context.Context, subject string, message string) error {
+	alerter.logger.Error().Msgf("ALERT %s: %s", subject, message)
+
+	body, err := json.Marshal(webhookAlert{
+		Text:    fmt.Sprintf("%s: %s", subject, message),
+		Subject: subject,
+		Message: message,
+		Time:    time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", alerter.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", USER_AGENT)
+
+	resp, err := alerter.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert to webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded with non-2xx status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Alerts are posted to the webhook at ALERT_WEBHOOK_URL environment
+// variable if it is set, otherwise alerts are only logged
+func ProvideAlerter(client utils.HttpClient) Alerter {
+	if url := utils.Getenv("ALERT_WEBHOOK_URL", ""); url != "" {
+		return WebhookAlerter{url: url, client: client, logger: utils.GetLogger("ALERT")}
+	}
+	return NewLogAlerter()
+}
@@ -1,10 +1,10 @@
 package bg
 
 import (
+	"context"
+	"fmt"
 	"pharmafinder/db"
-	"pharmafinder/db/entity"
 	"pharmafinder/utils"
-	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -13,53 +13,58 @@ const APOTHEKA_ENDPOINT = "https://www.apotheka.ee/shops/shop/shops"
 
 type ApothekaScraper struct {
 	repo       db.PharmacyRepository
+	chains     db.ChainRepository
 	httpClient utils.HttpClient
+	resolver   *PostalCodeResolver
+	guard      *SanityGuard
 	logger     zerolog.Logger
 }
 
-func ProvideApothekaScraper(repo db.PharmacyRepository, client utils.HttpClient) Scraper {
+func ProvideApothekaScraper(repo db.PharmacyRepository, chains db.ChainRepository, client utils.HttpClient, resolver *PostalCodeResolver, guard *SanityGuard) Scraper {
 	return &ApothekaScraper{
 		repo:       repo,
+		chains:     chains,
 		httpClient: client,
+		resolver:   resolver,
+		guard:      guard,
 		logger:     utils.GetLogger("BG"),
 	}
 }
 
-func (scraper *ApothekaScraper) Scrape() {
+func (scraper *ApothekaScraper) Name() string {
+	return "apotheka"
+}
+
+func (scraper *ApothekaScraper) Scrape(ctx context.Context) (ScrapeResult, error) {
 	scraper.logger.Info().Msg("Scraping Apotheka pharmacy locations...")
-	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(entity.CHAIN_APOTHEKA).QueryAll()
+	var result ScrapeResult
+
+	chain, err := findScraperChain(ctx, scraper.chains, scraper.Name(), &scraper.logger)
+	if err != nil || chain == nil {
+		return result, err
+	}
+
+	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(ctx, chain.Name).QueryAll()
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to query existing Apotheka pharmacies: %v", err)
-		return
+		return result, fmt.Errorf("failed to query existing Apotheka pharmacies: %v", err)
 	}
 
-	pharmacies, err := fetchShops(APOTHEKA_ENDPOINT, scraper.httpClient, &scraper.logger)
+	pharmacies, err := fetchShops(ctx, APOTHEKA_ENDPOINT, scraper.httpClient, &scraper.logger)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to fetch Apotheka pharmacies: %v", err)
-		return
+		return result, err
 	}
 
-	pharmaciesToSave := make([]entity.Pharmacy, 0)
-	apothekaPharmacies := mapShopsToPharmacies(pharmacies, entity.CHAIN_APOTHEKA, &scraper.logger, scraper.httpClient)
-	for i := range apothekaPharmacies {
-		var existingPharmacy *entity.Pharmacy
-		for _, existing := range existingPharmacies {
-			if apothekaPharmacies[i].PharmacyID == existing.PharmacyID {
-				existingPharmacy = &existing
-				break
-			}
-		}
-
-		if existingPharmacy != nil && time.Time(existingPharmacy.ModTime).UTC().UnixMilli() < time.Time(apothekaPharmacies[i].ModTime).UTC().UnixMilli() {
-			apothekaPharmacies[i].ID = existingPharmacy.ID
-			pharmaciesToSave = append(pharmaciesToSave, apothekaPharmacies[i])
-		} else if existingPharmacy == nil {
-			pharmaciesToSave = append(pharmaciesToSave, apothekaPharmacies[i])
-		}
+	apothekaPharmacies, err := mapShopsToPharmacies(ctx, pharmacies, chain.Name, &scraper.logger, scraper.resolver)
+	if err != nil {
+		return result, err
 	}
+	result.skip(len(pharmacies.Items) - len(apothekaPharmacies))
 
-	err = scraper.repo.StoreAll(pharmaciesToSave)
+	err = syncPharmacies(ctx, scraper.repo, scraper.guard, &result, existingPharmacies, apothekaPharmacies)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to persist Apotheka pharmacies: %v", err)
 	}
+	return result, err
 }
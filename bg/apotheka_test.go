@@ -1,6 +1,7 @@
 package bg_test
 
 import (
+	"context"
 	"embed"
 	"io"
 	"net/http"
@@ -22,34 +23,36 @@ var apothekaJson embed.FS
 
 var apothekaPharmacies map[int]entity.Pharmacy = map[int]entity.Pharmacy{
 	1: {
-		ID:          0,
-		PharmacyID:  1,
-		Chain:       string(entity.CHAIN_APOTHEKA),
-		Name:        "AKADEEMIA KONSUMI APTEEK",
-		Address:     "Akadeemia tee 35",
-		City:        "Tallinn",
-		County:      "Harjumaa",
-		PostalCode:  "12618",
-		Email:       "kajaapt@apotheka.ee",
-		PhoneNumber: "+3726587701",
-		ModTime:     types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-02-01 09:08:38"))),
-		Latitude:    59.403729,
-		Longitude:   24.655573,
+		ID:           0,
+		PharmacyID:   1,
+		Chain:        "Apotheka",
+		Name:         "AKADEEMIA KONSUMI APTEEK",
+		Address:      "Akadeemia tee 35",
+		City:         "Tallinn",
+		County:       "Harjumaa",
+		PostalCode:   "12618",
+		Email:        "kajaapt@apotheka.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3726587701", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-02-01 09:08:38"))),
+		Latitude:     59.403729,
+		Longitude:    24.655573,
+		OpeningHours: weeklyHours("10:00-20:00", "10:00-20:00", "10:00-20:00", "10:00-20:00", "10:00-20:00", "10:00-18:00", "10:00-17:00"),
 	},
 	5: {
-		ID:          0,
-		PharmacyID:  5,
-		Chain:       string(entity.CHAIN_APOTHEKA),
-		Name:        "ASTRI KESKUSE APTEEK",
-		Address:     "Tallinna mnt 41",
-		City:        "Narva",
-		County:      "Ida-Virumaa",
-		PostalCode:  "20605",
-		Email:       "tempharu@apotheka.ee",
-		PhoneNumber: "+3723573071",
-		ModTime:     types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-02-01 09:08:38"))),
-		Latitude:    59.380785,
-		Longitude:   28.174233,
+		ID:           0,
+		PharmacyID:   5,
+		Chain:        "Apotheka",
+		Name:         "ASTRI KESKUSE APTEEK",
+		Address:      "Tallinna mnt 41",
+		City:         "Narva",
+		County:       "Ida-Virumaa",
+		PostalCode:   "20605",
+		Email:        "tempharu@apotheka.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3723573071", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-02-01 09:08:38"))),
+		Latitude:     59.380785,
+		Longitude:    28.174233,
+		OpeningHours: weeklyHours("09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "10:00-20:00", "10:00-18:00"),
 	},
 }
 
@@ -96,11 +99,11 @@ func TestApothekaScraper_EmptyDB(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_APOTHEKA)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Apotheka")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 2, len(pharmacies))
 
 			for _, pharmacy := range pharmacies {
@@ -116,8 +119,12 @@ func TestApothekaScraper_EmptyDB(t *testing.T) {
 			return nil
 		})
 
-	scraper := bg.ProvideApothekaScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideApothekaScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Inserted)
+	assert.Equal(t, 0, result.Unchanged)
 }
 
 func TestApothekaScraper_Existing(t *testing.T) {
@@ -167,11 +174,11 @@ func TestApothekaScraper_Existing(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_APOTHEKA)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Apotheka")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 1, len(pharmacies))
 
 			for _, pharmacy := range pharmacies {
@@ -187,6 +194,127 @@ func TestApothekaScraper_Existing(t *testing.T) {
 			return nil
 		})
 
-	scraper := bg.ProvideApothekaScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideApothekaScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 1, result.Unchanged)
+}
+
+func TestApothekaScraper_PostalCodeLookupFails(t *testing.T) {
+	existing := make([]entity.Pharmacy, 0)
+	for k := range apothekaPharmacies {
+		pharmacy := apothekaPharmacies[k]
+		pharmacy.ID = int64(k)
+		existing = append(existing, pharmacy)
+	}
+
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Times(3).
+		DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "www.omniva.ee" {
+				return &http.Response{
+					StatusCode: 503,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}
+
+			file, _ := apothekaJson.Open("_embeds/apotheka.json")
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(file),
+			}, nil
+		})
+
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return(existing, nil)
+
+	// existing postal codes are kept, thus nothing is stored
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Apotheka")).
+		Return(queryMock)
+
+	scraper := bg.ProvideApothekaScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, 2, result.Unchanged)
+}
+
+func TestApothekaScraper_ClosedAndReopened(t *testing.T) {
+	// pharmacy 1 is open and unchanged, pharmacy 5 was closed but reappeared
+	// and pharmacy 99 no longer exists in the listing
+	open := apothekaPharmacies[1]
+	open.ID = 1
+	reappeared := apothekaPharmacies[5]
+	reappeared.ID = 2
+	reappeared.ClosedAt = utils.Ptr(types.Time(time.Now().UTC()))
+	disappeared := apothekaPharmacies[1]
+	disappeared.ID = 3
+	disappeared.PharmacyID = 99
+	disappeared.Name = "SULETUD APTEEK"
+
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Times(3).
+		DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "www.omniva.ee" {
+				search := req.URL.Query().Get("search")
+				switch search {
+				case "Akadeemia tee 35, Tallinn, Harju maakond":
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(`{"addresses":[{"address":"Akadeemia tee 35, Mustamäe linnaosa, Tallinn, Harju maakond, 12618","zipCode":"12618"}]}`)),
+					}, nil
+				case "Tallinna mnt 41, Narva, Ida-Viru maakond":
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(`{"addresses":[{"address":"Tallinna mnt 41, Narva linn, Ida-Viru maakond, 20605","zipCode":"20605"}]}`)),
+					}, nil
+				}
+			}
+
+			file, _ := apothekaJson.Open("_embeds/apotheka.json")
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(file),
+			}, nil
+		})
+
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return([]entity.Pharmacy{open, reappeared, disappeared}, nil)
+
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Apotheka")).
+		Return(queryMock)
+	repoMock.EXPECT().
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Eq([]int64{3}), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
+			assert.Equal(t, 1, len(pharmacies))
+			assert.Equal(t, int64(2), pharmacies[0].ID)
+			assert.Nil(t, pharmacies[0].ClosedAt)
+			return nil
+		})
+
+	scraper := bg.ProvideApothekaScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Inserted)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, 1, result.Closed)
 }
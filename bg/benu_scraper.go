@@ -1,12 +1,15 @@
 package bg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"pharmafinder/address"
 	"pharmafinder/db"
 	"pharmafinder/db/entity"
+	"pharmafinder/phone"
 	"pharmafinder/types"
 	"pharmafinder/utils"
 	"regexp"
@@ -20,16 +23,26 @@ import (
 
 const BENU_ENDPOINT = "https://www.benu.ee/leia-apteek"
 
+// Separates the parts of BENU pharmacy addresses. Hyphens without surrounding
+// whitespace are part of the names, e.g. "Kilingi-Nõmme" or "Mini-Rimi Apteek"
+var benuAddressSeparator = regexp.MustCompile(`\s+-\s*|-\s+`)
+
 type BenuScraper struct {
 	repo       db.PharmacyRepository
+	chains     db.ChainRepository
 	httpClient utils.HttpClient
+	resolver   *PostalCodeResolver
+	guard      *SanityGuard
 	logger     zerolog.Logger
 }
 
-func ProvideBenuScraper(repo db.PharmacyRepository, client utils.HttpClient) Scraper {
+func ProvideBenuScraper(repo db.PharmacyRepository, chains db.ChainRepository, client utils.HttpClient, resolver *PostalCodeResolver, guard *SanityGuard) Scraper {
 	return &BenuScraper{
 		repo:       repo,
+		chains:     chains,
 		httpClient: client,
+		resolver:   resolver,
+		guard:      guard,
 		logger:     utils.GetLogger("BG"),
 	}
 }
@@ -44,69 +57,70 @@ type benuPharmacy struct {
 	Phone     string `json:"phone"`
 	Email     string `json:"email"`
 	ModTime   string `json:"modTime"`
+
+	WorkHours map[string]*string `json:"workHours"`
 }
 
-func (src *benuPharmacy) mapToPharmacy(dst *entity.Pharmacy, newTS time.Time, logger *zerolog.Logger) error {
+func (src *benuPharmacy) mapToPharmacy(dst *entity.Pharmacy, chain string, newTS time.Time, logger *zerolog.Logger) error {
 	dst.PharmacyID = src.ID
-	dst.Chain = string(entity.CHAIN_BENU)
-	dst.County = src.Region
+	dst.Chain = chain
 	dst.PostalCode = src.PostCode
 	dst.Email = src.Email
-	re := regexp.MustCompile(`(\+372)? *([\d ]+)`)
-	groups := re.FindStringSubmatch(src.Phone)
-	if len(groups) == 3 {
-		dst.PhoneNumber = fmt.Sprintf("+372%s", strings.ReplaceAll(groups[2], " ", ""))
-	} else {
+	numbers, err := phone.Parse(src.Phone)
+	if len(numbers) == 0 {
 		logger.Error().Msgf("Failed to extract BENU pharmacy phone number")
 		return fmt.Errorf("invalid phone number '%s'", src.Phone)
+	} else if err != nil {
+		logger.Warn().Msgf("Failed to extract some of BENU pharmacy phone numbers: %v", err)
 	}
+	dst.PhoneNumbers = numbers
 	dst.ModTime = types.Time(newTS)
-	lat, err := strconv.ParseFloat(src.Latitude, 32)
+	lat, err := strconv.ParseFloat(src.Latitude, 64)
 	if err != nil {
 		logger.Error().Msgf("Failed to extract BENU pharmacy latitude: invalid value %s", src.Latitude)
 		return fmt.Errorf("failed to extract pharmacy latitude: invalid value %s", src.Latitude)
 	}
 
-	lng, err := strconv.ParseFloat(src.Longitude, 32)
+	lng, err := strconv.ParseFloat(src.Longitude, 64)
 	if err != nil {
 		logger.Error().Msgf("Failed to extract BENU pharmacy longitude: invalid value %s", src.Longitude)
 		return fmt.Errorf("failed to extract pharmacy longitude: invalid value %s", src.Longitude)
 	}
-	dst.Latitude = float32(lat)
-	dst.Longitude = float32(lng)
-
-	// extracting address information with this insane regex
-	re = regexp.MustCompile(`^(.*?)(( -)|(- )|( - ))(.*?)((( -)|(- )|( - ))(.*?))?((( -)|(- )|( - ))(.*))?$`)
-	groups = re.FindStringSubmatch(src.Address)
-
-	// 18 groups means that the address also contains a district
-	// 12 groups means no district but it has a city
-	// 6 groups means no district and no city (can be extracted from the address part)
-	if len(groups) == 19 {
-		if groups[18] != "" {
-			dst.City = strings.Trim(groups[1], " ")
-			dst.Name = strings.Trim(groups[12], " ")
-			dst.Address = fmt.Sprintf("%s, %s", strings.Trim(groups[18], " "), strings.Trim(groups[6], " "))
-		} else if groups[12] != "" {
-			dst.City = strings.Trim(groups[1], " ")
-			dst.Name = strings.Trim(groups[6], " ")
-			dst.Address = strings.Trim(groups[12], " ")
-		} else if groups[6] != "" {
-			dst.Name = strings.Trim(groups[1], " ")
-			data := strings.Split(strings.Trim(groups[6], " "), ",")
-			if len(data) >= 2 {
-				dst.Address = strings.Trim(data[0], " ")
-				dst.City = strings.Trim(data[1], " ")
-			} else {
-				dst.Address = strings.Trim(groups[6], " ")
-			}
-		}
+	dst.Latitude = lat
+	dst.Longitude = lng
+
+	dst.OpeningHours, err = parseBenuWorkHours(src.WorkHours)
+	if err != nil {
+		logger.Warn().Msgf("Failed to extract BENU pharmacy opening hours: %v", err)
+	}
+
+	// BENU addresses are dash separated "[location - [district - ]]name - street address"
+	// strings, where the location is either a settlement or a municipality
+	parts := benuAddressSeparator.Split(strings.TrimSpace(src.Address), -1)
+	var addr address.Address
+	switch len(parts) {
+	case 2:
+		dst.Name = parts[0]
+		addr = address.Parse(parts[1], src.Region)
+	case 3:
+		dst.Name = parts[1]
+		addr = address.Parse(parts[2], parts[0], src.Region)
+	case 4:
+		dst.Name = parts[2]
+		addr = address.Parse(parts[3], parts[1], parts[0], src.Region)
+	default:
+		logger.Error().Msgf("Failed to extract BENU pharmacy address from '%s'", src.Address)
+		return fmt.Errorf("unrecognized address '%s'", src.Address)
 	}
 
+	dst.Address = addr.StreetAddress()
+	dst.City = addr.City()
+	dst.County = addr.County
+
 	return nil
 }
 
-func (scraper *BenuScraper) createEntitiesFromJson(data string) ([]entity.Pharmacy, error) {
+func (scraper *BenuScraper) createEntitiesFromJson(ctx context.Context, chain string, data string, result *ScrapeResult) ([]entity.Pharmacy, error) {
 	var pharmacies map[string]benuPharmacy
 	err := json.Unmarshal([]byte(data), &pharmacies)
 	if err != nil {
@@ -114,12 +128,6 @@ func (scraper *BenuScraper) createEntitiesFromJson(data string) ([]entity.Pharma
 		return nil, fmt.Errorf("failed to unmarshal BENU pharmacy json")
 	}
 
-	existing, err := scraper.repo.FindPharmaciesByChain(entity.CHAIN_BENU).QueryAll()
-	if err != nil {
-		scraper.logger.Error().Msgf("Failed to query existing BENU pharmacies in the database: %v", err)
-		return nil, fmt.Errorf("failed to query existing BENU pharmacies in the database")
-	}
-
 	ret := make([]entity.Pharmacy, 0)
 	for _, pharmacy := range pharmacies {
 		newTS, err := time.Parse("2006-01-02 15:04:05", pharmacy.ModTime)
@@ -128,57 +136,57 @@ func (scraper *BenuScraper) createEntitiesFromJson(data string) ([]entity.Pharma
 			newTS = time.Now().UTC()
 		}
 
-		var existingPharmacy *entity.Pharmacy
-		for i := range existing {
-			if pharmacy.ID == existing[i].PharmacyID {
-				existingPharmacy = &existing[i]
-				break
-			}
+		var newPharmacy entity.Pharmacy
+		err = pharmacy.mapToPharmacy(&newPharmacy, chain, newTS, &scraper.logger)
+		if err != nil {
+			result.skipPharmacy(pharmacy.ID)
+			continue
 		}
 
-		// if existing pharmacy was found, then we check if it should be updated based on the timestamps
-		if existingPharmacy != nil && (time.Time(existingPharmacy.ModTime).UTC().UnixMilli() < newTS.UTC().UnixMilli()) {
-			err := pharmacy.mapToPharmacy(existingPharmacy, newTS, &scraper.logger)
-			if err != nil {
-				continue
-			}
-			ret = append(ret, *existingPharmacy)
-		} else if existingPharmacy == nil {
-			var newPharmacy entity.Pharmacy
-			err := pharmacy.mapToPharmacy(&newPharmacy, newTS, &scraper.logger)
-			if err != nil {
-				continue
-			}
-			ret = append(ret, newPharmacy)
+		// BENU omits postal codes of some pharmacies
+		if newPharmacy.PostalCode == "" {
+			newPharmacy.PostalCode, _ = scraper.resolver.Resolve(ctx, fmt.Sprintf("%s, %s", newPharmacy.Address, newPharmacy.City))
 		}
+		ret = append(ret, newPharmacy)
 	}
 
 	return ret, nil
 }
 
-func (scraper *BenuScraper) Scrape() {
+func (scraper *BenuScraper) Name() string {
+	return "benu"
+}
+
+func (scraper *BenuScraper) Scrape(ctx context.Context) (ScrapeResult, error) {
 	scraper.logger.Info().Msg("Running BENU pharmacy scraper")
-	req, err := http.NewRequest("GET", BENU_ENDPOINT, nil)
+	var result ScrapeResult
+
+	chain, err := findScraperChain(ctx, scraper.chains, scraper.Name(), &scraper.logger)
+	if err != nil || chain == nil {
+		return result, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", BENU_ENDPOINT, nil)
 	if err != nil {
 		scraper.logger.Error().Msg("Failed to create a new request for BENU scraper")
-		return
+		return result, fmt.Errorf("failed to create a new request for BENU scraper: %v", err)
 	}
 	req.Header.Set("User-Agent", USER_AGENT)
 	resp, err := scraper.httpClient.Do(req)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to make a request to %s: %v", BENU_ENDPOINT, err)
-		return
+		return result, fmt.Errorf("failed to make a request to %s: %v", BENU_ENDPOINT, err)
 	}
 
 	// make sure that the server responded with status code 200
 	if resp.StatusCode != 200 {
 		scraper.logger.Error().Msgf("Benu endpoint responded with non-200 status code %d", resp.StatusCode)
-		return
+		return result, fmt.Errorf("benu endpoint responded with non-200 status code %d", resp.StatusCode)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		scraper.logger.Error().Msg("Failed to read response body from BENU endpoint request")
-		return
+		return result, fmt.Errorf("failed to read response body from BENU endpoint: %v", err)
 	}
 
 	script := soup.HTMLParse(string(body)).
@@ -188,18 +196,32 @@ func (scraper *BenuScraper) Scrape() {
 
 	if script.Error != nil {
 		scraper.logger.Error().Msg("Failed to extract script tag from BENU website's HTML body")
-		return
+		return result, fmt.Errorf("failed to extract script tag from BENU website's HTML body")
 	}
 
 	txt := script.Text()
 	re := regexp.MustCompile(`(?m)^.*?pharmacies = ({.+}).*$`)
-	data := re.FindStringSubmatch(txt)[1]
-	pharmacies, err := scraper.createEntitiesFromJson(data)
+	groups := re.FindStringSubmatch(txt)
+	if len(groups) != 2 {
+		scraper.logger.Error().Msg("Failed to find pharmacy json from BENU website's script tag")
+		return result, fmt.Errorf("failed to find pharmacy json from BENU website's script tag")
+	}
 
+	pharmacies, err := scraper.createEntitiesFromJson(ctx, chain.Name, groups[1], &result)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to read pharmacy data from json: %v", err)
-		return
+		return result, err
 	}
 
-	scraper.repo.StoreAll(pharmacies)
+	existing, err := scraper.repo.FindPharmaciesByChain(ctx, chain.Name).QueryAll()
+	if err != nil {
+		scraper.logger.Error().Msgf("Failed to query existing BENU pharmacies in the database: %v", err)
+		return result, fmt.Errorf("failed to query existing BENU pharmacies in the database: %v", err)
+	}
+
+	err = syncPharmacies(ctx, scraper.repo, scraper.guard, &result, existing, pharmacies)
+	if err != nil {
+		scraper.logger.Error().Msgf("Failed to persist BENU pharmacies: %v", err)
+	}
+	return result, err
 }
@@ -1,6 +1,7 @@
 package bg_test
 
 import (
+	"context"
 	"embed"
 	"io"
 	"net/http"
@@ -20,94 +21,100 @@ var benuHtml embed.FS
 
 var benuPharmacies map[int]entity.Pharmacy = map[int]entity.Pharmacy{
 	491: {
-		ID:          0,
-		PharmacyID:  491,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Veskimöldre BENU Apteek",
-		Address:     "Instituudi tee 132",
-		City:        "Saue vald",
-		County:      "Harjumaa",
-		PostalCode:  "76403",
-		Email:       "benu.5656@benu.ee",
-		PhoneNumber: "+3726888055",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-07-02 08:36:31"))),
-		Latitude:    59.35778,
-		Longitude:   24.60182,
+		ID:           0,
+		PharmacyID:   491,
+		Chain:        "Benu",
+		Name:         "Veskimöldre BENU Apteek",
+		Address:      "Instituudi tee 132",
+		City:         "Saue vald",
+		County:       "Harjumaa",
+		PostalCode:   "76403",
+		Email:        "benu.5656@benu.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3726888055", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-07-02 08:36:31"))),
+		Latitude:     59.35778,
+		Longitude:    24.60182,
+		OpeningHours: weeklyHours("10:00-18:00", "10:00-18:00", "10:00-18:00", "10:00-18:00", "10:00-18:00"),
 	},
 	406: {
-		ID:          0,
-		PharmacyID:  406,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Kohila apteek",
-		Address:     "Lõuna 2",
-		City:        "Kohila",
-		County:      "Raplamaa",
-		PostalCode:  "79804",
-		Email:       "kohilaapteek1@gmail.com",
-		PhoneNumber: "+3724833574",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-06-02 12:54:48"))),
-		Latitude:    59.16742,
-		Longitude:   24.74963,
+		ID:           0,
+		PharmacyID:   406,
+		Chain:        "Benu",
+		Name:         "Kohila apteek",
+		Address:      "Lõuna 2",
+		City:         "Kohila",
+		County:       "Raplamaa",
+		PostalCode:   "79804",
+		Email:        "kohilaapteek1@gmail.com",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3724833574", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-06-02 12:54:48"))),
+		Latitude:     59.16742,
+		Longitude:    24.74963,
+		OpeningHours: weeklyHours("09:00-18:00", "09:00-18:00", "09:00-18:00", "09:00-18:00", "09:00-18:00", "09:00-14:00"),
 	},
 	33: {
-		ID:          0,
-		PharmacyID:  33,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Lasnamäe Tervisemaja Apteek",
-		Address:     "Linnamäe tee 3, Lasnamäe",
-		City:        "Tallinn",
-		County:      "Harjumaa",
-		PostalCode:  "13912",
-		Email:       "benu.5154@benu.ee",
-		PhoneNumber: "+3726091998",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-09-02 19:51:29"))),
-		Latitude:    59.44924,
-		Longitude:   24.86303,
+		ID:           0,
+		PharmacyID:   33,
+		Chain:        "Benu",
+		Name:         "Lasnamäe Tervisemaja Apteek",
+		Address:      "Linnamäe tee 3",
+		City:         "Tallinn",
+		County:       "Harjumaa",
+		PostalCode:   "13912",
+		Email:        "benu.5154@benu.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3726091998", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-09-02 19:51:29"))),
+		Latitude:     59.44924,
+		Longitude:    24.86303,
+		OpeningHours: weeklyHours("08:00-20:00", "08:00-20:00", "08:00-20:00", "08:00-20:00", "08:00-20:00", "10:00-18:00", "10:00-18:00"),
 	},
 	465: {
-		ID:          0,
-		PharmacyID:  465,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Jõhvi Tsentraali apteek",
-		Address:     "Keskväljak 4",
-		City:        "Jõhvi",
-		County:      "Ida-Virumaa",
-		PostalCode:  "41531",
-		Email:       "benu.5642@benu.ee",
-		PhoneNumber: "+3726622001",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-07-07 16:06:07"))),
-		Latitude:    59.35835,
-		Longitude:   27.41395,
+		ID:           0,
+		PharmacyID:   465,
+		Chain:        "Benu",
+		Name:         "Jõhvi Tsentraali apteek",
+		Address:      "Keskväljak 4",
+		City:         "Jõhvi",
+		County:       "Ida-Virumaa",
+		PostalCode:   "41531",
+		Email:        "benu.5642@benu.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3726622001", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-07-07 16:06:07"))),
+		Latitude:     59.35835,
+		Longitude:    27.41395,
+		OpeningHours: temporaryHours("2025-07-21", "2025-09-03", "10:00-18:00", "10:00-18:00", "10:00-18:00", "10:00-18:00", "10:00-18:00"),
 	},
 	9: {
-		ID:          0,
-		PharmacyID:  9,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Mini-Rimi Apteek",
-		Address:     "Kihelkonna mnt 3",
-		City:        "Kuressaare",
-		County:      "Saaremaa",
-		PostalCode:  "93810",
-		Email:       "benu.5144@benu.ee",
-		PhoneNumber: "+3724554472",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-03-06 15:04:28"))),
-		Latitude:    58.26269,
-		Longitude:   22.48023,
+		ID:           0,
+		PharmacyID:   9,
+		Chain:        "Benu",
+		Name:         "Mini-Rimi Apteek",
+		Address:      "Kihelkonna mnt 3",
+		City:         "Kuressaare",
+		County:       "Saaremaa",
+		PostalCode:   "93810",
+		Email:        "benu.5144@benu.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3724554472", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-03-06 15:04:28"))),
+		Latitude:     58.26269,
+		Longitude:    22.48023,
+		OpeningHours: weeklyHours("09:00-20:30", "09:00-20:30", "09:00-20:30", "09:00-20:30", "09:00-20:30", "10:00-15:30"),
 	},
 	389: {
-		ID:          0,
-		PharmacyID:  389,
-		Chain:       string(entity.CHAIN_BENU),
-		Name:        "Kilingi-Nõmme Apteek",
-		Address:     "Pärnu mnt 65",
-		City:        "Kilingi-Nõmme",
-		County:      "Pärnumaa",
-		PostalCode:  "86305",
-		Email:       "knapt103@hot.ee",
-		PhoneNumber: "+3724430440",
-		ModTime:     types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-01-06 10:16:47"))),
-		Latitude:    58.149111,
-		Longitude:   24.960938,
+		ID:           0,
+		PharmacyID:   389,
+		Chain:        "Benu",
+		Name:         "Kilingi-Nõmme Apteek",
+		Address:      "Pärnu mnt 65",
+		City:         "Kilingi-Nõmme",
+		County:       "Pärnumaa",
+		PostalCode:   "86305",
+		Email:        "knapt103@hot.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3724430440", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(unwrap(time.Parse("2006-01-02 15:04:05", "2025-01-06 10:16:47"))),
+		Latitude:     58.149111,
+		Longitude:    24.960938,
+		OpeningHours: weeklyHours("09:00-18:00", "09:00-16:00", "09:00-18:00", "09:00-16:00", "09:00-16:00"),
 	},
 }
 
@@ -137,11 +144,11 @@ func TestBenuScraper_EmptyDB(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_BENU)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Benu")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 6, len(pharmacies))
 
 			for _, pharmacy := range pharmacies {
@@ -157,8 +164,12 @@ func TestBenuScraper_EmptyDB(t *testing.T) {
 			return nil
 		})
 
-	scraper := bg.ProvideBenuScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideBenuScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, result.Inserted)
+	assert.Equal(t, 0, result.Unchanged)
 }
 
 func TestBenuScraper_Existing(t *testing.T) {
@@ -197,17 +208,21 @@ func TestBenuScraper_Existing(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_BENU)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Benu")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 1, len(pharmacies))
 			assert.Equal(t, int64(0), pharmacies[0].ID)
 			assert.Equal(t, int64(33), pharmacies[0].PharmacyID)
 			return nil
 		})
 
-	scraper := bg.ProvideBenuScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideBenuScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 5, result.Unchanged)
 }
@@ -0,0 +1,97 @@
+package bg_test
+
+import (
+	"context"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Pharmacy repository without any pharmacies, which keeps stored pharmacies in memory
+type capturingRepository struct {
+	bg.EmptyPharmacyRepository
+	stored []entity.Pharmacy
+}
+
+func (repo *capturingRepository) StoreAll(ctx context.Context, pharmacies []entity.Pharmacy) error {
+	repo.stored = append(repo.stored, pharmacies...)
+	return nil
+}
+
+func (repo *capturingRepository) SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error {
+	return repo.StoreAll(ctx, pharmacies)
+}
+
+// Replays the cassette of given scraper and returns the pharmacies it stored
+func replayCassette(t *testing.T, name string, newScraper func(*capturingRepository, utils.HttpClient, *bg.PostalCodeResolver) bg.Scraper) (bg.ScrapeResult, []entity.Pharmacy) {
+	client, err := utils.NewCassetteHttpClient(utils.CassettePath("_cassettes", name), utils.CASSETTE_REPLAY, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	repo := &capturingRepository{}
+	resolver := bg.NewPostalCodeResolver(bg.EmptyAddressRepository{}, bg.EmptyPostalCodeCacheRepository{}, client, onlineLookup)
+	scraper := newScraper(repo, client, resolver)
+	assert.Equal(t, name, scraper.Name())
+
+	result, err := scraper.Scrape(context.Background())
+	assert.NoError(t, err)
+	return result, repo.stored
+}
+
+// Asserts that every pharmacy of the listing was parsed into a complete pharmacy. Cassettes
+// are re-recorded from the live websites with `make cassettes`, thus the exact pharmacies
+// aren't known in advance and only the invariants of any scrape are asserted
+func assertCassettePharmacies(t *testing.T, result bg.ScrapeResult, pharmacies []entity.Pharmacy) {
+	assert.NotEmpty(t, pharmacies)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Equal(t, len(pharmacies), result.Inserted)
+
+	seen := make(map[int64]bool)
+	for _, pharmacy := range pharmacies {
+		assert.False(t, seen[pharmacy.PharmacyID], "duplicate pharmacy %s", pharmacy.Name)
+		seen[pharmacy.PharmacyID] = true
+
+		assert.NotEmpty(t, pharmacy.Name)
+		assert.NotEmpty(t, pharmacy.Address, "address of pharmacy %s", pharmacy.Name)
+		assert.NotEmpty(t, pharmacy.City, "city of pharmacy %s", pharmacy.Name)
+		assert.NotEmpty(t, pharmacy.County, "county of pharmacy %s", pharmacy.Name)
+		assert.NotEmpty(t, pharmacy.PhoneNumbers, "phone numbers of pharmacy %s", pharmacy.Name)
+
+		// coordinates must be within Estonia
+		assert.True(t, pharmacy.Latitude > 57.5 && pharmacy.Latitude < 60, "latitude of pharmacy %s", pharmacy.Name)
+		assert.True(t, pharmacy.Longitude > 21.5 && pharmacy.Longitude < 28.5, "longitude of pharmacy %s", pharmacy.Name)
+	}
+}
+
+func TestCassette_Apotheka(t *testing.T) {
+	result, pharmacies := replayCassette(t, "apotheka", func(repo *capturingRepository, client utils.HttpClient, resolver *bg.PostalCodeResolver) bg.Scraper {
+		return bg.ProvideApothekaScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, nil)
+	})
+	assertCassettePharmacies(t, result, pharmacies)
+}
+
+func TestCassette_Benu(t *testing.T) {
+	result, pharmacies := replayCassette(t, "benu", func(repo *capturingRepository, client utils.HttpClient, resolver *bg.PostalCodeResolver) bg.Scraper {
+		return bg.ProvideBenuScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, nil)
+	})
+	assertCassettePharmacies(t, result, pharmacies)
+}
+
+func TestCassette_Euroapteek(t *testing.T) {
+	result, pharmacies := replayCassette(t, "euroapteek", func(repo *capturingRepository, client utils.HttpClient, resolver *bg.PostalCodeResolver) bg.Scraper {
+		return bg.ProvideEuroapteekScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, nil)
+	})
+	assertCassettePharmacies(t, result, pharmacies)
+}
+
+func TestCassette_Sudameapteek(t *testing.T) {
+	result, pharmacies := replayCassette(t, "sudameapteek", func(repo *capturingRepository, client utils.HttpClient, resolver *bg.PostalCodeResolver) bg.Scraper {
+		return bg.ProvideSydameapteekScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, nil)
+	})
+	assertCassettePharmacies(t, result, pharmacies)
+}
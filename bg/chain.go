@@ -0,0 +1,32 @@
+package bg
+
+import (
+	"context"
+	"fmt"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+
+	"github.com/rs/zerolog"
+)
+
+// Looks up the chain populated by the scraper of given kind from the chain registry.
+// Nil chain with nil error means that the chain is inactive and shall not be scraped
+func findScraperChain(ctx context.Context, chains db.ChainRepository, kind string, logger *zerolog.Logger) (*entity.Chain, error) {
+	candidates, err := chains.FindChainsByScraperKind(ctx, kind).QueryAll()
+	if err != nil {
+		logger.Error().Msgf("Failed to query chain of %s scraper: %v", kind, err)
+		return nil, fmt.Errorf("failed to query chain of %s scraper: %v", kind, err)
+	}
+
+	if len(candidates) != 1 {
+		logger.Error().Msgf("Expected exactly one chain for %s scraper, found %d", kind, len(candidates))
+		return nil, fmt.Errorf("expected exactly one chain for %s scraper, found %d", kind, len(candidates))
+	}
+
+	if !candidates[0].Active {
+		logger.Info().Msgf("Chain %s is inactive, skipping", candidates[0].Name)
+		return nil, nil
+	}
+
+	return &candidates[0], nil
+}
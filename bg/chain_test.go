@@ -0,0 +1,79 @@
+package bg_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestScraper_InactiveChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	chainMock := newChainMock(ctrl, entity.Chain{ID: 1, Name: "Apotheka", ScraperKind: utils.Ptr("apotheka"), Active: false})
+
+	// neither the website nor the database is touched
+	scraper := bg.ProvideApothekaScraper(repoMock, chainMock, httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, bg.ScrapeResult{}, result)
+}
+
+func TestScraper_MissingChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+
+	scraper := bg.ProvideBenuScraper(repoMock, newChainMock(ctrl), httpMock, newResolver(ctrl, httpMock), nil)
+	_, err := scraper.Scrape(context.Background())
+
+	assert.Error(t, err)
+}
+
+func TestScraper_RenamedChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			file, _ := benuHtml.Open("_embeds/benu.html")
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(file),
+			}, nil
+		})
+
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return(nil, nil)
+
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("BENU Apteek")).
+		Return(queryMock)
+	repoMock.EXPECT().
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
+			for _, pharmacy := range pharmacies {
+				assert.Equal(t, "BENU Apteek", pharmacy.Chain)
+			}
+			return nil
+		})
+
+	chainMock := newChainMock(ctrl, entity.Chain{ID: 3, Name: "BENU Apteek", ScraperKind: utils.Ptr("benu"), Active: true})
+	scraper := bg.ProvideBenuScraper(repoMock, chainMock, httpMock, newResolver(ctrl, httpMock), nil)
+	_, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+}
@@ -2,35 +2,92 @@ package bg
 
 import (
 	"context"
+	"math/rand"
+	"pharmafinder/utils"
+	"time"
 
 	"github.com/robfig/cron"
+	"github.com/rs/zerolog"
 	"go.uber.org/fx"
 )
 
-// Empty object to represent CronJobs
-// mainly used for periodical pharmacy data scraping
-type CronJob struct{}
+// CronJob periodically runs pharmacy data scrapers
+// according to their configured schedules
+type CronJob struct {
+	cron      *cron.Cron
+	runner    *ScrapeRunner
+	schedules []ScrapeSchedule
+	stop      chan struct{}
+	logger    zerolog.Logger
+}
 
-func NewCronJob(scrapers []Scraper, lc fx.Lifecycle) CronJob {
-	c := cron.New()
+func NewCronJob(scrapers []Scraper, runner *ScrapeRunner, lc fx.Lifecycle) (*CronJob, error) {
+	job := &CronJob{
+		cron:   cron.New(),
+		runner: runner,
+		stop:   make(chan struct{}),
+		logger: utils.GetLogger("BG"),
+	}
 
-	// Run scrapers on server startup
 	for _, scraper := range scrapers {
-		go scraper.Scrape()
+		schedule, err := loadScrapeSchedule(scraper.Name())
+		if err != nil {
+			return nil, err
+		}
+		job.schedules = append(job.schedules, schedule)
+
+		if !schedule.Enabled {
+			job.logger.Info().Msgf("Scraper %s is disabled", scraper.Name())
+			continue
+		}
+
+		job.cron.Schedule(schedule.schedule, cron.FuncJob(func() {
+			job.runWithJitter(scraper, schedule.jitter)
+		}))
 	}
 
 	lc.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
-			for _, scraper := range scrapers {
-				c.AddFunc("0 0 1 1,6 *", scraper.Scrape)
+			for i, scraper := range scrapers {
+				// Run enabled scrapers on server startup
+				if job.schedules[i].Enabled {
+					go job.runWithJitter(scraper, job.schedules[i].jitter)
+				}
 			}
+			job.cron.Start()
 			return nil
 		},
-		OnStop: func(_ context.Context) error {
-			c.Stop()
-			return nil
+		OnStop: func(ctx context.Context) error {
+			job.cron.Stop()
+			close(job.stop)
+			return runner.Stop(ctx)
 		},
 	})
 
-	return CronJob{}
+	return job, nil
+}
+
+// Runs the scraper after a random delay of at most jitter, so that
+// scrapers sharing a schedule would not hit the network at the same time
+func (job *CronJob) runWithJitter(scraper Scraper, jitter time.Duration) {
+	if jitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(jitter)))
+		select {
+		case <-time.After(delay):
+		case <-job.stop:
+			return
+		}
+	}
+
+	job.runner.Run(scraper)
+}
+
+// Returns effective schedules of all scrapers along with their next run times
+func (job *CronJob) Schedules() []ScrapeSchedule {
+	now := time.Now()
+	schedules := make([]ScrapeSchedule, len(job.schedules))
+	for i := range job.schedules {
+		schedules[i] = job.schedules[i].withNextRun(now)
+	}
+	return schedules
 }
@@ -0,0 +1,190 @@
+package bg
+
+import (
+	"context"
+	"pharmafinder/db"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+)
+
+// Read-only pharmacy repository, which reads from the wrapped
+// repository and silently discards all writes, so that scrapers
+// could compute their changes without persisting them
+type DryRunPharmacyRepository struct {
+	db.PharmacyRepository
+}
+
+func (repo DryRunPharmacyRepository) StoreAll(ctx context.Context, pharmacies []entity.Pharmacy) error {
+	return nil
+}
+
+func (repo DryRunPharmacyRepository) CloseAll(ctx context.Context, ids []int64, closedAt types.Time) error {
+	return nil
+}
+
+func (repo DryRunPharmacyRepository) SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error {
+	return nil
+}
+
+func (repo DryRunPharmacyRepository) Trx(conn any) db.PharmacyRepository {
+	return repo
+}
+
+// Read-only postal code cache, which discards all writes
+type DryRunPostalCodeCacheRepository struct {
+	db.PostalCodeCacheRepository
+}
+
+func (repo DryRunPostalCodeCacheRepository) Store(ctx context.Context, entry entity.PostalCodeCacheEntry) error {
+	return nil
+}
+
+func (repo DryRunPostalCodeCacheRepository) Trx(conn any) db.PostalCodeCacheRepository {
+	return repo
+}
+
+// Pharmacy repository without any pharmacies, which discards all writes.
+// Scraping against it reports every scraped pharmacy as inserted
+type EmptyPharmacyRepository struct{}
+
+func (repo EmptyPharmacyRepository) FindPharmacies(filter db.PharmacyFilter) db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{}
+}
+
+func (repo EmptyPharmacyRepository) FindNearestPharmacies(point types.Point, maxMeters *float64, includeClosed bool) db.Query[dto.PharmacyDistanceDTO] {
+	return &db.StaticQuery[dto.PharmacyDistanceDTO]{}
+}
+
+func (repo EmptyPharmacyRepository) SearchPharmacies(terms []string, bounds *types.Bounds, includeClosed bool) db.Query[dto.PharmacySearchResultDTO] {
+	return &db.StaticQuery[dto.PharmacySearchResultDTO]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmacyByID(id int64) db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmacyDetailsByID(id int64) db.Query[dto.PharmacyDetailsDTO] {
+	return &db.StaticQuery[dto.PharmacyDetailsDTO]{}
+}
+
+func (repo EmptyPharmacyRepository) FindIndependentPharmacies() db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmaciesByChain(ctx context.Context, chain string) db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmacyByChainAndPharmacyID(ctx context.Context, pharmacyID int64, chain string) db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmacyRatingsByID(id int64) db.Query[dto.PharmacyRatingDTO] {
+	return &db.StaticQuery[dto.PharmacyRatingDTO]{}
+}
+
+func (repo EmptyPharmacyRepository) FindPharmacyRatings(sw types.Point, ne types.Point) db.Query[dto.PharmacyTierRatingDTO] {
+	return &db.StaticQuery[dto.PharmacyTierRatingDTO]{}
+}
+
+func (repo EmptyPharmacyRepository) StoreAll(ctx context.Context, pharmacies []entity.Pharmacy) error {
+	return nil
+}
+
+func (repo EmptyPharmacyRepository) CloseAll(ctx context.Context, ids []int64, closedAt types.Time) error {
+	return nil
+}
+
+func (repo EmptyPharmacyRepository) SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error {
+	return nil
+}
+
+func (repo EmptyPharmacyRepository) Trx(conn any) db.PharmacyRepository {
+	return repo
+}
+
+// Address dataset without any addresses
+type EmptyAddressRepository struct{}
+
+func (repo EmptyAddressRepository) FindAddressesByKey(ctx context.Context, addressKey string, settlementKey string) db.Query[entity.Address] {
+	return &db.StaticQuery[entity.Address]{}
+}
+
+func (repo EmptyAddressRepository) ReplaceAll(ctx context.Context, addresses []entity.Address) error {
+	return nil
+}
+
+func (repo EmptyAddressRepository) Trx(conn any) db.AddressRepository {
+	return repo
+}
+
+// Postal code cache without any entries, which discards all writes
+type EmptyPostalCodeCacheRepository struct{}
+
+func (repo EmptyPostalCodeCacheRepository) FindByAddress(ctx context.Context, address string) db.Query[entity.PostalCodeCacheEntry] {
+	return &db.StaticQuery[entity.PostalCodeCacheEntry]{}
+}
+
+func (repo EmptyPostalCodeCacheRepository) Store(ctx context.Context, entry entity.PostalCodeCacheEntry) error {
+	return nil
+}
+
+func (repo EmptyPostalCodeCacheRepository) Trx(conn any) db.PostalCodeCacheRepository {
+	return repo
+}
+
+// In-memory chain registry over a fixed set of chains, which discards all writes
+type StaticChainRepository struct {
+	Chains []entity.Chain
+}
+
+func (repo StaticChainRepository) FindChains(includeInactive bool) db.Query[dto.ChainDTO] {
+	chains := make([]dto.ChainDTO, 0)
+	for _, chain := range repo.Chains {
+		if includeInactive || chain.Active {
+			chains = append(chains, dto.ChainDTO{Chain: chain})
+		}
+	}
+	return &db.StaticQuery[dto.ChainDTO]{Values: chains}
+}
+
+func (repo StaticChainRepository) FindChainByID(id int64) db.Query[entity.Chain] {
+	for _, chain := range repo.Chains {
+		if chain.ID == id {
+			return &db.StaticQuery[entity.Chain]{Values: []entity.Chain{chain}}
+		}
+	}
+	return &db.StaticQuery[entity.Chain]{}
+}
+
+func (repo StaticChainRepository) FindChainByName(ctx context.Context, name string) db.Query[entity.Chain] {
+	for _, chain := range repo.Chains {
+		if chain.Name == name {
+			return &db.StaticQuery[entity.Chain]{Values: []entity.Chain{chain}}
+		}
+	}
+	return &db.StaticQuery[entity.Chain]{}
+}
+
+func (repo StaticChainRepository) FindChainsByScraperKind(ctx context.Context, kind string) db.Query[entity.Chain] {
+	chains := make([]entity.Chain, 0)
+	for _, chain := range repo.Chains {
+		if chain.ScraperKind != nil && *chain.ScraperKind == kind {
+			chains = append(chains, chain)
+		}
+	}
+	return &db.StaticQuery[entity.Chain]{Values: chains}
+}
+
+func (repo StaticChainRepository) Store(chain *entity.Chain) error {
+	return nil
+}
+
+func (repo StaticChainRepository) Delete(id int64) db.Query[entity.Chain] {
+	return repo.FindChainByID(id)
+}
+
+func (repo StaticChainRepository) Trx(conn any) db.ChainRepository {
+	return repo
+}
@@ -1,21 +1,20 @@
 package bg
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"hash/crc64"
 	"io"
 	"net/http"
+	"pharmafinder/address"
 	"pharmafinder/db"
 	"pharmafinder/db/entity"
+	"pharmafinder/phone"
 	"pharmafinder/types"
 	"pharmafinder/utils"
-	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/anaskhan96/soup"
 	"github.com/rs/zerolog"
 )
 
@@ -23,7 +22,10 @@ const EUROAPTEEK_WEBSITE = "https://www.euroapteek.ee/apteegid"
 
 type EuroapteekScraper struct {
 	repo       db.PharmacyRepository
+	chains     db.ChainRepository
 	httpClient utils.HttpClient
+	resolver   *PostalCodeResolver
+	guard      *SanityGuard
 	logger     zerolog.Logger
 }
 
@@ -35,21 +37,32 @@ type euroapteekPharmacy struct {
 	County      string `json:"country"`
 	Latitude    string `json:"lat"`
 	Longitude   string `json:"lng"`
+
+	MondayFridayHours string `json:"mondayFridayHours"`
+	SaturdayHours     string `json:"saturdayHours"`
+	SundayHours       string `json:"sundayHours"`
 }
 
 var crc64Table *crc64.Table = crc64.MakeTable(crc64.ISO)
 
-func ProvideEuroapteekScraper(repo db.PharmacyRepository, client utils.HttpClient) Scraper {
+func ProvideEuroapteekScraper(repo db.PharmacyRepository, chains db.ChainRepository, client utils.HttpClient, resolver *PostalCodeResolver, guard *SanityGuard) Scraper {
 	return &EuroapteekScraper{
 		repo:       repo,
+		chains:     chains,
 		httpClient: client,
+		resolver:   resolver,
+		guard:      guard,
 		logger:     utils.GetLogger("BG"),
 	}
 }
 
-func (scraper *EuroapteekScraper) mapToPharmacies(existingPharmacies []entity.Pharmacy, scrapedPharmacies []euroapteekPharmacy) []entity.Pharmacy {
+func (scraper *EuroapteekScraper) mapToPharmacies(ctx context.Context, chain string, existingPharmacies []entity.Pharmacy, scrapedPharmacies []euroapteekPharmacy, result *ScrapeResult) ([]entity.Pharmacy, error) {
 	pharmacies := make([]entity.Pharmacy, 0)
 	for _, scraped := range scrapedPharmacies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		var existingPharmacy *entity.Pharmacy
 		pharmacyID := crc64.Checksum([]byte(scraped.Name), crc64Table)
 		for i := range existingPharmacies {
@@ -59,150 +72,159 @@ func (scraper *EuroapteekScraper) mapToPharmacies(existingPharmacies []entity.Ph
 			}
 		}
 
-		if existingPharmacy == nil {
-			var pharmacy entity.Pharmacy
-			pharmacy.PharmacyID = int64(crc64.Checksum([]byte(scraped.Name), crc64Table))
-			pharmacy.Chain = string(entity.CHAIN_EUROAPTEEK)
-			pharmacy.Name = scraped.Name
-			pharmacy.Address = scraped.Address
-			pharmacy.City = scraped.City
-			pharmacy.County = scraped.County
-			// Commented out for testing reasons (this is extremely slow query)
-			pharmacy.PostalCode = fetchOmnivaZipCode(fmt.Sprintf("%s, %s, %s", pharmacy.Address, pharmacy.City, pharmacy.County), scraper.httpClient, &scraper.logger)
-			pharmacy.ModTime = types.Time(time.UnixMilli(0))
-
-			// extract coordinates (lat, lng)
-			lat, err := strconv.ParseFloat(scraped.Latitude, 32)
-			if err != nil {
-				scraper.logger.Error().Msgf("Failed to extract latitude for Euroapteek pharmacy %s: %v", pharmacy.Name, err)
-				continue
-			}
-			pharmacy.Latitude = float32(lat)
+		var pharmacy entity.Pharmacy
+		pharmacy.PharmacyID = int64(pharmacyID)
+		pharmacy.Chain = chain
+		pharmacy.Name = scraped.Name
+		addr := address.Parse(scraped.Address, scraped.City, scraped.County)
+		pharmacy.Address = addr.StreetAddress()
+		pharmacy.City = addr.City()
+		pharmacy.County = addr.County
+		pharmacy.ModTime = types.Time(time.UnixMilli(0))
+		pharmacy.OpeningHours = parseWorkdayWeekendHours(scraped.MondayFridayHours, scraped.SaturdayHours, scraped.SundayHours)
+
+		// Zip code lookups are extremely slow, thus we only
+		// query them for pharmacies which we haven't seen before
+		if existingPharmacy != nil {
+			pharmacy.PostalCode = existingPharmacy.PostalCode
+		} else {
+			pharmacy.PostalCode, _ = scraper.resolver.Resolve(ctx, fmt.Sprintf("%s, %s, %s", pharmacy.Address, pharmacy.City, pharmacy.County))
+		}
 
-			lng, err := strconv.ParseFloat(scraped.Longitude, 32)
-			if err != nil {
-				scraper.logger.Error().Msgf("Failed to extract longitude for Euroapteek pharmacy %s: %v", pharmacy.Name, err)
-				continue
-			}
-			pharmacy.Longitude = float32(lng)
-
-			// extract and sanitize phone number
-			re := regexp.MustCompile(`(\+372)? *([\d ]+)`)
-			groups := re.FindStringSubmatch(scraped.PhoneNumber)
-			if len(groups) == 3 {
-				pharmacy.PhoneNumber = fmt.Sprintf("+372%s", strings.ReplaceAll(groups[2], " ", ""))
-			} else {
-				scraper.logger.Warn().Msgf("Failed to extract phone number for Euroapteek pharmacy %s", pharmacy.Name)
-			}
+		// extract coordinates (lat, lng)
+		lat, err := strconv.ParseFloat(scraped.Latitude, 64)
+		if err != nil {
+			scraper.logger.Error().Msgf("Failed to extract latitude for Euroapteek pharmacy %s: %v", pharmacy.Name, err)
+			result.skipPharmacy(pharmacy.PharmacyID)
+			continue
+		}
+		pharmacy.Latitude = lat
 
-			pharmacies = append(pharmacies, pharmacy)
+		lng, err := strconv.ParseFloat(scraped.Longitude, 64)
+		if err != nil {
+			scraper.logger.Error().Msgf("Failed to extract longitude for Euroapteek pharmacy %s: %v", pharmacy.Name, err)
+			result.skipPharmacy(pharmacy.PharmacyID)
+			continue
 		}
+		pharmacy.Longitude = lng
+
+		// invalid numbers are left out, while valid ones are kept
+		pharmacy.PhoneNumbers, err = phone.Parse(scraped.PhoneNumber)
+		if err != nil {
+			scraper.logger.Warn().Msgf("Failed to extract phone number for Euroapteek pharmacy %s: %v", pharmacy.Name, err)
+		}
+
+		pharmacies = append(pharmacies, pharmacy)
 	}
 
-	return pharmacies
+	return pharmacies, nil
 }
 
-func (scraper *EuroapteekScraper) extractEuroapteekPharmaciesFromJson(data string) ([]euroapteekPharmacy, error) {
-	var pharmacies []interface{}
-	err := json.Unmarshal([]byte(data), &pharmacies)
-	if err != nil {
-		scraper.logger.Error().Msgf("Failed to unmarshal Euroapteek json: %v", err)
-		return nil, err
+// Reports whether a flight data object is shaped like a Euroapteek pharmacy
+func isEuroapteekPharmacy(object map[string]interface{}) bool {
+	for _, key := range []string{"name", "address", "city", "lat", "lng"} {
+		if _, ok := object[key].(string); !ok {
+			return false
+		}
 	}
+	return true
+}
 
-	pharmacyMaps, ok := pharmacies[3].(map[string]interface{})["children"].([]interface{})[3].(map[string]interface{})["children"].([]interface{})[2].([]interface{})[3].(map[string]interface{})["pharmacies"].([]interface{})
-	if !ok {
-		scraper.logger.Error().Msg("Failed to extract pharmacy list from Euroapteek JSON")
-		return nil, fmt.Errorf("failed to extract pharmacies array from JSON")
+// Extracts pharmacies from the Next.js flight data of the Euroapteek pharmacies page by
+// searching the rendered tree for pharmacy-shaped objects, so that changes to the page
+// layout would not break the scraper as long as the pharmacy objects keep their shape
+func (scraper *EuroapteekScraper) extractEuroapteekPharmacies(html string) ([]euroapteekPharmacy, error) {
+	objects, err := findNextFlightObjects(html, isEuroapteekPharmacy)
+	if err != nil {
+		return nil, err
+	} else if len(objects) == 0 {
+		return nil, fmt.Errorf("no pharmacy objects found in Next.js flight data")
 	}
 
-	var candidatePharmacies []euroapteekPharmacy
-	for _, pharmacyInterface := range pharmacyMaps {
-		if pharmacy, ok := pharmacyInterface.(map[string]interface{}); ok {
-			ok := make([]bool, 7)
-			var name, phoneNumber, address, city, county, latitude, longitude string
-			name, ok[0] = pharmacy["name"].(string)
-			phoneNumber, ok[1] = pharmacy["phoneNumber"].(string)
-			address, ok[2] = pharmacy["address"].(string)
-			city, ok[3] = pharmacy["city"].(string)
-			county, ok[4] = pharmacy["country"].(string)
-			latitude, ok[5] = pharmacy["lat"].(string)
-			longitude, ok[6] = pharmacy["lng"].(string)
-
-			if !ok[0] || !ok[1] || !ok[2] || !ok[3] ||
-				!ok[4] || !ok[5] || !ok[6] {
-				continue
-			}
-
-			candidatePharmacies = append(candidatePharmacies, euroapteekPharmacy{
-				Name:        name,
-				PhoneNumber: phoneNumber,
-				Address:     address,
-				City:        city,
-				County:      county,
-				Latitude:    latitude,
-				Longitude:   longitude,
-			})
-		} else {
+	// the same pharmacy may be rendered by several components
+	seen := make(map[string]bool)
+	pharmacies := make([]euroapteekPharmacy, 0)
+	for _, object := range objects {
+		var pharmacy euroapteekPharmacy
+		pharmacy.Name, _ = object["name"].(string)
+		pharmacy.PhoneNumber, _ = object["phoneNumber"].(string)
+		pharmacy.Address, _ = object["address"].(string)
+		pharmacy.City, _ = object["city"].(string)
+		pharmacy.County, _ = object["country"].(string)
+		pharmacy.Latitude, _ = object["lat"].(string)
+		pharmacy.Longitude, _ = object["lng"].(string)
+
+		// opening hours are optional
+		pharmacy.MondayFridayHours, _ = object["mondayFridayHours"].(string)
+		pharmacy.SaturdayHours, _ = object["saturdayHours"].(string)
+		pharmacy.SundayHours, _ = object["sundayHours"].(string)
+
+		if seen[pharmacy.Name] {
 			continue
 		}
+		seen[pharmacy.Name] = true
+		pharmacies = append(pharmacies, pharmacy)
 	}
 
-	return candidatePharmacies, nil
+	return pharmacies, nil
+}
+
+func (scraper *EuroapteekScraper) Name() string {
+	return "euroapteek"
 }
 
-func (scraper *EuroapteekScraper) Scrape() {
+func (scraper *EuroapteekScraper) Scrape(ctx context.Context) (ScrapeResult, error) {
 	scraper.logger.Info().Msg("Scraping Euroapteek pharmacy locations...")
+	var result ScrapeResult
 
-	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(entity.CHAIN_EUROAPTEEK).QueryAll()
+	chain, err := findScraperChain(ctx, scraper.chains, scraper.Name(), &scraper.logger)
+	if err != nil || chain == nil {
+		return result, err
+	}
+
+	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(ctx, chain.Name).QueryAll()
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to query existing Euroapteek pharmacies: %v", err)
-		return
+		return result, fmt.Errorf("failed to query existing Euroapteek pharmacies: %v", err)
 	}
 
-	req, err := http.NewRequest("GET", EUROAPTEEK_WEBSITE, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", EUROAPTEEK_WEBSITE, nil)
 	if err != nil {
 		scraper.logger.Error().Msg("Failed to create a request object for Euroapteek API")
-		return
+		return result, fmt.Errorf("failed to create a request object for Euroapteek API: %v", err)
 	}
 
 	resp, err := scraper.httpClient.Do(req)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to make a request to Euroapteek API: %v", err)
-		return
+		return result, fmt.Errorf("failed to make a request to Euroapteek API: %v", err)
 	}
 
 	if resp.StatusCode != 200 {
 		scraper.logger.Error().Msgf("Euroapteek API responded with non-200 status code %d", resp.StatusCode)
-		return
+		return result, fmt.Errorf("euroapteek API responded with non-200 status code %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to read response body from Euroapteek API: %v", err)
-		return
+		return result, fmt.Errorf("failed to read response body from Euroapteek API: %v", err)
 	}
 
-	scripts := soup.HTMLParse(string(body)).FindAll("script")
-	if len(scripts) < 46 {
-		scraper.logger.Error().Msgf("Failed to find the required script tag for Euroapteek HTML")
-		return
+	scrapedPharmacies, err := scraper.extractEuroapteekPharmacies(string(body))
+	if err != nil {
+		scraper.logger.Error().Msgf("Failed to extract pharmacies from Euroapteek HTML: %v", err)
+		return result, fmt.Errorf("failed to extract pharmacies from Euroapteek HTML: %v", err)
 	}
 
-	script := scripts[45]
-	scriptTxt := strings.ReplaceAll(strings.TrimSpace(script.Text()), "\n", " ")
-	re := regexp.MustCompile(`self\.__next_f\.push\(\[\d+,( |\t)*"\d+:(.*)"( |\t)*\]\)`)
-	data := strings.ReplaceAll(re.FindStringSubmatch(scriptTxt)[2], "\\n", "")
-	data = strings.ReplaceAll(data, "\\", "")
-	scrapedPharmacies, err := scraper.extractEuroapteekPharmaciesFromJson(data)
+	pharmacies, err := scraper.mapToPharmacies(ctx, chain.Name, existingPharmacies, scrapedPharmacies, &result)
 	if err != nil {
-		scraper.logger.Error().Msgf("Failed to create pharmacy entities from provided Euroapteek json")
-		return
+		return result, err
 	}
 
-	pharmacies := scraper.mapToPharmacies(existingPharmacies, scrapedPharmacies)
-	err = scraper.repo.StoreAll(pharmacies)
+	err = syncPharmacies(ctx, scraper.repo, scraper.guard, &result, existingPharmacies, pharmacies)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to persist Euroapteek pharmacies: %v", err)
 	}
+	return result, err
 }
@@ -1,7 +1,9 @@
 package bg_test
 
 import (
+	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 	"pharmafinder/db/entity"
 	"pharmafinder/mock"
 	"pharmafinder/types"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -17,35 +20,37 @@ import (
 	"go.uber.org/mock/gomock"
 )
 
-//go:embed _embeds/euroapteek.html
+//go:embed _embeds/euroapteek.html _embeds/euroapteek_empty.html
 var euroapteekHtml embed.FS
 
 var euroapteekPharmacies map[int64]entity.Pharmacy = map[int64]entity.Pharmacy{
 	int64(-7238096502453610823): {
-		PharmacyID:  -7238096502453610823,
-		Chain:       string(entity.CHAIN_EUROAPTEEK),
-		Name:        "Liivaku Apteek",
-		Address:     "J. Sütiste tee 28",
-		City:        "Tallinn",
-		County:      "Harjumaa",
-		PostalCode:  "13411",
-		PhoneNumber: "+37282820101",
-		ModTime:     types.Time(time.UnixMilli(0)),
-		Latitude:    59.397629,
-		Longitude:   24.69058,
+		PharmacyID:   -7238096502453610823,
+		Chain:        "Euroapteek",
+		Name:         "Liivaku Apteek",
+		Address:      "J. Sütiste tee 28",
+		City:         "Tallinn",
+		County:       "Harjumaa",
+		PostalCode:   "13411",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+37282820101", Type: entity.PHONE_NUMBER_TYPE_MOBILE}},
+		ModTime:      types.Time(time.UnixMilli(0)),
+		Latitude:     59.397629,
+		Longitude:    24.69058,
+		OpeningHours: weeklyHours("09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "10:00-18:00"),
 	},
 	int64(-2073188454510069133): {
-		PharmacyID:  -2073188454510069133,
-		Chain:       string(entity.CHAIN_EUROAPTEEK),
-		Name:        "Nõmme Tee Apteek",
-		Address:     "Nõmme tee 23a",
-		City:        "Tallinn",
-		County:      "Harjumaa",
-		PostalCode:  "11311",
-		PhoneNumber: "+37282820102",
-		ModTime:     types.Time(time.UnixMilli(0)),
-		Latitude:    59.41785,
-		Longitude:   24.72165,
+		PharmacyID:   -2073188454510069133,
+		Chain:        "Euroapteek",
+		Name:         "Nõmme Tee Apteek",
+		Address:      "Nõmme tee 23a",
+		City:         "Tallinn",
+		County:       "Harjumaa",
+		PostalCode:   "11311",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+37282820102", Type: entity.PHONE_NUMBER_TYPE_MOBILE}},
+		ModTime:      types.Time(time.UnixMilli(0)),
+		Latitude:     59.41785,
+		Longitude:    24.72165,
+		OpeningHours: weeklyHours("09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-20:00", "09:00-18:00", "09:00-17:00"),
 	},
 }
 
@@ -92,11 +97,11 @@ func TestEuroapteekScraper_EmptyDB(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_EUROAPTEEK)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Euroapteek")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 2, len(pharmacies))
 
 			for _, pharmacy := range pharmacies {
@@ -112,8 +117,12 @@ func TestEuroapteekScraper_EmptyDB(t *testing.T) {
 			return nil
 		})
 
-	scraper := bg.ProvideEuroapteekScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideEuroapteekScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Inserted)
+	assert.Equal(t, 0, result.Unchanged)
 }
 
 func TestEuroapteekScraper_Existing(t *testing.T) {
@@ -163,11 +172,11 @@ func TestEuroapteekScraper_Existing(t *testing.T) {
 
 	repoMock := mock.NewMockPharmacyRepository(ctrl)
 	repoMock.EXPECT().
-		FindPharmaciesByChain(gomock.Eq(entity.CHAIN_EUROAPTEEK)).
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Euroapteek")).
 		Return(queryMock)
 	repoMock.EXPECT().
-		StoreAll(gomock.Any()).
-		DoAndReturn(func(pharmacies []entity.Pharmacy) error {
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
 			assert.Equal(t, 1, len(pharmacies))
 
 			for _, pharmacy := range pharmacies {
@@ -183,6 +192,145 @@ func TestEuroapteekScraper_Existing(t *testing.T) {
 			return nil
 		})
 
-	scraper := bg.ProvideEuroapteekScraper(repoMock, httpMock)
-	scraper.Scrape()
+	scraper := bg.ProvideEuroapteekScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 1, result.Unchanged)
+}
+
+// Creates an HTTP client, which serves given Euroapteek page
+// and resolves postal codes of the Euroapteek test pharmacies
+func newEuroapteekClient(ctrl *gomock.Controller, page string) *mock.MockHttpClient {
+	html, _ := euroapteekHtml.ReadFile(page)
+	return newEuroapteekPageClient(ctrl, string(html))
+}
+
+func newEuroapteekPageClient(ctrl *gomock.Controller, html string) *mock.MockHttpClient {
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "www.omniva.ee" {
+				zipCodes := map[string]string{
+					"J. Sütiste tee 28, Tallinn, Harjumaa": "13411",
+					"Nõmme tee 23a, Tallinn, Harjumaa":     "11311",
+				}
+				zipCode := zipCodes[req.URL.Query().Get("search")]
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"addresses":[{"address":"","zipCode":"%s"}]}`, zipCode))),
+				}, nil
+			}
+
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(html)),
+			}, nil
+		})
+	return httpMock
+}
+
+var flightChunkRegex = regexp.MustCompile(`self\.__next_f\.push\(\[\s*1\s*,\s*("(?:[^"\\]|\\.)*")\s*\]\)`)
+
+// Derives a page version from the captured page by rewriting its flight data.
+// The rewritten flight data is pushed in fixed size chunks, which split rows
+// at other places than the captured page does
+func deriveEuroapteekPage(t *testing.T, rewrite func(stream string, pharmacies json.RawMessage) string) string {
+	html, err := euroapteekHtml.ReadFile("_embeds/euroapteek.html")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var stream strings.Builder
+	for _, match := range flightChunkRegex.FindAllStringSubmatch(string(html), -1) {
+		var chunk string
+		if !assert.NoError(t, json.Unmarshal([]byte(match[1]), &chunk)) {
+			t.FailNow()
+		}
+		stream.WriteString(chunk)
+	}
+
+	// pharmacy list of the captured page, which is deeply nested in the page tree
+	start := strings.Index(stream.String(), `"pharmacies":[`) + len(`"pharmacies":`)
+	decoder := json.NewDecoder(strings.NewReader(stream.String()[start:]))
+	var pharmacies json.RawMessage
+	if !assert.NoError(t, decoder.Decode(&pharmacies)) {
+		t.FailNow()
+	}
+
+	rewritten := []rune(rewrite(stream.String(), pharmacies))
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html><html><body><script>(self.__next_f = self.__next_f || []).push([0])</script>")
+	for i := 0; i < len(rewritten); i += 1000 {
+		chunk, _ := json.Marshal(string(rewritten[i:min(i+1000, len(rewritten))]))
+		page.WriteString(fmt.Sprintf("<script>self.__next_f.push([1,%s])</script>", chunk))
+	}
+	page.WriteString("</body></html>")
+	return page.String()
+}
+
+func TestEuroapteekScraper_PageVersions(t *testing.T) {
+	pages := map[string]func(t *testing.T) string{
+		// captured page with the pharmacy list deeply nested in the page tree
+		"captured": func(t *testing.T) string {
+			html, _ := euroapteekHtml.ReadFile("_embeds/euroapteek.html")
+			return string(html)
+		},
+		// pharmacy list in a separate row referenced by the page tree
+		"referenced list": func(t *testing.T) string {
+			return deriveEuroapteekPage(t, func(stream string, pharmacies json.RawMessage) string {
+				nested := `"pharmacies":` + string(pharmacies)
+				assert.Contains(t, stream, nested)
+				stream = strings.Replace(stream, nested, `"pharmacies":"$ff"`, 1)
+				return stream + "ff:" + string(pharmacies) + "\n"
+			})
+		},
+		// pharmacies rendered by two components with different wrappers
+		"two components": func(t *testing.T) string {
+			return deriveEuroapteekPage(t, func(stream string, pharmacies json.RawMessage) string {
+				var items []json.RawMessage
+				assert.NoError(t, json.Unmarshal(pharmacies, &items))
+
+				children := make([]string, len(items))
+				for i, item := range items {
+					children[i] = fmt.Sprintf(`["$","li","%d",{"data":%s}]`, i, item)
+				}
+				return stream + `ff:["$","ul",null,{"children":[` + strings.Join(children, ",") + "]}]\n"
+			})
+		},
+	}
+
+	for name, page := range pages {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := newEuroapteekPageClient(ctrl, page(t))
+			repo := &capturingRepository{}
+
+			scraper := bg.ProvideEuroapteekScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, newResolver(ctrl, client), nil)
+			result, err := scraper.Scrape(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, len(euroapteekPharmacies), result.Inserted)
+			assert.Equal(t, len(euroapteekPharmacies), len(repo.stored))
+			for _, pharmacy := range repo.stored {
+				assert.Equal(t, euroapteekPharmacies[pharmacy.PharmacyID], pharmacy)
+			}
+		})
+	}
+}
+
+func TestEuroapteekScraper_MissingPharmacies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := newEuroapteekClient(ctrl, "_embeds/euroapteek_empty.html")
+	repo := &capturingRepository{}
+
+	scraper := bg.ProvideEuroapteekScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, newResolver(ctrl, client), nil)
+	_, err := scraper.Scrape(context.Background())
+
+	// existing pharmacies must not be closed
+	assert.ErrorContains(t, err, "no pharmacy objects found in Next.js flight data")
+	assert.Empty(t, repo.stored)
 }
@@ -0,0 +1,160 @@
+package bg
+
+import (
+	"math"
+	"pharmafinder/address"
+	"pharmafinder/db/entity"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Minimum confidence of a match between a scraped and an existing
+// pharmacy for it to be applied without manual confirmation
+const IDENTITY_MATCH_CONFIDENCE = 0.8
+
+// Minimum confidence of a match between a scraped and an existing
+// pharmacy for it to be queued for manual confirmation
+const IDENTITY_REVIEW_CONFIDENCE = 0.5
+
+// Returns the great-circle distance between two pharmacies in meters
+func pharmacyDistance(a *entity.Pharmacy, b *entity.Pharmacy) float64 {
+	const earthRadius = 6371000.0
+	lat1 := float64(a.Latitude) * math.Pi / 180
+	lat2 := float64(b.Latitude) * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := float64(b.Longitude-a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// Scores how likely it is that a scraped pharmacy without a known identity is the
+// existing pharmacy, e.g. after it was renamed. The score is in range [0, 1] and is
+// built from the normalized street address, settlement, name and geographic proximity
+func identityConfidence(existing *entity.Pharmacy, scraped *entity.Pharmacy) float64 {
+	if existing.Chain != scraped.Chain {
+		return 0
+	}
+
+	score := 0.0
+	if street := address.StreetKey(scraped.Address); street != "" && street == address.StreetKey(existing.Address) {
+		score += 0.4
+	}
+
+	if city := address.SettlementKey(scraped.City); city != "" && city == address.SettlementKey(existing.City) {
+		score += 0.1
+	}
+
+	if name := strings.ToLower(strings.TrimSpace(scraped.Name)); name != "" && name == strings.ToLower(strings.TrimSpace(existing.Name)) {
+		score += 0.2
+	}
+
+	// missing coordinates say nothing about the location
+	if existing.Latitude != 0 && scraped.Latitude != 0 {
+		switch distance := pharmacyDistance(existing, scraped); {
+		case distance <= 25:
+			score += 0.4
+		case distance <= 100:
+			score += 0.3
+		case distance <= 300:
+			score += 0.1
+		}
+	}
+
+	return math.Min(score, 1)
+}
+
+// Outcome of matching scraped pharmacies to existing ones
+type identityMatches struct {
+	// Index of the matching existing pharmacy for every
+	// scraped pharmacy or -1 if the pharmacy is new
+	existing []int
+	// Scraped pharmacies with matches awaiting manual confirmation
+	pending []bool
+	// Existing pharmacies, which are candidates of pending matches
+	held []bool
+}
+
+// Matches scraped pharmacies to existing ones by their source ID and, failing that,
+// by confidence of them being the same pharmacy. A match is only applied automatically
+// if it is confident enough and neither of the pharmacies has any other confident
+// matches. Less confident or ambiguous matches are queued into the result for manual
+// confirmation, in which case neither of the pharmacies is touched until the match is resolved.
+// Pharmacies are never matched again once their match has been rejected
+func matchIdentities(result *ScrapeResult, existing []entity.Pharmacy, scraped []entity.Pharmacy) identityMatches {
+	matches := identityMatches{
+		existing: make([]int, len(scraped)),
+		pending:  make([]bool, len(scraped)),
+		held:     make([]bool, len(existing)),
+	}
+
+	taken := make([]bool, len(existing))
+	for i := range scraped {
+		matches.existing[i] = -1
+		for j := range existing {
+			if !taken[j] && existing[j].PharmacyID == scraped[i].PharmacyID && existing[j].Chain == scraped[i].Chain {
+				matches.existing[i] = j
+				taken[j] = true
+				break
+			}
+		}
+	}
+
+	type candidate struct {
+		scraped    int
+		existing   int
+		confidence float64
+	}
+
+	candidates := make([]candidate, 0)
+	confidentScraped := make(map[int]int)
+	confidentExisting := make(map[int]int)
+	for i := range scraped {
+		if matches.existing[i] >= 0 {
+			continue
+		}
+
+		for j := range existing {
+			// merged pharmacies are only matched by their source ID,
+			// while rejected matches are never queued again
+			if taken[j] || existing[j].MergedInto != nil || slices.Contains(existing[j].RejectedMatches, scraped[i].PharmacyID) {
+				continue
+			}
+
+			confidence := identityConfidence(&existing[j], &scraped[i])
+			if confidence < IDENTITY_REVIEW_CONFIDENCE {
+				continue
+			}
+
+			candidates = append(candidates, candidate{scraped: i, existing: j, confidence: confidence})
+			if confidence >= IDENTITY_MATCH_CONFIDENCE {
+				confidentScraped[i]++
+				confidentExisting[j]++
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].confidence > candidates[b].confidence
+	})
+
+	for _, c := range candidates {
+		if c.confidence >= IDENTITY_MATCH_CONFIDENCE && confidentScraped[c.scraped] == 1 && confidentExisting[c.existing] == 1 {
+			matches.existing[c.scraped] = c.existing
+			taken[c.existing] = true
+		}
+	}
+
+	for _, c := range candidates {
+		if matches.existing[c.scraped] >= 0 || taken[c.existing] {
+			continue
+		}
+
+		matches.pending[c.scraped] = true
+		matches.held[c.existing] = true
+		result.queueMatch(&scraped[c.scraped], &existing[c.existing], c.confidence)
+	}
+
+	return matches
+}
@@ -0,0 +1,144 @@
+package bg_test
+
+import (
+	"context"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const renamedJson = `[
+	{"chain": "Kalamaja", "name": "Kalamaja Apteek", "address": "Kotzebue tn 9", "city": "Tallinn", "county": "Harjumaa", "postalCode": "10412", "lat": 59.442558, "lng": 24.737238}
+]`
+
+func newIndependentRepoMock(ctrl *gomock.Controller, existing []entity.Pharmacy) *mock.MockPharmacyRepository {
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return(existing, nil)
+
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Kalamaja")).
+		Return(queryMock)
+	return repoMock
+}
+
+func TestIdentity_Renamed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	existing := []entity.Pharmacy{
+		{ID: 1, PharmacyID: bg.IndependentPharmacyID("Kotzebue Apteek"), Chain: "Kalamaja", Name: "Kotzebue Apteek", Address: "Kotzebue tänav 9", City: "Tallinn", County: "Harjumaa", PostalCode: "10412", Latitude: 59.44256, Longitude: 24.73724},
+	}
+
+	repoMock := newIndependentRepoMock(ctrl, existing)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Equal(t, 1, len(pharmacies))
+			assert.Equal(t, int64(1), pharmacies[0].ID)
+			assert.Equal(t, bg.IndependentPharmacyID("Kalamaja Apteek"), pharmacies[0].PharmacyID)
+			assert.Equal(t, "Kalamaja Apteek", pharmacies[0].Name)
+			return nil
+		})
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(renamedJson), repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Inserted)
+	assert.Equal(t, 1, result.Updated)
+	assert.Empty(t, result.Matches)
+
+	fields := make([]string, 0)
+	for _, change := range result.Changes[0].Changes {
+		fields = append(fields, change.Field)
+	}
+	assert.Contains(t, fields, "pharmacyId")
+	assert.Contains(t, fields, "name")
+}
+
+func TestIdentity_Ambiguous(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	existing := []entity.Pharmacy{
+		{ID: 1, PharmacyID: bg.IndependentPharmacyID("Kotzebue Apteek"), Chain: "Kalamaja", Name: "Kotzebue Apteek", Address: "Kotzebue tänav 9", City: "Tallinn", Latitude: 59.44256, Longitude: 24.73724},
+		{ID: 2, PharmacyID: bg.IndependentPharmacyID("Kalamaja Ravimid"), Chain: "Kalamaja", Name: "Kalamaja Ravimid", Address: "Kotzebue tänav 9", City: "Tallinn", Latitude: 59.44255, Longitude: 24.73723},
+	}
+
+	// neither of the candidates is modified until the match is resolved
+	repoMock := newIndependentRepoMock(ctrl, existing)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Empty(t, pharmacies)
+			return nil
+		}).
+		AnyTimes()
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(renamedJson), repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Inserted)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 2, len(result.Matches))
+	for _, match := range result.Matches {
+		assert.Equal(t, bg.IndependentPharmacyID("Kalamaja Apteek"), match.PharmacyID)
+		assert.Equal(t, "Kalamaja Apteek", match.Pharmacy.Name)
+		assert.Equal(t, string(entity.PHARMACY_MATCH_PENDING), match.Status)
+	}
+}
+
+func TestIdentity_Unrelated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	existing := []entity.Pharmacy{
+		{ID: 1, PharmacyID: bg.IndependentPharmacyID("Pelgulinna Apteek"), Chain: "Kalamaja", Name: "Pelgulinna Apteek", Address: "Sõle 51", City: "Tallinn", Latitude: 59.4445, Longitude: 24.7083},
+	}
+
+	repoMock := newIndependentRepoMock(ctrl, existing)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Equal(t, 1, len(pharmacies))
+			assert.Equal(t, int64(0), pharmacies[0].ID)
+			return nil
+		})
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(renamedJson), repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Empty(t, result.Matches)
+}
+
+func TestIdentity_Rejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	existing := []entity.Pharmacy{
+		{ID: 1, PharmacyID: bg.IndependentPharmacyID("Kotzebue Apteek"), Chain: "Kalamaja", Name: "Kotzebue Apteek", Address: "Kotzebue tänav 9", City: "Tallinn", Latitude: 59.44256, Longitude: 24.73724},
+		{ID: 2, PharmacyID: bg.IndependentPharmacyID("Kalamaja Ravimid"), Chain: "Kalamaja", Name: "Kalamaja Ravimid", Address: "Kotzebue tänav 9", City: "Tallinn", Latitude: 59.44255, Longitude: 24.73723},
+	}
+
+	// both of the matches were rejected by an admin, thus the pharmacy is a new one
+	for i := range existing {
+		existing[i].RejectedMatches = []int64{bg.IndependentPharmacyID("Kalamaja Apteek")}
+	}
+
+	repoMock := newIndependentRepoMock(ctrl, existing)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Equal(t, 1, len(pharmacies))
+			assert.Equal(t, int64(0), pharmacies[0].ID)
+			return nil
+		})
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(renamedJson), repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Empty(t, result.Matches)
+}
@@ -0,0 +1,79 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"pharmafinder/address"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/phone"
+	"pharmafinder/types"
+	"time"
+)
+
+// Returns the identity of an independent pharmacy within its chain. The identity
+// is derived from the name the pharmacy was first created with and never changes
+func IndependentPharmacyID(name string) int64 {
+	return int64(crc64.Checksum([]byte(name), crc64Table))
+}
+
+// Imports independent pharmacies from a JSON array of pharmacies (e.g. the embedded
+// db/independent-pharmacies.json seed). Pharmacies are upserted by their identity,
+// existing pharmacies missing from the file are left untouched. All pharmacies must
+// belong to chains which are not populated by any scraper
+func ImportIndependentPharmacies(ctx context.Context, r io.Reader, repo db.PharmacyRepository, chains db.ChainRepository) (ScrapeResult, error) {
+	var result ScrapeResult
+
+	var pharmacies []entity.Pharmacy
+	if err := json.NewDecoder(r).Decode(&pharmacies); err != nil {
+		return result, fmt.Errorf("failed to unmarshal independent pharmacy json: %v", err)
+	}
+
+	var existing []entity.Pharmacy
+	seenChains := make(map[string]bool)
+	for i := range pharmacies {
+		pharmacies[i].ID = 0
+		pharmacies[i].PharmacyID = IndependentPharmacyID(pharmacies[i].Name)
+		pharmacies[i].ModTime = types.Time(time.Now().UTC())
+		pharmacies[i].ClosedAt = nil
+
+		// numbers are normalized and classified anew, so that the file could list them in any format
+		numbers, err := phone.Parse(pharmacies[i].PhoneNumbers.String())
+		if err != nil {
+			return result, fmt.Errorf("invalid phone numbers of pharmacy %s: %v", pharmacies[i].Name, err)
+		}
+		pharmacies[i].PhoneNumbers = numbers
+
+		// addresses are normalized the same way as the addresses of scraped pharmacies
+		addr := address.Parse(pharmacies[i].Address, pharmacies[i].City, pharmacies[i].County)
+		pharmacies[i].Address = addr.StreetAddress()
+		pharmacies[i].City = addr.City()
+		pharmacies[i].County = addr.County
+
+		if seenChains[pharmacies[i].Chain] {
+			continue
+		}
+		seenChains[pharmacies[i].Chain] = true
+
+		chain, err := chains.FindChainByName(ctx, pharmacies[i].Chain).Query()
+		if err != nil {
+			return result, fmt.Errorf("failed to query chain %s: %v", pharmacies[i].Chain, err)
+		} else if chain == nil {
+			return result, fmt.Errorf("unknown chain '%s' of pharmacy %s", pharmacies[i].Chain, pharmacies[i].Name)
+		} else if chain.ScraperKind != nil {
+			return result, fmt.Errorf("pharmacies of chain %s are populated by %s scraper", chain.Name, *chain.ScraperKind)
+		}
+
+		resps, err := repo.FindPharmaciesByChain(ctx, chain.Name).QueryAll()
+		if err != nil {
+			return result, fmt.Errorf("failed to query for existing pharmacies: %v", err)
+		}
+		existing = append(existing, resps...)
+	}
+
+	err := upsertPharmacies(ctx, repo, &result, existing, pharmacies)
+	return result, err
+}
@@ -0,0 +1,107 @@
+package bg_test
+
+import (
+	"context"
+	"os"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const independentJson = `[
+	{"chain": "Kalamaja", "name": "Kalamaja Apteek", "address": "Kotzebue 9", "city": "Tallinn", "county": "Harjumaa", "postalCode": "10412", "lat": 59.442558, "lng": 24.737238},
+	{"chain": "Kalamaja", "name": "Pelgulinna Apteek", "address": "Sõle 51, Tallinn", "city": "", "county": "", "postalCode": "10313", "lat": 59.4445, "lng": 24.7083}
+]`
+
+func TestImportIndependentPharmacies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	existing := []entity.Pharmacy{
+		{ID: 1, PharmacyID: bg.IndependentPharmacyID("Kalamaja Apteek"), Chain: "Kalamaja", Name: "Kalamaja Apteek", Address: "Kotzebue 7", City: "Tallinn", County: "Harjumaa", PostalCode: "10412", Latitude: 59.44, Longitude: 24.73},
+		{ID: 2, PharmacyID: bg.IndependentPharmacyID("Created By Admin"), Chain: "Kalamaja", Name: "Created By Admin"},
+	}
+
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return(existing, nil)
+
+	// pharmacies missing from the file are not closed
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Kalamaja")).
+		Return(queryMock)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Equal(t, 2, len(pharmacies))
+			assert.Equal(t, int64(1), pharmacies[0].ID)
+			assert.Equal(t, "Kotzebue 9", pharmacies[0].Address)
+			assert.Equal(t, int64(0), pharmacies[1].ID)
+			assert.Equal(t, bg.IndependentPharmacyID("Pelgulinna Apteek"), pharmacies[1].PharmacyID)
+
+			// addresses are normalized like the addresses of scraped pharmacies
+			assert.Equal(t, "Sõle 51", pharmacies[1].Address)
+			assert.Equal(t, "Tallinn", pharmacies[1].City)
+			assert.Equal(t, "Harjumaa", pharmacies[1].County)
+			return nil
+		})
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(independentJson), repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 0, result.Closed)
+}
+
+func TestImportIndependentPharmacies_Seed(t *testing.T) {
+	seed, err := os.Open("../db/independent-pharmacies.json")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer seed.Close()
+
+	ctrl := gomock.NewController(t)
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return([]entity.Pharmacy{}, nil)
+
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Kalamaja")).
+		Return(queryMock)
+	repoMock.EXPECT().
+		StoreAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy) error {
+			assert.Equal(t, 1, len(pharmacies))
+			assert.Equal(t, "Kalamaja Apteek", pharmacies[0].Name)
+			assert.Equal(t, weeklyHours("09:00-19:00", "09:00-19:00", "09:00-19:00", "09:00-19:00", "09:00-19:00", "10:00-15:00"), pharmacies[0].OpeningHours)
+
+			// 2026-10-19 is a Monday
+			assert.True(t, pharmacies[0].OpeningHours.IsOpenAt(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)))
+			return nil
+		})
+
+	result, err := bg.ImportIndependentPharmacies(context.Background(), seed, repoMock, bg.StaticChainRepository{Chains: testChains})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+}
+
+func TestImportIndependentPharmacies_ScrapedChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+
+	_, err := bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(`[{"chain": "Benu", "name": "Benu Apteek"}]`), repoMock, bg.StaticChainRepository{Chains: testChains})
+	assert.Error(t, err)
+
+	_, err = bg.ImportIndependentPharmacies(context.Background(), strings.NewReader(`[{"chain": "Unknown", "name": "Apteek"}]`), repoMock, bg.StaticChainRepository{Chains: testChains})
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"time"
+)
+
+var (
+	ErrMergeIntoItself = errors.New("pharmacy can't be merged into itself")
+	ErrMergeNotFound   = errors.New("pharmacy not found")
+)
+
+// Merges the source pharmacy into the target pharmacy, e.g. when the same pharmacy
+// has been stored twice after an acquisition by another chain. Reviews and revisions of the
+// source are moved to the target, the source is closed for good and its ID redirects to the target.
+// Returns db.ErrScraperRunning if the scraper of either chain is running
+func MergePharmacies(ctx context.Context, repo db.PharmacyRepository, merges db.PharmacyMergeRepository, sourceID int64, targetID int64, mergedBy string) (*entity.PharmacyMerge, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeIntoItself
+	}
+
+	for _, id := range []int64{sourceID, targetID} {
+		pharmacy, err := repo.FindPharmacyByID(id).Query()
+		if err != nil {
+			return nil, err
+		} else if pharmacy == nil {
+			return nil, fmt.Errorf("%w: %d", ErrMergeNotFound, id)
+		}
+	}
+
+	// the merge is recorded into the revision history of the target
+	ctx = db.WithRevisionOrigin(ctx, db.RevisionOrigin{Source: entity.REVISION_SOURCE_ADMIN, Name: mergedBy})
+	merge, err := merges.Merge(ctx, sourceID, targetID, mergedBy, types.Time(time.Now().UTC()))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := utils.GetLogger("BG")
+	logger.Info().Msgf("Pharmacy %d (%s) was merged into %d by %s, %d reviews were moved",
+		merge.SourceID, merge.Source.Name, merge.TargetID, merge.MergedBy, merge.Reviews)
+	return merge, nil
+}
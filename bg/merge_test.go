@@ -0,0 +1,97 @@
+package bg_test
+
+import (
+	"context"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func newPharmacyLookupMock(ctrl *gomock.Controller, pharmacies ...entity.Pharmacy) *mock.MockPharmacyRepository {
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmacyByID(gomock.Any()).
+		DoAndReturn(func(id int64) db.Query[entity.Pharmacy] {
+			for _, pharmacy := range pharmacies {
+				if pharmacy.ID == id {
+					return &db.StaticQuery[entity.Pharmacy]{Values: []entity.Pharmacy{pharmacy}}
+				}
+			}
+			return &db.StaticQuery[entity.Pharmacy]{}
+		}).
+		AnyTimes()
+	return repoMock
+}
+
+func TestMergePharmacies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := entity.Pharmacy{ID: 1, Chain: "Benu", Name: "Benu Apteek Kotzebue"}
+	target := entity.Pharmacy{ID: 2, Chain: "Apotheka", Name: "Apotheka Kotzebue"}
+
+	mergeMock := mock.NewMockPharmacyMergeRepository(ctrl)
+	mergeMock.EXPECT().
+		Merge(gomock.Any(), gomock.Eq(int64(1)), gomock.Eq(int64(2)), gomock.Eq("admin"), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ int64, _ int64, _ string, _ types.Time) (*entity.PharmacyMerge, error) {
+			// the merge revision of the target is attributed to the admin
+			assert.Equal(t, db.RevisionOrigin{Source: entity.REVISION_SOURCE_ADMIN, Name: "admin"}, db.RevisionOriginFromContext(ctx))
+			return &entity.PharmacyMerge{ID: 1, SourceID: 1, TargetID: 2, Source: entity.PharmacySnapshot(source), Reviews: 3, MergedBy: "admin"}, nil
+		})
+
+	merge, err := bg.MergePharmacies(context.Background(), newPharmacyLookupMock(ctrl, source, target), mergeMock, 1, 2, "admin")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, merge.Reviews)
+	assert.Equal(t, "Benu Apteek Kotzebue", merge.Source.Name)
+}
+
+func TestMergePharmacies_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := entity.Pharmacy{ID: 1, Chain: "Benu", Name: "Benu Apteek Kotzebue"}
+
+	// nothing is merged
+	mergeMock := mock.NewMockPharmacyMergeRepository(ctrl)
+	repoMock := newPharmacyLookupMock(ctrl, source)
+
+	_, err := bg.MergePharmacies(context.Background(), repoMock, mergeMock, 1, 1, "admin")
+	assert.ErrorIs(t, err, bg.ErrMergeIntoItself)
+
+	_, err = bg.MergePharmacies(context.Background(), repoMock, mergeMock, 1, 2, "admin")
+	assert.ErrorIs(t, err, bg.ErrMergeNotFound)
+
+	_, err = bg.MergePharmacies(context.Background(), repoMock, mergeMock, 3, 1, "admin")
+	assert.ErrorIs(t, err, bg.ErrMergeNotFound)
+}
+
+func TestMergedPharmacy_StaysClosed(t *testing.T) {
+	client, err := utils.NewCassetteHttpClient(utils.CassettePath("_cassettes", "apotheka"), utils.CASSETTE_REPLAY, nil)
+	assert.NoError(t, err)
+
+	// the merged pharmacy is still listed by its chain
+	merged := apothekaPharmacies[1]
+	merged.ID = 10
+	merged.ClosedAt = utils.Ptr(types.Time(time.Now().UTC()))
+	merged.MergedInto = utils.Ptr(int64(20))
+
+	repo := &existingRepository{existing: []entity.Pharmacy{merged}}
+	resolver := bg.NewPostalCodeResolver(bg.EmptyAddressRepository{}, bg.EmptyPostalCodeCacheRepository{}, client, onlineLookup)
+	scraper := bg.ProvideApothekaScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, nil)
+
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, 0, result.Updated)
+	if assert.Len(t, repo.stored, 1) {
+		assert.Equal(t, int64(5), repo.stored[0].PharmacyID)
+	}
+	assert.Empty(t, repo.closed)
+}
@@ -0,0 +1,156 @@
+package bg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returned when a page contains no Next.js flight data at all
+var ErrNoFlightData = errors.New("no Next.js flight data found")
+
+// Matches chunks of the React Server Components payload, which Next.js streams into
+// the page as `self.__next_f.push([1, "..."])` calls. Other chunk types (bootstrap,
+// form state and binary data) carry no rendered data and are ignored
+var flightChunkRegex = regexp.MustCompile(`self\.__next_f\.push\(\[\s*1\s*,\s*("(?:[^"\\]|\\.)*")\s*\]\)`)
+
+// Single row of the React Server Components payload, e.g. `1f:["$","div",null,{}]`
+type flightRow struct {
+	// Hexadecimal ID of the row, which other rows refer to as "$<id>"
+	ID string
+	// Row type tag, e.g. 'I' for module imports, 'T' for text or 0 for JSON models
+	Tag byte
+	// Raw payload of the row
+	Data string
+}
+
+// Reassembles the React Server Components payload from all flight
+// data chunks of a Next.js page in the order they were pushed
+func extractNextFlightStream(html string) (string, error) {
+	matches := flightChunkRegex.FindAllStringSubmatch(html, -1)
+	if len(matches) == 0 {
+		return "", ErrNoFlightData
+	}
+
+	var stream strings.Builder
+	for i, match := range matches {
+		var chunk string
+		if err := json.Unmarshal([]byte(match[1]), &chunk); err != nil {
+			return "", fmt.Errorf("malformed Next.js flight data chunk %d: %v", i, err)
+		}
+		stream.WriteString(chunk)
+	}
+
+	return stream.String(), nil
+}
+
+// Splits the React Server Components payload into rows. Rows are separated by
+// newlines, except for text rows, which are prefixed with their length in bytes
+func parseNextFlightRows(stream string) ([]flightRow, error) {
+	rows := make([]flightRow, 0)
+	for pos := 0; pos < len(stream); {
+		if stream[pos] == '\n' {
+			pos++
+			continue
+		}
+
+		colon := strings.IndexByte(stream[pos:], ':')
+		if colon < 0 {
+			return nil, fmt.Errorf("malformed Next.js flight row at offset %d: missing row ID", pos)
+		}
+
+		row := flightRow{ID: stream[pos : pos+colon]}
+		pos += colon + 1
+		if pos < len(stream) && stream[pos] >= 'A' && stream[pos] <= 'Z' {
+			row.Tag = stream[pos]
+			pos++
+		}
+
+		if row.Tag == 'T' {
+			comma := strings.IndexByte(stream[pos:], ',')
+			if comma < 0 {
+				return nil, fmt.Errorf("malformed Next.js flight text row %s: missing length", row.ID)
+			}
+
+			length, err := strconv.ParseInt(stream[pos:pos+comma], 16, 64)
+			if err != nil || pos+comma+1+int(length) > len(stream) {
+				return nil, fmt.Errorf("malformed Next.js flight text row %s: invalid length '%s'", row.ID, stream[pos:pos+comma])
+			}
+
+			pos += comma + 1
+			row.Data = stream[pos : pos+int(length)]
+			pos += int(length)
+		} else {
+			end := strings.IndexByte(stream[pos:], '\n')
+			if end < 0 {
+				end = len(stream) - pos
+			}
+
+			row.Data = stream[pos : pos+end]
+			pos += end
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Walks the decoded model rows of a Next.js page and returns all objects
+// accepted by the matcher. Objects nested in accepted objects are not visited
+func findNextFlightObjects(html string, matcher func(map[string]interface{}) bool) ([]map[string]interface{}, error) {
+	stream, err := extractNextFlightStream(html)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseNextFlightRows(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var walk func(node interface{}, found []map[string]interface{}) []map[string]interface{}
+	walk = func(node interface{}, found []map[string]interface{}) []map[string]interface{} {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if matcher(v) {
+				return append(found, v)
+			}
+
+			// keys are visited in a stable order to keep the order of found objects deterministic
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				found = walk(v[key], found)
+			}
+		case []interface{}:
+			for _, child := range v {
+				found = walk(child, found)
+			}
+		}
+		return found
+	}
+
+	found := make([]map[string]interface{}, 0)
+	for _, row := range rows {
+		// only model rows contain rendered data
+		if row.Tag != 0 {
+			continue
+		}
+
+		var model interface{}
+		if err := json.Unmarshal([]byte(row.Data), &model); err != nil {
+			return nil, fmt.Errorf("malformed Next.js flight row %s: %v", row.ID, err)
+		}
+		found = walk(model, found)
+	}
+
+	return found, nil
+}
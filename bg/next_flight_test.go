@@ -0,0 +1,50 @@
+package bg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNextFlightRows(t *testing.T) {
+	// text rows may contain newlines and are measured in bytes
+	stream := "1:\"$Sreact.fragment\"\n2:I[9766,[],\"\"]\n:HL[\"/a.css\",\"style\"]\n3:T8,ä\nb:[1]\n4:[\"$\",\"div\",null,{}]"
+	rows, err := parseNextFlightRows(stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []flightRow{
+		{ID: "1", Data: "\"$Sreact.fragment\""},
+		{ID: "2", Tag: 'I', Data: "[9766,[],\"\"]"},
+		{ID: "", Tag: 'H', Data: "L[\"/a.css\",\"style\"]"},
+		{ID: "3", Tag: 'T', Data: "ä\nb:[1]"},
+		{ID: "4", Data: "[\"$\",\"div\",null,{}]"},
+	}, rows)
+}
+
+func TestParseNextFlightRows_Malformed(t *testing.T) {
+	_, err := parseNextFlightRows("1:T100,short")
+	assert.ErrorContains(t, err, "invalid length")
+
+	_, err = parseNextFlightRows("no row id")
+	assert.ErrorContains(t, err, "missing row ID")
+}
+
+func TestFindNextFlightObjects(t *testing.T) {
+	html := `<script>self.__next_f.push([1,"0:{\"a\":[{\"name\":\"x\"},{\"b\":{\"name\":\"y\"}}]}\n1:T3,{}\n"])</script>` +
+		`<script>self.__next_f.push([1, "2:[{\"name\":\"z\",\"nested\":{\"name\":\"w\"}}]"])</script>`
+
+	objects, err := findNextFlightObjects(html, func(object map[string]interface{}) bool {
+		_, ok := object["name"]
+		return ok
+	})
+
+	assert.NoError(t, err)
+	names := make([]interface{}, 0)
+	for _, object := range objects {
+		names = append(names, object["name"])
+	}
+	assert.Equal(t, []interface{}{"x", "y", "z"}, names)
+
+	_, err = findNextFlightObjects("<html></html>", func(map[string]interface{}) bool { return true })
+	assert.ErrorIs(t, err, ErrNoFlightData)
+}
@@ -1,6 +1,7 @@
 package bg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,8 +9,6 @@ import (
 	"net/url"
 	"pharmafinder/utils"
 	"strings"
-
-	"github.com/rs/zerolog"
 )
 
 const OMNIVA_ZIP_CODE_ENDPOINT = "https://www.omniva.ee/wp-json/custom/v1/omniva-zip-search?search=%s"
@@ -21,44 +20,42 @@ type zipcodeResponse struct {
 	} `json:"addresses"`
 }
 
-func fetchOmnivaZipCode(address string, client utils.HttpClient, logger *zerolog.Logger) string {
+// Looks up the postal code of given address from Omniva zip code API.
+// Empty postal code with nil error means that Omniva found no matching addresses
+func fetchOmnivaZipCode(ctx context.Context, address string, client utils.HttpClient) (string, error) {
 	escapedAddress := strings.ReplaceAll(url.QueryEscape(address), "%20", "+")
 	url := fmt.Sprintf(OMNIVA_ZIP_CODE_ENDPOINT, escapedAddress)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		logger.Error().Msg("Failed to create a new request object for Omniva zip code API")
-		return ""
+		return "", fmt.Errorf("failed to create a new request object for Omniva zip code API: %v", err)
 	}
 
 	req.Header.Set("User-Agent", USER_AGENT)
 	resp, err := client.Do(req)
 	if err != nil {
-		logger.Error().Msgf("Failed to make a request to %s: %v", url, err)
-		return ""
+		return "", fmt.Errorf("failed to make a request to %s: %v", url, err)
 	}
+	defer resp.Body.Close()
 
 	// make sure that the server responded with status code 200
 	if resp.StatusCode != 200 {
-		logger.Error().Msgf("Omniva zipcode API responded with non-200 status code %d", resp.StatusCode)
-		return ""
+		return "", fmt.Errorf("omniva zipcode API responded with non-200 status code %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		logger.Error().Msg("Failed to read response body from Omniva zipcode API response")
-		return ""
+		return "", fmt.Errorf("failed to read response body from Omniva zipcode API response: %v", err)
 	}
 
 	var zipcode zipcodeResponse
 	err = json.Unmarshal(body, &zipcode)
 	if err != nil {
-		logger.Error().Msgf("Failed to unmarshal Omniva zipcode API response: %v", err)
-		return ""
+		return "", fmt.Errorf("failed to unmarshal Omniva zipcode API response: %v", err)
 	}
 
 	if len(zipcode.Addresses) > 0 {
-		return zipcode.Addresses[0].ZipCode
+		return zipcode.Addresses[0].ZipCode, nil
 	}
 
-	return ""
+	return "", nil
 }
@@ -0,0 +1,262 @@
+package bg
+
+import (
+	"encoding/json"
+	"fmt"
+	"pharmafinder/db/entity"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var weekdays map[string]time.Weekday = map[string]time.Weekday{
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"sunday":    time.Sunday,
+}
+
+// Sorts weekly opening hours so that the week starts on Monday
+func sortWeekly(weekly []entity.WeeklyOpeningHours) {
+	slices.SortStableFunc(weekly, func(a, b entity.WeeklyOpeningHours) int {
+		return (int(a.Day)+6)%7 - (int(b.Day)+6)%7
+	})
+}
+
+// Parses weekday names such as "Monday" or "https://schema.org/Monday"
+func parseWeekday(value string) (time.Weekday, bool) {
+	parts := strings.Split(value, "/")
+	day, ok := weekdays[strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))]
+	return day, ok
+}
+
+// Matches clock values such as "9", "9.00", "09:00" or "10:00:00"
+var clockRegex = regexp.MustCompile(`^(\d{1,2})(?:[.:](\d{2}))?(?::\d{2})?$`)
+
+// Normalizes clock values such as "9", "9.00", "09:00" or "10:00:00"
+// into "15:04" format
+func normalizeClock(value string) (string, bool) {
+	groups := clockRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if len(groups) != 3 {
+		return "", false
+	}
+
+	var hours, minutes int
+	fmt.Sscanf(groups[1], "%d", &hours)
+	if groups[2] != "" {
+		fmt.Sscanf(groups[2], "%d", &minutes)
+	}
+
+	if hours > 24 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return "", false
+	}
+
+	return fmt.Sprintf("%02d:%02d", hours, minutes), true
+}
+
+// Parses human readable time ranges such as "10:00 - 18:00" or "9.00-14.00"
+// into "15:04" formatted opening and closing times.
+//
+// Returns false if the range denotes a closed day (e.g. "Suletud" or "X")
+// or if it cannot be parsed
+func parseTimeRange(value string) (string, string, bool) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	opens, ok := normalizeClock(parts[0])
+	if !ok {
+		return "", "", false
+	}
+
+	closes, ok := normalizeClock(parts[1])
+	if !ok {
+		return "", "", false
+	}
+
+	return opens, closes, true
+}
+
+type businessHoursSpecification struct {
+	DayOfWeek    json.RawMessage `json:"dayOfWeek"`
+	Opens        string          `json:"opens"`
+	Closes       string          `json:"closes"`
+	ValidFrom    string          `json:"validFrom"`
+	ValidThrough string          `json:"validThrough"`
+}
+
+// Maximum amount of days a date-specific exception may span,
+// used to avoid expanding nonsensical date ranges
+const MAX_EXCEPTION_DAYS = 366
+
+// Expands a date range into single day exceptions using provided
+// callback for determining the opening hours of each date
+func expandExceptions(from string, to string, hoursOn func(date time.Time) entity.OpeningHoursException) ([]entity.OpeningHoursException, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date '%s'", from)
+	}
+
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date '%s'", to)
+	}
+
+	if end.Before(start) || end.Sub(start) > MAX_EXCEPTION_DAYS*24*time.Hour {
+		return nil, fmt.Errorf("invalid date range %s - %s", from, to)
+	}
+
+	exceptions := make([]entity.OpeningHoursException, 0)
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		exception := hoursOn(date)
+		exception.Date = date.Format("2006-01-02")
+		exceptions = append(exceptions, exception)
+	}
+
+	return exceptions, nil
+}
+
+// Parses schema.org BusinessHoursSpecification list, which is used
+// by Apotheka and Südameapteek shop API
+func parseBusinessHours(data string) (entity.OpeningHours, error) {
+	var hours entity.OpeningHours
+	if strings.TrimSpace(data) == "" {
+		return hours, nil
+	}
+
+	var specs []businessHoursSpecification
+	if err := json.Unmarshal([]byte(data), &specs); err != nil {
+		return hours, fmt.Errorf("failed to unmarshal business hours: %v", err)
+	}
+
+	for _, spec := range specs {
+		var days []string
+		if err := json.Unmarshal(spec.DayOfWeek, &days); err != nil {
+			var day string
+			if err := json.Unmarshal(spec.DayOfWeek, &day); err != nil {
+				return hours, fmt.Errorf("invalid dayOfWeek value %s", string(spec.DayOfWeek))
+			}
+			days = []string{day}
+		}
+
+		opens, ok1 := normalizeClock(spec.Opens)
+		closes, ok2 := normalizeClock(spec.Closes)
+		if !ok1 || !ok2 {
+			return hours, fmt.Errorf("invalid business hours %s - %s", spec.Opens, spec.Closes)
+		}
+
+		for _, dayStr := range days {
+			if day, ok := parseWeekday(dayStr); ok {
+				hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{Day: day, Opens: opens, Closes: closes})
+			}
+		}
+	}
+
+	sortWeekly(hours.Weekly)
+	return hours, nil
+}
+
+// Parses schema.org SpecialOpeningHoursSpecification list into date-specific exceptions.
+// Following schema.org conventions, "00:00" opening and closing times denote closed days
+func parseSpecialOpeningHours(data json.RawMessage) ([]entity.OpeningHoursException, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var specs []businessHoursSpecification
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal holiday opening hours: %v", err)
+	}
+
+	var exceptions []entity.OpeningHoursException
+	for _, spec := range specs {
+		validThrough := spec.ValidThrough
+		if validThrough == "" {
+			validThrough = spec.ValidFrom
+		}
+
+		opens, ok1 := normalizeClock(spec.Opens)
+		closes, ok2 := normalizeClock(spec.Closes)
+		closed := !ok1 || !ok2 || (opens == "00:00" && closes == "00:00")
+		expanded, err := expandExceptions(spec.ValidFrom, validThrough, func(_ time.Time) entity.OpeningHoursException {
+			if closed {
+				return entity.OpeningHoursException{Closed: true}
+			}
+			return entity.OpeningHoursException{Opens: opens, Closes: closes}
+		})
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, expanded...)
+	}
+
+	return exceptions, nil
+}
+
+// Parses BENU work hours object, which maps English weekday names to time ranges.
+//
+// Temporary schedules contain additional ValidFrom and ValidTo keys, in which case
+// the schedule is expanded into date-specific exceptions as well. The schedule is kept
+// as weekly opening hours too, as it is the only schedule BENU publishes
+func parseBenuWorkHours(workHours map[string]*string) (entity.OpeningHours, error) {
+	var hours entity.OpeningHours
+	weekly := make(map[time.Weekday][2]string)
+	for key, value := range workHours {
+		day, ok := parseWeekday(key)
+		if !ok || value == nil {
+			continue
+		}
+
+		if opens, closes, ok := parseTimeRange(*value); ok {
+			weekly[day] = [2]string{opens, closes}
+		}
+	}
+
+	validFrom, hasFrom := workHours["ValidFrom"]
+	validTo, hasTo := workHours["ValidTo"]
+	if hasFrom && hasTo && validFrom != nil && validTo != nil {
+		exceptions, err := expandExceptions(*validFrom, *validTo, func(date time.Time) entity.OpeningHoursException {
+			if r, ok := weekly[date.Weekday()]; ok {
+				return entity.OpeningHoursException{Opens: r[0], Closes: r[1]}
+			}
+			return entity.OpeningHoursException{Closed: true}
+		})
+		if err != nil {
+			return hours, err
+		}
+		hours.Exceptions = exceptions
+	}
+
+	for day, r := range weekly {
+		hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{Day: day, Opens: r[0], Closes: r[1]})
+	}
+
+	sortWeekly(hours.Weekly)
+	return hours, nil
+}
+
+// Creates weekly opening hours from separate working day, Saturday and Sunday
+// time ranges, which is the format used by Euroapteek
+func parseWorkdayWeekendHours(workdays string, saturday string, sunday string) entity.OpeningHours {
+	var hours entity.OpeningHours
+	if opens, closes, ok := parseTimeRange(workdays); ok {
+		for day := time.Monday; day <= time.Friday; day++ {
+			hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{Day: day, Opens: opens, Closes: closes})
+		}
+	}
+
+	if opens, closes, ok := parseTimeRange(saturday); ok {
+		hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{Day: time.Saturday, Opens: opens, Closes: closes})
+	}
+
+	if opens, closes, ok := parseTimeRange(sunday); ok {
+		hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{Day: time.Sunday, Opens: opens, Closes: closes})
+	}
+
+	return hours
+}
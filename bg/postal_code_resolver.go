@@ -0,0 +1,172 @@
+package bg
+
+import (
+	"context"
+	"pharmafinder/address"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Default time for which resolved postal codes are cached
+const DEFAULT_POSTAL_CODE_TTL = 90 * 24 * time.Hour
+
+// Default time for which lookups without results are cached
+const DEFAULT_POSTAL_CODE_NEGATIVE_TTL = 7 * 24 * time.Hour
+
+type PostalCodeResolverConfig struct {
+	// Whether addresses missing from the offline dataset
+	// are looked up from Omniva zip code API
+	OnlineLookup bool
+	// Time for which resolved postal codes of online lookups are cached
+	TTL time.Duration
+	// Time for which online lookups without results are cached
+	NegativeTTL time.Duration
+}
+
+// PostalCodeResolver resolves postal codes of addresses using the
+// offline address dataset and optionally Omniva zip code API
+// with a persistent cache in front of it
+type PostalCodeResolver struct {
+	addresses db.AddressRepository
+	cache     db.PostalCodeCacheRepository
+	client    utils.HttpClient
+	config    PostalCodeResolverConfig
+	logger    zerolog.Logger
+}
+
+// Online lookups are enabled with POSTAL_CODE_ONLINE_LOOKUP environment variable.
+// Cache TTLs are read from POSTAL_CODE_TTL and POSTAL_CODE_NEGATIVE_TTL
+// environment variables in Go duration format, e.g. "720h"
+func ProvidePostalCodeResolver(addresses db.AddressRepository, cache db.PostalCodeCacheRepository, client utils.HttpClient) *PostalCodeResolver {
+	logger := utils.GetLogger("BG")
+	online, err := strconv.ParseBool(utils.Getenv("POSTAL_CODE_ONLINE_LOOKUP", "false"))
+	if err != nil {
+		logger.Warn().Msg("Invalid POSTAL_CODE_ONLINE_LOOKUP value, online postal code lookups are disabled")
+	}
+
+	ttl, err := time.ParseDuration(utils.Getenv("POSTAL_CODE_TTL", DEFAULT_POSTAL_CODE_TTL.String()))
+	if err != nil || ttl <= 0 {
+		logger.Warn().Msgf("Invalid POSTAL_CODE_TTL value, falling back to %s", DEFAULT_POSTAL_CODE_TTL)
+		ttl = DEFAULT_POSTAL_CODE_TTL
+	}
+
+	negativeTTL, err := time.ParseDuration(utils.Getenv("POSTAL_CODE_NEGATIVE_TTL", DEFAULT_POSTAL_CODE_NEGATIVE_TTL.String()))
+	if err != nil || negativeTTL <= 0 {
+		logger.Warn().Msgf("Invalid POSTAL_CODE_NEGATIVE_TTL value, falling back to %s", DEFAULT_POSTAL_CODE_NEGATIVE_TTL)
+		negativeTTL = DEFAULT_POSTAL_CODE_NEGATIVE_TTL
+	}
+
+	return NewPostalCodeResolver(addresses, cache, client, PostalCodeResolverConfig{
+		OnlineLookup: online,
+		TTL:          ttl,
+		NegativeTTL:  negativeTTL,
+	})
+}
+
+func NewPostalCodeResolver(addresses db.AddressRepository, cache db.PostalCodeCacheRepository, client utils.HttpClient, config PostalCodeResolverConfig) *PostalCodeResolver {
+	return &PostalCodeResolver{
+		addresses: addresses,
+		cache:     cache,
+		client:    client,
+		config:    config,
+		logger:    utils.GetLogger("BG"),
+	}
+}
+
+// Normalizes the address so that trivial differences in
+// letter case and whitespace would map to the same cache entry
+func normalizeAddress(address string) string {
+	parts := strings.Split(strings.ToLower(address), ",")
+	for i := range parts {
+		parts[i] = strings.Join(strings.Fields(parts[i]), " ")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Resolves the postal code of given "street address, settlement, ..." formatted
+// address. Empty postal code with nil error means that the address has no known
+// postal code, while non-nil error means that the online lookup itself failed
+func (resolver *PostalCodeResolver) Resolve(ctx context.Context, address string) (string, error) {
+	if postalCode, ok := resolver.resolveOffline(ctx, address); ok {
+		return postalCode, nil
+	}
+
+	if !resolver.config.OnlineLookup {
+		return "", nil
+	}
+
+	return resolver.resolveOnline(ctx, address)
+}
+
+// Looks up the postal code from the offline address dataset. The settlement
+// is only used to disambiguate between equal street addresses in different settlements
+func (resolver *PostalCodeResolver) resolveOffline(ctx context.Context, query string) (string, bool) {
+	parts := strings.Split(query, ",")
+	addressKey := address.StreetKey(parts[0])
+	settlementKey := ""
+	if len(parts) > 1 {
+		settlementKey = address.SettlementKey(parts[1])
+	}
+
+	candidates, err := resolver.addresses.FindAddressesByKey(ctx, addressKey, settlementKey).QueryAll()
+	if err == nil && len(candidates) == 0 && settlementKey != "" {
+		candidates, err = resolver.addresses.FindAddressesByKey(ctx, addressKey, "").QueryAll()
+	}
+
+	if err != nil {
+		resolver.logger.Warn().Msgf("Failed to query offline address dataset for '%s': %v", query, err)
+		return "", false
+	}
+
+	postalCode := ""
+	for _, candidate := range candidates {
+		if postalCode != "" && candidate.PostalCode != postalCode {
+			resolver.logger.Debug().Msgf("Ambiguous postal code for '%s' in offline address dataset", query)
+			return "", false
+		}
+		postalCode = candidate.PostalCode
+	}
+
+	return postalCode, postalCode != ""
+}
+
+func (resolver *PostalCodeResolver) resolveOnline(ctx context.Context, address string) (string, error) {
+	key := normalizeAddress(address)
+	cached, err := resolver.cache.FindByAddress(ctx, key).Query()
+	if err != nil {
+		resolver.logger.Warn().Msgf("Failed to query postal code cache for '%s': %v", key, err)
+	} else if cached != nil && time.Now().Before(time.Time(cached.ExpiresAt)) {
+		return cached.PostalCode, nil
+	}
+
+	postalCode, err := fetchOmnivaZipCode(ctx, address, resolver.client)
+	if err != nil {
+		resolver.logger.Error().Msgf("Failed to resolve postal code for '%s': %v", address, err)
+		return "", err
+	}
+
+	ttl := resolver.config.TTL
+	if postalCode == "" {
+		ttl = resolver.config.NegativeTTL
+	}
+
+	now := time.Now().UTC()
+	err = resolver.cache.Store(ctx, entity.PostalCodeCacheEntry{
+		Address:    key,
+		PostalCode: postalCode,
+		ResolvedAt: types.Time(now),
+		ExpiresAt:  types.Time(now.Add(ttl)),
+	})
+	if err != nil {
+		resolver.logger.Warn().Msgf("Failed to cache postal code for '%s': %v", key, err)
+	}
+
+	return postalCode, nil
+}
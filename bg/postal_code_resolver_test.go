@@ -0,0 +1,165 @@
+package bg_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// Creates a postal code cache mock, which returns provided entry on lookups
+func newCacheMock(ctrl *gomock.Controller, cached *entity.PostalCodeCacheEntry) *mock.MockPostalCodeCacheRepository {
+	queryMock := mock.NewMockQuery[entity.PostalCodeCacheEntry](ctrl)
+	queryMock.EXPECT().
+		Query().
+		Return(cached, nil)
+
+	cacheMock := mock.NewMockPostalCodeCacheRepository(ctrl)
+	cacheMock.EXPECT().
+		FindByAddress(gomock.Any(), gomock.Eq("akadeemia tee 35, tallinn, harju maakond")).
+		Return(queryMock)
+	return cacheMock
+}
+
+func omnivaResponse(status int, body string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func TestPostalCodeResolver_CacheHit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cacheMock := newCacheMock(ctrl, &entity.PostalCodeCacheEntry{
+		Address:    "akadeemia tee 35, tallinn, harju maakond",
+		PostalCode: "12618",
+		ExpiresAt:  types.Time(time.Now().Add(time.Hour)),
+	})
+	httpMock := mock.NewMockHttpClient(ctrl)
+
+	resolver := bg.NewPostalCodeResolver(newAddressMock(ctrl), cacheMock, httpMock, onlineLookup)
+	postalCode, err := resolver.Resolve(context.Background(), "Akadeemia tee 35,  Tallinn, Harju maakond")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "12618", postalCode)
+}
+
+func TestPostalCodeResolver_NegativeCacheHit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cacheMock := newCacheMock(ctrl, &entity.PostalCodeCacheEntry{
+		Address:   "akadeemia tee 35, tallinn, harju maakond",
+		ExpiresAt: types.Time(time.Now().Add(time.Hour)),
+	})
+	httpMock := mock.NewMockHttpClient(ctrl)
+
+	resolver := bg.NewPostalCodeResolver(newAddressMock(ctrl), cacheMock, httpMock, onlineLookup)
+	postalCode, err := resolver.Resolve(context.Background(), "Akadeemia tee 35, Tallinn, Harju maakond")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", postalCode)
+}
+
+func TestPostalCodeResolver_ExpiredEntry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cacheMock := newCacheMock(ctrl, &entity.PostalCodeCacheEntry{
+		Address:    "akadeemia tee 35, tallinn, harju maakond",
+		PostalCode: "10000",
+		ExpiresAt:  types.Time(time.Now().Add(-time.Hour)),
+	})
+	cacheMock.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, entry entity.PostalCodeCacheEntry) error {
+			assert.Equal(t, "akadeemia tee 35, tallinn, harju maakond", entry.Address)
+			assert.Equal(t, "12618", entry.PostalCode)
+			assert.True(t, time.Time(entry.ExpiresAt).After(time.Now()))
+			return nil
+		})
+
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Return(omnivaResponse(200, `{"addresses":[{"address":"Akadeemia tee 35, Mustamäe linnaosa, Tallinn, Harju maakond, 12618","zipCode":"12618"}]}`))
+
+	resolver := bg.NewPostalCodeResolver(newAddressMock(ctrl), cacheMock, httpMock, onlineLookup)
+	postalCode, err := resolver.Resolve(context.Background(), "Akadeemia tee 35, Tallinn, Harju maakond")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "12618", postalCode)
+}
+
+func TestPostalCodeResolver_NoResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cacheMock := newCacheMock(ctrl, nil)
+	cacheMock.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, entry entity.PostalCodeCacheEntry) error {
+			assert.Equal(t, "", entry.PostalCode)
+			return nil
+		})
+
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Return(omnivaResponse(200, `{"addresses":[]}`))
+
+	resolver := bg.NewPostalCodeResolver(newAddressMock(ctrl), cacheMock, httpMock, onlineLookup)
+	postalCode, err := resolver.Resolve(context.Background(), "Akadeemia tee 35, Tallinn, Harju maakond")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", postalCode)
+}
+
+func TestPostalCodeResolver_FailedLookupIsNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cacheMock := newCacheMock(ctrl, nil)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Return(omnivaResponse(503, ""))
+
+	resolver := bg.NewPostalCodeResolver(newAddressMock(ctrl), cacheMock, httpMock, onlineLookup)
+	postalCode, err := resolver.Resolve(context.Background(), "Akadeemia tee 35, Tallinn, Harju maakond")
+
+	assert.Error(t, err)
+	assert.Equal(t, "", postalCode)
+}
+
+func TestPostalCodeResolver_Offline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	addressMock := newAddressMock(ctrl,
+		entity.Address{StreetAddress: "Tallinna maantee 41", Settlement: "Narva linn", PostalCode: "20605", AddressKey: "tallinna maantee 41", SettlementKey: "narva"},
+		entity.Address{StreetAddress: "Tallinna maantee 41", Settlement: "Rakvere linn", PostalCode: "44311", AddressKey: "tallinna maantee 41", SettlementKey: "rakvere"},
+		entity.Address{StreetAddress: "Akadeemia tee 35", Settlement: "Mustamäe linnaosa", PostalCode: "12618", AddressKey: "akadeemia tee 35", SettlementKey: "mustamäe linnaosa"},
+	)
+
+	// online lookups are disabled, thus neither cache nor Omniva is consulted
+	resolver := bg.NewPostalCodeResolver(addressMock, nil, nil, bg.PostalCodeResolverConfig{})
+
+	// settlement disambiguates equal street addresses
+	postalCode, err := resolver.Resolve(context.Background(), "Tallinna mnt. 41, Narva, Ida-Viru maakond")
+	assert.NoError(t, err)
+	assert.Equal(t, "20605", postalCode)
+
+	// unknown settlement falls back to the street address if it is unambiguous
+	postalCode, err = resolver.Resolve(context.Background(), "Akadeemia tee 35, Tallinn, Harju maakond")
+	assert.NoError(t, err)
+	assert.Equal(t, "12618", postalCode)
+
+	// ambiguous street address without a known settlement
+	postalCode, err = resolver.Resolve(context.Background(), "Tallinna mnt 41")
+	assert.NoError(t, err)
+	assert.Equal(t, "", postalCode)
+
+	postalCode, err = resolver.Resolve(context.Background(), "Pikk 1, Tartu")
+	assert.NoError(t, err)
+	assert.Equal(t, "", postalCode)
+}
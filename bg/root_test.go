@@ -1,5 +1,175 @@
 package bg_test
 
+import (
+	"context"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/mock/gomock"
+)
+
+var onlineLookup = bg.PostalCodeResolverConfig{
+	OnlineLookup: true,
+	TTL:          time.Hour,
+	NegativeTTL:  time.Minute,
+}
+
+// Chains seeded by the chains table migration
+var testChains = []entity.Chain{
+	{ID: 1, Name: "Apotheka", DisplayName: "Apotheka", ScraperKind: utils.Ptr("apotheka"), Active: true},
+	{ID: 2, Name: "Südameapteek", DisplayName: "Südameapteek", ScraperKind: utils.Ptr("sudameapteek"), Active: true},
+	{ID: 3, Name: "Benu", DisplayName: "Benu Apteek", ScraperKind: utils.Ptr("benu"), Active: true},
+	{ID: 4, Name: "Euroapteek", DisplayName: "Euroapteek", ScraperKind: utils.Ptr("euroapteek"), Active: true},
+	{ID: 5, Name: "Kalamaja", DisplayName: "Kalamaja Apteek", Active: true},
+}
+
 func unwrap[T any](val T, err error) T {
 	return val
 }
+
+// Creates a scrape recorder, which stores the last
+// persisted state of the scrape run into provided pointer
+func newRecorder(ctrl *gomock.Controller, lastRun *entity.ScrapeRun) *bg.ScrapeRecorder {
+	runRepoMock := mock.NewMockScrapeRunRepository(ctrl)
+	runRepoMock.EXPECT().
+		Store(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(run *entity.ScrapeRun) error {
+			run.ID = 1
+			if lastRun != nil {
+				*lastRun = *run
+			}
+			return nil
+		})
+	runRepoMock.EXPECT().
+		StoreChanges(gomock.Any()).
+		AnyTimes().
+		Return(nil)
+
+	matchRepoMock := mock.NewMockPharmacyMatchRepository(ctrl)
+	matchRepoMock.EXPECT().
+		StoreAll(gomock.Any()).
+		AnyTimes().
+		Return(nil)
+
+	return bg.ProvideScrapeRecorder(runRepoMock, matchRepoMock)
+}
+
+// Creates an advisory locker, which either always
+// grants or always refuses the requested locks
+func newLocker(ctrl *gomock.Controller, acquired bool) *mock.MockAdvisoryLocker {
+	lockerMock := mock.NewMockAdvisoryLocker(ctrl)
+	lockerMock.EXPECT().
+		TryLock(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(func() {}, acquired, nil)
+	return lockerMock
+}
+
+// Creates a postal code resolver with an empty offline address dataset
+// and an empty cache, which resolves all postal codes using provided client
+func newResolver(ctrl *gomock.Controller, client utils.HttpClient) *bg.PostalCodeResolver {
+	addressMock := newAddressMock(ctrl)
+
+	cacheQueryMock := mock.NewMockQuery[entity.PostalCodeCacheEntry](ctrl)
+	cacheQueryMock.EXPECT().
+		Query().
+		AnyTimes().
+		Return(nil, nil)
+
+	cacheMock := mock.NewMockPostalCodeCacheRepository(ctrl)
+	cacheMock.EXPECT().
+		FindByAddress(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(cacheQueryMock)
+	cacheMock.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil)
+
+	return bg.NewPostalCodeResolver(addressMock, cacheMock, client, onlineLookup)
+}
+
+// Creates an offline address dataset mock, which returns
+// provided addresses with matching address and settlement keys
+func newAddressMock(ctrl *gomock.Controller, addresses ...entity.Address) *mock.MockAddressRepository {
+	addressMock := mock.NewMockAddressRepository(ctrl)
+	addressMock.EXPECT().
+		FindAddressesByKey(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, addressKey string, settlementKey string) db.Query[entity.Address] {
+			matches := make([]entity.Address, 0)
+			for _, address := range addresses {
+				if address.AddressKey == addressKey && (settlementKey == "" || address.SettlementKey == settlementKey) {
+					matches = append(matches, address)
+				}
+			}
+
+			queryMock := mock.NewMockQuery[entity.Address](ctrl)
+			queryMock.EXPECT().
+				QueryAll().
+				Return(matches, nil)
+			return queryMock
+		})
+	return addressMock
+}
+
+// Creates a chain registry mock, which returns provided
+// chains with matching scraper kinds
+func newChainMock(ctrl *gomock.Controller, chains ...entity.Chain) *mock.MockChainRepository {
+	chainMock := mock.NewMockChainRepository(ctrl)
+	chainMock.EXPECT().
+		FindChainsByScraperKind(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, kind string) db.Query[entity.Chain] {
+			matches := make([]entity.Chain, 0)
+			for _, chain := range chains {
+				if chain.ScraperKind != nil && *chain.ScraperKind == kind {
+					matches = append(matches, chain)
+				}
+			}
+			return &db.StaticQuery[entity.Chain]{Values: matches}
+		})
+	return chainMock
+}
+
+// Creates weekly opening hours from "15:04-15:04" time ranges
+// starting on Monday, empty strings denote closed days
+func weeklyHours(ranges ...string) entity.OpeningHours {
+	var hours entity.OpeningHours
+	for i, r := range ranges {
+		opens, closes, ok := strings.Cut(r, "-")
+		if !ok {
+			continue
+		}
+		hours.Weekly = append(hours.Weekly, entity.WeeklyOpeningHours{
+			Day:    time.Weekday((i + 1) % 7),
+			Opens:  opens,
+			Closes: closes,
+		})
+	}
+	return hours
+}
+
+// Creates weekly opening hours along with date-specific opening hours for every
+// day between from and to using weekly "15:04-15:04" time ranges starting on Monday
+func temporaryHours(from string, to string, ranges ...string) entity.OpeningHours {
+	hours := weeklyHours(ranges...)
+	start := unwrap(time.Parse("2006-01-02", from))
+	end := unwrap(time.Parse("2006-01-02", to))
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		exception := entity.OpeningHoursException{Date: date.Format("2006-01-02"), Closed: true}
+		for _, w := range hours.Weekly {
+			if w.Day == date.Weekday() {
+				exception = entity.OpeningHoursException{Date: exception.Date, Opens: w.Opens, Closes: w.Closes}
+			}
+		}
+		hours.Exceptions = append(hours.Exceptions, exception)
+	}
+	return hours
+}
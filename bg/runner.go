@@ -0,0 +1,131 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/utils"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Returned by ScrapeRunner.Run if another replica was already running the scraper
+var ErrScrapeSkipped = errors.New("scraper is already running on another replica")
+
+// Default deadline for a single scraper run
+const DEFAULT_SCRAPE_TIMEOUT = 15 * time.Minute
+
+// ScrapeRunner runs scrapers with a deadline, records their
+// outcomes and keeps track of in-flight scrapes so that they
+// could be cancelled on shutdown
+type ScrapeRunner struct {
+	recorder *ScrapeRecorder
+	locker   db.AdvisoryLocker
+	timeout  time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	logger   zerolog.Logger
+
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// Scrape deadline is read from SCRAPE_TIMEOUT environment
+// variable in Go duration format, e.g. "15m"
+func ProvideScrapeRunner(recorder *ScrapeRecorder, locker db.AdvisoryLocker) *ScrapeRunner {
+	logger := utils.GetLogger("BG")
+	timeout, err := time.ParseDuration(utils.Getenv("SCRAPE_TIMEOUT", DEFAULT_SCRAPE_TIMEOUT.String()))
+	if err != nil || timeout <= 0 {
+		logger.Warn().Msgf("Invalid SCRAPE_TIMEOUT value, falling back to %s", DEFAULT_SCRAPE_TIMEOUT)
+		timeout = DEFAULT_SCRAPE_TIMEOUT
+	}
+
+	return NewScrapeRunner(recorder, locker, timeout)
+}
+
+func NewScrapeRunner(recorder *ScrapeRecorder, locker db.AdvisoryLocker, timeout time.Duration) *ScrapeRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ScrapeRunner{
+		recorder: recorder,
+		locker:   locker,
+		timeout:  timeout,
+		ctx:      ctx,
+		cancel:   cancel,
+		logger:   utils.GetLogger("BG"),
+	}
+}
+
+// Runs given scraper synchronously and records its outcome.
+// Only one replica may run a scraper at a time, if another
+// replica holds the scraper's lock, the run is skipped
+func (runner *ScrapeRunner) Run(scraper Scraper) (result ScrapeResult, err error) {
+	runner.mu.Lock()
+	if runner.stopped {
+		runner.mu.Unlock()
+		return ScrapeResult{}, fmt.Errorf("scrape runner has been stopped")
+	}
+	runner.wg.Add(1)
+	runner.mu.Unlock()
+	defer runner.wg.Done()
+
+	unlock, ok, err := runner.locker.TryLock(runner.ctx, db.ScraperLockName(scraper.Name()))
+	if err != nil {
+		runner.logger.Error().Msgf("Failed to acquire lock for %s scraper: %v", scraper.Name(), err)
+		runner.recorder.Finish(runner.recorder.Begin(scraper.Name()), ScrapeResult{}, err)
+		return ScrapeResult{}, err
+	} else if !ok {
+		runner.logger.Info().Msgf("Scraper %s is already running on another replica, skipping", scraper.Name())
+		runner.recorder.Skip(scraper.Name())
+		return ScrapeResult{}, ErrScrapeSkipped
+	}
+	defer unlock()
+
+	run := runner.recorder.Begin(scraper.Name())
+	ctx, cancel := context.WithTimeout(runner.ctx, runner.timeout)
+	defer cancel()
+
+	// changes made by the scraper are attributed to the run in pharmacy revisions
+	origin := db.RevisionOrigin{Source: entity.REVISION_SOURCE_SCRAPER, Name: scraper.Name()}
+	if run.ID != 0 {
+		origin.RunID = &run.ID
+	}
+	ctx = db.WithRevisionOrigin(ctx, origin)
+
+	// a panicking scraper must not bring down the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			runner.logger.Error().Msgf("Scraper %s panicked: %v", scraper.Name(), r)
+			err = fmt.Errorf("scraper panicked: %v", r)
+		}
+		runner.recorder.Finish(run, result, err)
+	}()
+
+	return scraper.Scrape(ctx)
+}
+
+// Cancels all in-flight scrapes and waits until they have returned
+// or until provided context is done
+func (runner *ScrapeRunner) Stop(ctx context.Context) error {
+	runner.mu.Lock()
+	runner.stopped = true
+	runner.cancel()
+	runner.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		runner.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for scrapers to stop: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,162 @@
+package bg_test
+
+import (
+	"context"
+	"fmt"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// Scraper stub which delegates scraping to provided function
+type funcScraper func(ctx context.Context) (bg.ScrapeResult, error)
+
+func (f funcScraper) Name() string {
+	return "func"
+}
+
+func (f funcScraper) Scrape(ctx context.Context) (bg.ScrapeResult, error) {
+	return f(ctx)
+}
+
+func TestScrapeRunner_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, true), time.Minute)
+
+	result, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		return bg.ScrapeResult{Inserted: 2, Unchanged: 1}, nil
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Inserted)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_SUCCESS), run.Outcome)
+	assert.Equal(t, 2, run.Inserted)
+	assert.Equal(t, 1, run.Unchanged)
+	assert.NotNil(t, run.FinishedAt)
+}
+
+func TestScrapeRunner_RevisionOrigin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, nil), newLocker(ctrl, true), time.Minute)
+
+	var origin db.RevisionOrigin
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		origin = db.RevisionOriginFromContext(ctx)
+		return bg.ScrapeResult{}, nil
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, entity.REVISION_SOURCE_SCRAPER, origin.Source)
+	assert.Equal(t, "func", origin.Name)
+	assert.Equal(t, int64(1), *origin.RunID)
+}
+
+func TestScrapeRunner_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, true), time.Minute)
+
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		return bg.ScrapeResult{}, fmt.Errorf("endpoint responded with non-200 status code 503")
+	}))
+
+	assert.Error(t, err)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_FAILED), run.Outcome)
+	assert.Equal(t, "endpoint responded with non-200 status code 503", *run.Error)
+}
+
+func TestScrapeRunner_Panic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, true), time.Minute)
+
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		panic("index out of range")
+	}))
+
+	assert.Error(t, err)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_FAILED), run.Outcome)
+}
+
+func TestScrapeRunner_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, true), 10*time.Millisecond)
+
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		<-ctx.Done()
+		return bg.ScrapeResult{}, ctx.Err()
+	}))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_FAILED), run.Outcome)
+}
+
+func TestScrapeRunner_StopCancelsInFlightScrapes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, true), time.Minute)
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+			close(started)
+			<-ctx.Done()
+			return bg.ScrapeResult{}, ctx.Err()
+		}))
+		done <- err
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, runner.Stop(ctx))
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_FAILED), run.Outcome)
+
+	// runs after stopping are rejected
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		return bg.ScrapeResult{}, nil
+	}))
+	assert.Error(t, err)
+}
+
+func TestScrapeRunner_LockedByAnotherReplica(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var run entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &run), newLocker(ctrl, false), time.Minute)
+
+	_, err := runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		assert.Fail(t, "Scraper must not run without holding the lock")
+		return bg.ScrapeResult{}, nil
+	}))
+
+	assert.ErrorIs(t, err, bg.ErrScrapeSkipped)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_SKIPPED), run.Outcome)
+	assert.NotNil(t, run.FinishedAt)
+}
+
+func TestScrapeRunner_ReleasesLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	released := false
+	lockerMock := mock.NewMockAdvisoryLocker(ctrl)
+	lockerMock.EXPECT().
+		TryLock(gomock.Any(), gomock.Eq("scraper:func")).
+		Return(func() { released = true }, true, nil)
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, nil), lockerMock, time.Minute)
+
+	runner.Run(funcScraper(func(ctx context.Context) (bg.ScrapeResult, error) {
+		assert.False(t, released)
+		return bg.ScrapeResult{}, nil
+	}))
+
+	assert.True(t, released)
+}
@@ -0,0 +1,188 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pharmafinder/db/entity"
+	"pharmafinder/utils"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Returned when a scrape result fails the sanity checks and is not written
+var ErrScrapeRejected = errors.New("scrape result rejected by sanity guard")
+
+// Bounding box of Estonia with a small margin for coastal islands
+const (
+	ESTONIA_MIN_LAT = 57.5
+	ESTONIA_MAX_LAT = 59.9
+	ESTONIA_MIN_LNG = 21.5
+	ESTONIA_MAX_LNG = 28.3
+)
+
+const (
+	DEFAULT_GUARD_MIN_COUNT                  = 1
+	DEFAULT_GUARD_MAX_DROP_PERCENT           = 30
+	DEFAULT_GUARD_MAX_MISSING_COORDS_PERCENT = 10
+	// postal codes depend on the optional address dataset, thus they aren't checked by default
+	DEFAULT_GUARD_MAX_MISSING_POSTAL_CODE_PERCENT = 100
+	DEFAULT_GUARD_MAX_OUT_OF_BOUNDS_PERCENT       = 5
+)
+
+type SanityGuardConfig struct {
+	// Minimum number of scraped pharmacies
+	MinCount int
+	// Maximum share of open pharmacies in the database, which may be
+	// missing from a single scrape, i.e. closed by it or failing to parse
+	MaxDropPercent float64
+	// Maximum share of scraped pharmacies without coordinates
+	MaxMissingCoordsPercent float64
+	// Maximum share of scraped pharmacies without postal code
+	MaxMissingPostalCodePercent float64
+	// Maximum share of scraped pharmacies with coordinates outside Estonia
+	MaxOutOfBoundsPercent float64
+}
+
+// Error describing why a scrape result was rejected
+type SanityError struct {
+	Chain      string
+	Violations []string
+}
+
+func (err *SanityError) Error() string {
+	return fmt.Sprintf("%v for chain %s: %s", ErrScrapeRejected, err.Chain, strings.Join(err.Violations, "; "))
+}
+
+func (err *SanityError) Unwrap() error {
+	return ErrScrapeRejected
+}
+
+// SanityGuard refuses suspicious scrape results before they are written, e.g. when a
+// changed page layout makes only a handful of pharmacies parse and the rest would be closed
+type SanityGuard struct {
+	config  SanityGuardConfig
+	alerter Alerter
+	logger  zerolog.Logger
+}
+
+// Reads a percentage from given environment variable, falling back to the default on invalid values
+func getenvPercent(name string, fallback float64, logger *zerolog.Logger) float64 {
+	value, err := strconv.ParseFloat(utils.Getenv(name, strconv.FormatFloat(fallback, 'f', -1, 64)), 64)
+	if err != nil || value < 0 || value > 100 {
+		logger.Warn().Msgf("Invalid %s value, falling back to %v", name, fallback)
+		return fallback
+	}
+	return value
+}
+
+// Thresholds are read from SCRAPE_GUARD_MIN_COUNT, SCRAPE_GUARD_MAX_DROP_PERCENT,
+// SCRAPE_GUARD_MAX_MISSING_COORDS_PERCENT, SCRAPE_GUARD_MAX_MISSING_POSTAL_CODE_PERCENT
+// and SCRAPE_GUARD_MAX_OUT_OF_BOUNDS_PERCENT environment variables
+func ProvideSanityGuard(alerter Alerter) *SanityGuard {
+	logger := utils.GetLogger("BG")
+	minCount, err := strconv.Atoi(utils.Getenv("SCRAPE_GUARD_MIN_COUNT", strconv.Itoa(DEFAULT_GUARD_MIN_COUNT)))
+	if err != nil || minCount < 0 {
+		logger.Warn().Msgf("Invalid SCRAPE_GUARD_MIN_COUNT value, falling back to %d", DEFAULT_GUARD_MIN_COUNT)
+		minCount = DEFAULT_GUARD_MIN_COUNT
+	}
+
+	return NewSanityGuard(alerter, SanityGuardConfig{
+		MinCount:                    minCount,
+		MaxDropPercent:              getenvPercent("SCRAPE_GUARD_MAX_DROP_PERCENT", DEFAULT_GUARD_MAX_DROP_PERCENT, &logger),
+		MaxMissingCoordsPercent:     getenvPercent("SCRAPE_GUARD_MAX_MISSING_COORDS_PERCENT", DEFAULT_GUARD_MAX_MISSING_COORDS_PERCENT, &logger),
+		MaxMissingPostalCodePercent: getenvPercent("SCRAPE_GUARD_MAX_MISSING_POSTAL_CODE_PERCENT", DEFAULT_GUARD_MAX_MISSING_POSTAL_CODE_PERCENT, &logger),
+		MaxOutOfBoundsPercent:       getenvPercent("SCRAPE_GUARD_MAX_OUT_OF_BOUNDS_PERCENT", DEFAULT_GUARD_MAX_OUT_OF_BOUNDS_PERCENT, &logger),
+	})
+}
+
+func NewSanityGuard(alerter Alerter, config SanityGuardConfig) *SanityGuard {
+	return &SanityGuard{
+		config:  config,
+		alerter: alerter,
+		logger:  utils.GetLogger("BG"),
+	}
+}
+
+func percentOf(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// Checks scraped pharmacies of a chain against the existing ones and the number of
+// open pharmacies missing from the scrape, which includes both the pharmacies the scrape
+// would close and the ones which failed to parse. Violations are alerted and returned
+// as *SanityError. A nil guard accepts everything
+func (guard *SanityGuard) Check(ctx context.Context, existing []entity.Pharmacy, scraped []entity.Pharmacy, missing int) error {
+	if guard == nil {
+		return nil
+	}
+
+	chain := ""
+	for _, pharmacies := range [][]entity.Pharmacy{scraped, existing} {
+		if len(pharmacies) > 0 {
+			chain = pharmacies[0].Chain
+			break
+		}
+	}
+
+	violations := make([]string, 0)
+	if len(scraped) < guard.config.MinCount {
+		violations = append(violations, fmt.Sprintf("only %d pharmacies were scraped, expected at least %d", len(scraped), guard.config.MinCount))
+	}
+
+	open := 0
+	for i := range existing {
+		if existing[i].ClosedAt == nil {
+			open++
+		}
+	}
+
+	if drop := percentOf(missing, open); drop > guard.config.MaxDropPercent {
+		violations = append(violations, fmt.Sprintf("%d of %d open pharmacies (%.0f%%) would be closed or failed to parse, at most %v%% allowed", missing, open, drop, guard.config.MaxDropPercent))
+	}
+
+	missingCoords, missingPostalCodes, outOfBounds := 0, 0, 0
+	for i := range scraped {
+		if scraped[i].PostalCode == "" {
+			missingPostalCodes++
+		}
+
+		lat, lng := scraped[i].Latitude, scraped[i].Longitude
+		if lat == 0 || lng == 0 {
+			missingCoords++
+		} else if lat < ESTONIA_MIN_LAT || lat > ESTONIA_MAX_LAT || lng < ESTONIA_MIN_LNG || lng > ESTONIA_MAX_LNG {
+			outOfBounds++
+		}
+	}
+
+	checks := []struct {
+		count int
+		max   float64
+		what  string
+	}{
+		{missingCoords, guard.config.MaxMissingCoordsPercent, "have no coordinates"},
+		{missingPostalCodes, guard.config.MaxMissingPostalCodePercent, "have no postal code"},
+		{outOfBounds, guard.config.MaxOutOfBoundsPercent, "are located outside Estonia"},
+	}
+
+	for _, check := range checks {
+		if share := percentOf(check.count, len(scraped)); share > check.max {
+			violations = append(violations, fmt.Sprintf("%d of %d scraped pharmacies (%.0f%%) %s, at most %v%% allowed", check.count, len(scraped), share, check.what, check.max))
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	err := &SanityError{Chain: chain, Violations: violations}
+	if alertErr := guard.alerter.Alert(ctx, fmt.Sprintf("Scrape of %s rejected", chain), strings.Join(violations, "; ")); alertErr != nil {
+		guard.logger.Error().Msgf("Failed to alert about rejected scrape of %s: %v", chain, alertErr)
+	}
+	return err
+}
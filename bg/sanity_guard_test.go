@@ -0,0 +1,173 @@
+package bg_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var testGuardConfig = bg.SanityGuardConfig{
+	MinCount:                    1,
+	MaxDropPercent:              30,
+	MaxMissingCoordsPercent:     10,
+	MaxMissingPostalCodePercent: 50,
+	MaxOutOfBoundsPercent:       5,
+}
+
+// Pharmacy repository with a fixed set of existing pharmacies,
+// which captures stored and closed pharmacies
+type existingRepository struct {
+	capturingRepository
+	existing []entity.Pharmacy
+	closed   []int64
+}
+
+func (repo *existingRepository) FindPharmaciesByChain(ctx context.Context, chain string) db.Query[entity.Pharmacy] {
+	return &db.StaticQuery[entity.Pharmacy]{Values: repo.existing}
+}
+
+func (repo *existingRepository) SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error {
+	repo.stored = append(repo.stored, pharmacies...)
+	repo.closed = append(repo.closed, closeIDs...)
+	return nil
+}
+
+func newGuardPharmacies(n int, lat float64, lng float64, postalCode string) []entity.Pharmacy {
+	pharmacies := make([]entity.Pharmacy, n)
+	for i := range pharmacies {
+		pharmacies[i] = entity.Pharmacy{
+			ID:         int64(i + 1),
+			PharmacyID: int64(1000 + i),
+			Chain:      "Benu",
+			Name:       fmt.Sprintf("Benu Apteek %d", i),
+			Address:    fmt.Sprintf("Pikk %d", i+1),
+			City:       "Tartu",
+			PostalCode: postalCode,
+			Latitude:   lat,
+			Longitude:  lng,
+		}
+	}
+	return pharmacies
+}
+
+func TestSanityGuard_Accepts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	alerterMock := mock.NewMockAlerter(ctrl)
+	guard := bg.NewSanityGuard(alerterMock, testGuardConfig)
+
+	existing := newGuardPharmacies(10, 58.38, 26.72, "51004")
+	existing[9].ClosedAt = utils.Ptr(types.Time{})
+
+	// closing 2 of 9 open pharmacies is below the threshold
+	err := guard.Check(context.Background(), existing, existing[:7], 2)
+	assert.NoError(t, err)
+}
+
+func TestSanityGuard_Violations(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []entity.Pharmacy
+		scraped  []entity.Pharmacy
+		missing  int
+		expected string
+	}{
+		{"min count", nil, nil, 0, "only 0 pharmacies were scraped, expected at least 1"},
+		{"drop", newGuardPharmacies(10, 58.38, 26.72, "51004"), newGuardPharmacies(3, 58.38, 26.72, "51004"), 7, "7 of 10 open pharmacies (70%) would be closed or failed to parse, at most 30% allowed"},
+		{"coordinates", nil, newGuardPharmacies(3, 0, 0, "51004"), 0, "3 of 3 scraped pharmacies (100%) have no coordinates, at most 10% allowed"},
+		{"postal codes", nil, newGuardPharmacies(3, 58.38, 26.72, ""), 0, "3 of 3 scraped pharmacies (100%) have no postal code, at most 50% allowed"},
+		{"bounds", nil, newGuardPharmacies(3, 26.72, 58.38, "51004"), 0, "3 of 3 scraped pharmacies (100%) are located outside Estonia, at most 5% allowed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			alerterMock := mock.NewMockAlerter(ctrl)
+			alerterMock.EXPECT().
+				Alert(gomock.Any(), gomock.Any(), gomock.Eq(test.expected)).
+				Times(1).
+				Return(nil)
+
+			guard := bg.NewSanityGuard(alerterMock, testGuardConfig)
+			err := guard.Check(context.Background(), test.existing, test.scraped, test.missing)
+
+			assert.ErrorIs(t, err, bg.ErrScrapeRejected)
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
+
+func TestSanityGuard_AbortsScrape(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	alerterMock := mock.NewMockAlerter(ctrl)
+	alerterMock.EXPECT().
+		Alert(gomock.Any(), gomock.Eq("Scrape of Benu rejected"), gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	client, err := utils.NewCassetteHttpClient(utils.CassettePath("_cassettes", "benu"), utils.CASSETTE_REPLAY, nil)
+	assert.NoError(t, err)
+
+	// none of the existing pharmacies are in the listing anymore
+	repo := &existingRepository{existing: newGuardPharmacies(20, 58.38, 26.72, "51004")}
+	resolver := bg.NewPostalCodeResolver(bg.EmptyAddressRepository{}, bg.EmptyPostalCodeCacheRepository{}, client, onlineLookup)
+	guard := bg.NewSanityGuard(alerterMock, testGuardConfig)
+	scraper := bg.ProvideBenuScraper(repo, bg.StaticChainRepository{Chains: testChains}, client, resolver, guard)
+
+	var lastRun entity.ScrapeRun
+	runner := bg.NewScrapeRunner(newRecorder(ctrl, &lastRun), newLocker(ctrl, true), bg.DEFAULT_SCRAPE_TIMEOUT)
+	_, err = runner.Run(scraper)
+
+	assert.ErrorIs(t, err, bg.ErrScrapeRejected)
+	assert.Empty(t, repo.stored)
+	assert.Empty(t, repo.closed)
+	assert.Equal(t, string(entity.SCRAPE_OUTCOME_FAILED), lastRun.Outcome)
+}
+
+func TestSanityGuard_CountsSkippedPharmacies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	alerterMock := mock.NewMockAlerter(ctrl)
+	alerterMock.EXPECT().
+		Alert(gomock.Any(), gomock.Eq("Scrape of Benu rejected"), gomock.Eq("7 of 10 open pharmacies (70%) would be closed or failed to parse, at most 30% allowed")).
+		Times(1).
+		Return(nil)
+
+	// all of the existing pharmacies are listed, but only 3 of them have valid coordinates
+	entries := make([]string, 10)
+	for i := range entries {
+		latitude := "58.38"
+		if i >= 3 {
+			latitude = ""
+		}
+		entries[i] = fmt.Sprintf(`"%d":{"ID":%d,"latitude":"%s","longitude":"26.72","region":"Tartumaa","address":"Benu Apteek %d - Pikk %d","postCode":"51004","phone":"7301234"}`, 1000+i, 1000+i, latitude, i, i+1)
+	}
+	page := fmt.Sprintf(`<html><body><main><div class="bnContainer"><script>var pharmacies = {%s};</script></div></main></body></html>`, strings.Join(entries, ","))
+
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Times(1).
+		Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(page))}, nil)
+
+	repo := &existingRepository{existing: newGuardPharmacies(10, 58.38, 26.72, "51004")}
+	guard := bg.NewSanityGuard(alerterMock, testGuardConfig)
+	scraper := bg.ProvideBenuScraper(repo, bg.StaticChainRepository{Chains: testChains}, httpMock, newResolver(ctrl, httpMock), guard)
+
+	result, err := scraper.Scrape(context.Background())
+
+	assert.ErrorIs(t, err, bg.ErrScrapeRejected)
+	assert.Equal(t, 7, result.Skipped)
+	assert.Empty(t, repo.stored)
+	assert.Empty(t, repo.closed)
+}
@@ -0,0 +1,75 @@
+package bg
+
+import (
+	"fmt"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// Default schedule of all scrapers in standard 5-field crontab format (daily at 03:00)
+const DEFAULT_SCRAPE_SCHEDULE = "0 3 * * *"
+
+// Default upper bound for the random delay added to every scheduled run
+const DEFAULT_SCRAPE_JITTER = 5 * time.Minute
+
+// Effective schedule of a single scraper
+type ScrapeSchedule struct {
+	Scraper string      `json:"scraper"`
+	Enabled bool        `json:"enabled"`
+	Spec    string      `json:"schedule"`
+	Jitter  string      `json:"jitter"`
+	NextRun *types.Time `json:"nextRun"`
+
+	schedule cron.Schedule
+	jitter   time.Duration
+}
+
+// Reads the schedule of given scraper from the environment:
+//   - SCRAPE_SCHEDULE sets the default crontab spec for all scrapers
+//   - SCRAPE_SCHEDULE_<NAME> overrides the spec for a single scraper
+//   - SCRAPE_ENABLED_<NAME> enables or disables a single scraper
+//   - SCRAPE_JITTER sets the maximum random delay of scheduled runs
+//
+// where <NAME> is the upper-cased name of the scraper, e.g. SCRAPE_SCHEDULE_BENU
+func loadScrapeSchedule(scraper string) (ScrapeSchedule, error) {
+	name := strings.ToUpper(scraper)
+	spec := utils.Getenv("SCRAPE_SCHEDULE_"+name, utils.Getenv("SCRAPE_SCHEDULE", DEFAULT_SCRAPE_SCHEDULE))
+
+	enabled, err := strconv.ParseBool(utils.Getenv("SCRAPE_ENABLED_"+name, "true"))
+	if err != nil {
+		return ScrapeSchedule{}, fmt.Errorf("invalid SCRAPE_ENABLED_%s value: %v", name, err)
+	}
+
+	jitter, err := time.ParseDuration(utils.Getenv("SCRAPE_JITTER", DEFAULT_SCRAPE_JITTER.String()))
+	if err != nil || jitter < 0 {
+		return ScrapeSchedule{}, fmt.Errorf("invalid SCRAPE_JITTER value: %v", err)
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return ScrapeSchedule{}, fmt.Errorf("invalid schedule '%s' for %s scraper: %v", spec, scraper, err)
+	}
+
+	return ScrapeSchedule{
+		Scraper:  scraper,
+		Enabled:  enabled,
+		Spec:     spec,
+		Jitter:   jitter.String(),
+		schedule: schedule,
+		jitter:   jitter,
+	}, nil
+}
+
+// Returns a copy of the schedule with its next run time filled in.
+// The actual run happens up to Jitter later than NextRun
+func (s ScrapeSchedule) withNextRun(now time.Time) ScrapeSchedule {
+	if s.Enabled {
+		s.NextRun = utils.Ptr(types.Time(s.schedule.Next(now)))
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package bg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadScrapeSchedule_Defaults(t *testing.T) {
+	schedule, err := loadScrapeSchedule("benu")
+
+	assert.NoError(t, err)
+	assert.True(t, schedule.Enabled)
+	assert.Equal(t, DEFAULT_SCRAPE_SCHEDULE, schedule.Spec)
+	assert.Equal(t, DEFAULT_SCRAPE_JITTER, schedule.jitter)
+}
+
+func TestLoadScrapeSchedule_Overrides(t *testing.T) {
+	t.Setenv("SCRAPE_SCHEDULE", "0 1 * * *")
+	t.Setenv("SCRAPE_SCHEDULE_BENU", "30 4 * * 1")
+	t.Setenv("SCRAPE_ENABLED_APOTHEKA", "false")
+	t.Setenv("SCRAPE_JITTER", "0s")
+
+	benu, err := loadScrapeSchedule("benu")
+	assert.NoError(t, err)
+	assert.True(t, benu.Enabled)
+	assert.Equal(t, "30 4 * * 1", benu.Spec)
+	assert.Equal(t, time.Duration(0), benu.jitter)
+
+	// 2026-10-18 is a Sunday, next run is on Monday
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	next := time.Time(*benu.withNextRun(now).NextRun)
+	assert.Equal(t, time.Date(2026, 10, 19, 4, 30, 0, 0, time.Local), next)
+
+	apotheka, err := loadScrapeSchedule("apotheka")
+	assert.NoError(t, err)
+	assert.False(t, apotheka.Enabled)
+	assert.Equal(t, "0 1 * * *", apotheka.Spec)
+	assert.Nil(t, apotheka.withNextRun(now).NextRun)
+}
+
+func TestLoadScrapeSchedule_Invalid(t *testing.T) {
+	t.Setenv("SCRAPE_SCHEDULE_BENU", "every monday")
+	_, err := loadScrapeSchedule("benu")
+	assert.Error(t, err)
+
+	t.Setenv("SCRAPE_SCHEDULE_BENU", "")
+	t.Setenv("SCRAPE_ENABLED_BENU", "maybe")
+	_, err = loadScrapeSchedule("benu")
+	assert.Error(t, err)
+}
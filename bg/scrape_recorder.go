@@ -0,0 +1,109 @@
+package bg
+
+import (
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ScrapeRecorder persists the history of scraper runs
+// so that silently broken scrapers could be noticed
+type ScrapeRecorder struct {
+	repo    db.ScrapeRunRepository
+	matches db.PharmacyMatchRepository
+	logger  zerolog.Logger
+}
+
+func ProvideScrapeRecorder(repo db.ScrapeRunRepository, matches db.PharmacyMatchRepository) *ScrapeRecorder {
+	return &ScrapeRecorder{
+		repo:    repo,
+		matches: matches,
+		logger:  utils.GetLogger("BG"),
+	}
+}
+
+// Begins a new scrape run for given scraper
+func (recorder *ScrapeRecorder) Begin(scraper string) *entity.ScrapeRun {
+	run := &entity.ScrapeRun{
+		Scraper:   scraper,
+		StartedAt: types.Time(time.Now().UTC()),
+		Outcome:   string(entity.SCRAPE_OUTCOME_RUNNING),
+	}
+
+	if err := recorder.repo.Store(run); err != nil {
+		recorder.logger.Error().Msgf("Failed to persist the beginning of %s scrape run: %v", scraper, err)
+	}
+
+	return run
+}
+
+// Records a scrape run, which was skipped because
+// another replica was already running the same scraper
+func (recorder *ScrapeRecorder) Skip(scraper string) {
+	now := types.Time(time.Now().UTC())
+	run := &entity.ScrapeRun{
+		Scraper:    scraper,
+		StartedAt:  now,
+		FinishedAt: &now,
+		Outcome:    string(entity.SCRAPE_OUTCOME_SKIPPED),
+	}
+
+	if err := recorder.repo.Store(run); err != nil {
+		recorder.logger.Error().Msgf("Failed to persist skipped %s scrape run: %v", scraper, err)
+	}
+}
+
+// Finishes the scrape run and persists its outcome.
+// If err is not nil, the run is recorded as failed and
+// changes and queued matches of the result are discarded since they were never written
+func (recorder *ScrapeRecorder) Finish(run *entity.ScrapeRun, result ScrapeResult, err error) {
+	run.FinishedAt = utils.Ptr(types.Time(time.Now().UTC()))
+	run.Inserted = result.Inserted
+	run.Updated = result.Updated
+	run.Unchanged = result.Unchanged
+	run.Skipped = result.Skipped
+	run.Closed = result.Closed
+
+	changes := result.Changes
+	matches := result.Matches
+	if err != nil {
+		run.Outcome = string(entity.SCRAPE_OUTCOME_FAILED)
+		run.Error = utils.Ptr(err.Error())
+		changes = nil
+		matches = nil
+	} else {
+		run.Outcome = string(entity.SCRAPE_OUTCOME_SUCCESS)
+	}
+
+	if err := recorder.repo.Store(run); err != nil {
+		recorder.logger.Error().Msgf("Failed to persist %s scrape run: %v", run.Scraper, err)
+		return
+	}
+
+	for i := range changes {
+		changes[i].RunID = run.ID
+	}
+
+	if err := recorder.repo.StoreChanges(changes); err != nil {
+		recorder.logger.Error().Msgf("Failed to persist changes of %s scrape run %d: %v", run.Scraper, run.ID, err)
+	}
+
+	for i := range matches {
+		matches[i].RunID = &run.ID
+	}
+
+	if err := recorder.matches.StoreAll(matches); err != nil {
+		recorder.logger.Error().Msgf("Failed to queue pharmacy matches of %s scrape run %d: %v", run.Scraper, run.ID, err)
+	} else if len(matches) > 0 {
+		recorder.logger.Warn().Msgf("%s scrape run %d queued %d pharmacy matches for manual confirmation", run.Scraper, run.ID, len(matches))
+	}
+
+	recorder.logger.Info().Msgf(
+		"%s scrape run finished with outcome '%s': %d inserted, %d updated, %d unchanged, %d skipped, %d closed",
+		run.Scraper, run.Outcome, run.Inserted, run.Updated, run.Unchanged, run.Skipped, run.Closed,
+	)
+}
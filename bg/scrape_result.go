@@ -0,0 +1,80 @@
+package bg
+
+import "pharmafinder/db/entity"
+
+// Outcome of a single scrape, which accumulates counts
+// and per-pharmacy changes made during the scrape
+type ScrapeResult struct {
+	Inserted  int                      `json:"inserted"`
+	Updated   int                      `json:"updated"`
+	Unchanged int                      `json:"unchanged"`
+	Skipped   int                      `json:"skipped"`
+	Closed    int                      `json:"closed"`
+	Changes   []entity.ScrapeRunChange `json:"changes"`
+	// Possible matches of scraped pharmacies awaiting manual confirmation
+	Matches []entity.PharmacyMatch `json:"matches"`
+
+	// Scraped pharmacy IDs which were present in the listing,
+	// but could not be parsed. These must not be closed
+	skippedIDs map[int64]bool
+}
+
+// Marks n scraped records as skipped, e.g. because they could not be parsed
+func (result *ScrapeResult) skip(n int) {
+	result.Skipped += n
+}
+
+// Marks a single scraped record with known pharmacy ID as skipped
+func (result *ScrapeResult) skipPharmacy(pharmacyID int64) {
+	if result.skippedIDs == nil {
+		result.skippedIDs = make(map[int64]bool)
+	}
+	result.skippedIDs[pharmacyID] = true
+	result.Skipped++
+}
+
+func (result *ScrapeResult) unchanged() {
+	result.Unchanged++
+}
+
+func (result *ScrapeResult) addChange(pharmacy *entity.Pharmacy, action entity.ScrapeAction, changes entity.FieldChanges) {
+	result.Changes = append(result.Changes, entity.ScrapeRunChange{
+		PharmacyID: pharmacy.PharmacyID,
+		Chain:      pharmacy.Chain,
+		Name:       pharmacy.Name,
+		Action:     string(action),
+		Changes:    changes,
+	})
+}
+
+func (result *ScrapeResult) inserted(pharmacy *entity.Pharmacy, changes entity.FieldChanges) {
+	result.Inserted++
+	result.addChange(pharmacy, entity.SCRAPE_ACTION_INSERT, changes)
+}
+
+func (result *ScrapeResult) updated(pharmacy *entity.Pharmacy, changes entity.FieldChanges) {
+	result.Updated++
+	result.addChange(pharmacy, entity.SCRAPE_ACTION_UPDATE, changes)
+}
+
+func (result *ScrapeResult) reopened(pharmacy *entity.Pharmacy, changes entity.FieldChanges) {
+	result.Updated++
+	result.addChange(pharmacy, entity.SCRAPE_ACTION_REOPEN, changes)
+}
+
+func (result *ScrapeResult) closed(pharmacy *entity.Pharmacy) {
+	result.Closed++
+	result.addChange(pharmacy, entity.SCRAPE_ACTION_CLOSE, entity.FieldChanges{})
+}
+
+// Queues a possible match between scraped and existing pharmacy for manual confirmation
+func (result *ScrapeResult) queueMatch(scraped *entity.Pharmacy, existing *entity.Pharmacy, confidence float64) {
+	result.Matches = append(result.Matches, entity.PharmacyMatch{
+		Chain:       scraped.Chain,
+		PharmacyID:  scraped.PharmacyID,
+		CandidateID: existing.ID,
+		Confidence:  float32(confidence),
+		Pharmacy:    entity.PharmacySnapshot(*scraped),
+		Status:      string(entity.PHARMACY_MATCH_PENDING),
+	})
+}
@@ -1,7 +1,15 @@
 package bg
 
+import "context"
+
 const USER_AGENT = "Big Pharma Bot"
 
 type Scraper interface {
-	Scrape()
+	// Unique name of the scraper, used to identify
+	// its runs in the scrape history
+	Name() string
+
+	// Scrapes pharmacies and synchronizes them with the database.
+	// Scraping must be aborted once provided context is done
+	Scrape(ctx context.Context) (ScrapeResult, error)
 }
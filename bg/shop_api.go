@@ -1,16 +1,17 @@
 package bg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"pharmafinder/address"
 	"pharmafinder/db/entity"
+	"pharmafinder/phone"
 	"pharmafinder/types"
 	"pharmafinder/utils"
-	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -30,8 +31,11 @@ type shop struct {
 	Email             string  `json:"email"`
 	Phone             string  `json:"phone"`
 	UpdatedAt         string  `json:"updated_at"`
-	LocationLatitude  float32 `json:"location_latitude"`
-	LocationLongitude float32 `json:"location_longitude"`
+	LocationLatitude  float64 `json:"location_latitude"`
+	LocationLongitude float64 `json:"location_longitude"`
+
+	BusinessHours       string          `json:"business_hours"`
+	HolidayOpeningHours json.RawMessage `json:"holiday_opening_hours"`
 }
 
 type shops struct {
@@ -41,9 +45,9 @@ type shops struct {
 
 // Fetch Apotheka or Südameapteek pharmacies
 // from provided API endpoint
-func fetchShops(url string, client utils.HttpClient, logger *zerolog.Logger) (*shops, error) {
+func fetchShops(ctx context.Context, url string, client utils.HttpClient, logger *zerolog.Logger) (*shops, error) {
 	logger.Debug().Msgf("Fetching shops from API endpoint %s", url)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		logger.Error().Msgf("Failed to create a GET request object to %s: %v", url, err)
 		return nil, fmt.Errorf("failed to request shops from %s", url)
@@ -78,9 +82,13 @@ func fetchShops(url string, client utils.HttpClient, logger *zerolog.Logger) (*s
 	return &pharmacies, nil
 }
 
-func mapShopsToPharmacies(pharmacyShops *shops, chain entity.PharmacyChain, logger *zerolog.Logger, client utils.HttpClient) []entity.Pharmacy {
+func mapShopsToPharmacies(ctx context.Context, pharmacyShops *shops, chain string, logger *zerolog.Logger, resolver *PostalCodeResolver) ([]entity.Pharmacy, error) {
 	pharmacies := make([]entity.Pharmacy, 0)
 	for i := range pharmacyShops.Items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		pharmacyID, err := strconv.ParseInt(pharmacyShops.Items[i].ShopID, 10, 64)
 		if err != nil {
 			logger.Warn().Msgf("Failed to extract pharmacy ID for %s pharmacy %s, skipping", chain, pharmacyShops.Items[i].Name)
@@ -89,34 +97,22 @@ func mapShopsToPharmacies(pharmacyShops *shops, chain entity.PharmacyChain, logg
 
 		var pharmacy entity.Pharmacy
 		pharmacy.PharmacyID = pharmacyID
-		pharmacy.Chain = string(chain)
+		pharmacy.Chain = chain
 		pharmacy.Name = pharmacyShops.Items[i].Name
 
-		parts := strings.Split(pharmacyShops.Items[i].Address, ",")
-		if len(parts) > 0 {
-			pharmacy.Address = strings.Trim(parts[0], " ")
-		} else {
-			pharmacy.Address = pharmacyShops.Items[i].Address
-		}
-
-		parts = strings.Split(pharmacyShops.Items[i].City, ",")
-		if len(parts) > 0 {
-			pharmacy.City = strings.Trim(parts[0], " ")
-		} else {
-			pharmacy.City = pharmacyShops.Items[i].City
-		}
-
-		pharmacy.County = pharmacyShops.Items[i].County
+		addr := address.Parse(pharmacyShops.Items[i].Address, pharmacyShops.Items[i].City, pharmacyShops.Items[i].County)
+		pharmacy.Address = addr.StreetAddress()
+		pharmacy.City = addr.City()
+		pharmacy.County = addr.County
 
-		pharmacy.PostalCode = fetchOmnivaZipCode(pharmacyShops.Items[i].Address, client, logger)
+		// failed lookups leave the postal code empty, in which
+		// case the postal code of an existing pharmacy is kept
+		pharmacy.PostalCode, _ = resolver.Resolve(ctx, pharmacyShops.Items[i].Address)
 		pharmacy.Email = pharmacyShops.Items[i].Email
 
-		re := regexp.MustCompile(`(\+372)? *([\d ]+)`)
-		groups := re.FindStringSubmatch(pharmacyShops.Items[i].Phone)
-		if len(groups) == 3 {
-			pharmacy.PhoneNumber = fmt.Sprintf("+372%s", strings.ReplaceAll(groups[2], " ", ""))
-		} else {
-			logger.Warn().Msgf("Failed to extract Apotheka/Südameapteek pharmacy phone number")
+		pharmacy.PhoneNumbers, err = phone.Parse(pharmacyShops.Items[i].Phone)
+		if err != nil {
+			logger.Warn().Msgf("Failed to extract Apotheka/Südameapteek pharmacy phone number: %v", err)
 		}
 
 		ts, err := time.Parse("2006-01-02 15:04:05", pharmacyShops.Items[i].UpdatedAt)
@@ -129,8 +125,20 @@ func mapShopsToPharmacies(pharmacyShops *shops, chain entity.PharmacyChain, logg
 		pharmacy.Latitude = pharmacyShops.Items[i].LocationLatitude
 		pharmacy.Longitude = pharmacyShops.Items[i].LocationLongitude
 
+		hours, err := parseBusinessHours(pharmacyShops.Items[i].BusinessHours)
+		if err != nil {
+			logger.Warn().Msgf("Failed to extract opening hours for pharmacy '%s': %v", pharmacy.Name, err)
+		}
+
+		exceptions, err := parseSpecialOpeningHours(pharmacyShops.Items[i].HolidayOpeningHours)
+		if err != nil {
+			logger.Warn().Msgf("Failed to extract holiday opening hours for pharmacy '%s': %v", pharmacy.Name, err)
+		}
+		hours.Exceptions = exceptions
+		pharmacy.OpeningHours = hours
+
 		pharmacies = append(pharmacies, pharmacy)
 	}
 
-	return pharmacies
+	return pharmacies, nil
 }
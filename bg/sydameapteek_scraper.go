@@ -1,10 +1,10 @@
 package bg
 
 import (
+	"context"
+	"fmt"
 	"pharmafinder/db"
-	"pharmafinder/db/entity"
 	"pharmafinder/utils"
-	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -13,53 +13,58 @@ const SYDAMEAPTEEK_ENDPOINT = "https://www.sudameapteek.ee/shops/shop/shops"
 
 type SydameapteekScraper struct {
 	repo       db.PharmacyRepository
+	chains     db.ChainRepository
 	httpClient utils.HttpClient
+	resolver   *PostalCodeResolver
+	guard      *SanityGuard
 	logger     zerolog.Logger
 }
 
-func ProvideSydameapteekScraper(repo db.PharmacyRepository, client utils.HttpClient) Scraper {
+func ProvideSydameapteekScraper(repo db.PharmacyRepository, chains db.ChainRepository, client utils.HttpClient, resolver *PostalCodeResolver, guard *SanityGuard) Scraper {
 	return &SydameapteekScraper{
 		repo:       repo,
+		chains:     chains,
 		httpClient: client,
+		resolver:   resolver,
+		guard:      guard,
 		logger:     utils.GetLogger("BG"),
 	}
 }
 
-func (scraper *SydameapteekScraper) Scrape() {
+func (scraper *SydameapteekScraper) Name() string {
+	return "sudameapteek"
+}
+
+func (scraper *SydameapteekScraper) Scrape(ctx context.Context) (ScrapeResult, error) {
 	scraper.logger.Info().Msg("Scraping Südameapteek pharmacy locations...")
-	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(entity.CHAIN_SUDAMEAPTEEK).QueryAll()
+	var result ScrapeResult
+
+	chain, err := findScraperChain(ctx, scraper.chains, scraper.Name(), &scraper.logger)
+	if err != nil || chain == nil {
+		return result, err
+	}
+
+	existingPharmacies, err := scraper.repo.FindPharmaciesByChain(ctx, chain.Name).QueryAll()
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to query existing Südameapteek pharmacies: %v", err)
-		return
+		return result, fmt.Errorf("failed to query existing Südameapteek pharmacies: %v", err)
 	}
 
-	pharmacies, err := fetchShops(SYDAMEAPTEEK_ENDPOINT, scraper.httpClient, &scraper.logger)
+	pharmacies, err := fetchShops(ctx, SYDAMEAPTEEK_ENDPOINT, scraper.httpClient, &scraper.logger)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to fetch Südameapteek pharmacies: %v", err)
-		return
+		return result, err
 	}
 
-	pharmaciesToSave := make([]entity.Pharmacy, 0)
-	sudameapteekPharmacies := mapShopsToPharmacies(pharmacies, entity.CHAIN_SUDAMEAPTEEK, &scraper.logger, scraper.httpClient)
-	for i := range sudameapteekPharmacies {
-		var existingPharmacy *entity.Pharmacy
-		for _, existing := range existingPharmacies {
-			if sudameapteekPharmacies[i].PharmacyID == existing.PharmacyID {
-				existingPharmacy = &existing
-				break
-			}
-		}
-
-		if existingPharmacy != nil && time.Time(existingPharmacy.ModTime).UTC().UnixMilli() < time.Time(sudameapteekPharmacies[i].ModTime).UTC().UnixMilli() {
-			sudameapteekPharmacies[i].ID = existingPharmacy.ID
-			pharmaciesToSave = append(pharmaciesToSave, sudameapteekPharmacies[i])
-		} else if existingPharmacy == nil {
-			pharmaciesToSave = append(pharmaciesToSave, sudameapteekPharmacies[i])
-		}
+	sudameapteekPharmacies, err := mapShopsToPharmacies(ctx, pharmacies, chain.Name, &scraper.logger, scraper.resolver)
+	if err != nil {
+		return result, err
 	}
+	result.skip(len(pharmacies.Items) - len(sudameapteekPharmacies))
 
-	err = scraper.repo.StoreAll(pharmaciesToSave)
+	err = syncPharmacies(ctx, scraper.repo, scraper.guard, &result, existingPharmacies, sudameapteekPharmacies)
 	if err != nil {
 		scraper.logger.Error().Msgf("Failed to persist Südameapteek pharmacies: %v", err)
 	}
+	return result, err
 }
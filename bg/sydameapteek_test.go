@@ -0,0 +1,131 @@
+package bg_test
+
+import (
+	"context"
+	"embed"
+	"io"
+	"net/http"
+	"pharmafinder/bg"
+	"pharmafinder/db/entity"
+	"pharmafinder/mock"
+	"pharmafinder/types"
+	"pharmafinder/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+//go:embed _embeds/sudameapteek.json
+var sudameapteekJson embed.FS
+
+var sudameapteekPharmacies map[int]entity.Pharmacy = map[int]entity.Pharmacy{
+	12: {
+		ID:           0,
+		PharmacyID:   12,
+		Chain:        "Südameapteek",
+		Name:         "Kristiine Südameapteek",
+		Address:      "Endla 45",
+		City:         "Tallinn",
+		County:       "Harjumaa",
+		PostalCode:   "10615",
+		Email:        "kristiine@sudameapteek.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3726655120", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-03-15 11:42:05"))),
+		Latitude:     59.427154,
+		Longitude:    24.723722,
+		OpeningHours: weeklyHours("10:00-21:00", "10:00-21:00", "10:00-21:00", "10:00-21:00", "10:00-21:00", "10:00-21:00", "10:00-21:00"),
+	},
+	27: {
+		ID:           0,
+		PharmacyID:   27,
+		Chain:        "Südameapteek",
+		Name:         "Lõunakeskuse Südameapteek",
+		Address:      "Ringtee 75",
+		City:         "Tartu",
+		County:       "Tartumaa",
+		PostalCode:   "50501",
+		Email:        "lounakeskus@sudameapteek.ee",
+		PhoneNumbers: entity.PhoneNumbers{{Number: "+3727303311", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}},
+		ModTime:      types.Time(utils.Unwrap(time.Parse("2006-01-02 15:04:05", "2021-03-15 11:42:05"))),
+		Latitude:     58.358203,
+		Longitude:    26.679883,
+		OpeningHours: weeklyHours("09:00-21:00", "09:00-21:00", "09:00-21:00", "09:00-21:00", "09:00-21:00", "10:00-20:00", "10:00-20:00"),
+	},
+}
+
+// The chain is resolved through the scraper's own name, so that the
+// scraper kinds seeded for the chains are kept in line with the scrapers
+func TestSydameapteekScraper_EmptyDB(t *testing.T) {
+	// make a local copy of the map
+	m2 := make(map[int]entity.Pharmacy)
+	for k := range sudameapteekPharmacies {
+		m2[k] = sudameapteekPharmacies[k]
+	}
+
+	ctrl := gomock.NewController(t)
+	httpMock := mock.NewMockHttpClient(ctrl)
+	httpMock.EXPECT().
+		Do(gomock.Any()).
+		Times(3).
+		DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "www.omniva.ee" {
+				search := req.URL.Query().Get("search")
+				switch search {
+				case "Endla 45, Tallinn, Harju maakond":
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(`{"addresses":[{"address":"Endla 45, Kristiine linnaosa, Tallinn, Harju maakond, 10615","zipCode":"10615"}]}`)),
+					}, nil
+				case "Ringtee 75, Tartu, Tartu maakond":
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(`{"addresses":[{"address":"Ringtee 75, Tartu linn, Tartu maakond, 50501","zipCode":"50501"}]}`)),
+					}, nil
+				}
+			}
+
+			assert.Equal(t, bg.SYDAMEAPTEEK_ENDPOINT, req.URL.String())
+			file, _ := sudameapteekJson.Open("_embeds/sudameapteek.json")
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(file),
+			}, nil
+		})
+
+	queryMock := mock.NewMockQuery[entity.Pharmacy](ctrl)
+	queryMock.EXPECT().
+		QueryAll().
+		Return(nil, nil)
+
+	repoMock := mock.NewMockPharmacyRepository(ctrl)
+	repoMock.EXPECT().
+		FindPharmaciesByChain(gomock.Any(), gomock.Eq("Südameapteek")).
+		Return(queryMock)
+	repoMock.EXPECT().
+		SyncAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, pharmacies []entity.Pharmacy, _ []int64, _ types.Time) error {
+			assert.Equal(t, 2, len(pharmacies))
+
+			for _, pharmacy := range pharmacies {
+				if v, ok := m2[int(pharmacy.PharmacyID)]; ok {
+					assert.Equal(t, v, pharmacy)
+					delete(m2, int(pharmacy.PharmacyID))
+				} else {
+					assert.Fail(t, "No pharmacy with ID %v was found", pharmacy.PharmacyID)
+				}
+			}
+
+			assert.Equal(t, 0, len(m2))
+			return nil
+		})
+
+	scraper := bg.ProvideSydameapteekScraper(repoMock, newChainMock(ctrl, testChains...), httpMock, newResolver(ctrl, httpMock), nil)
+	result, err := scraper.Scrape(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Inserted)
+	assert.Equal(t, 0, result.Unchanged)
+}
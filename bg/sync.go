@@ -0,0 +1,120 @@
+package bg
+
+import (
+	"context"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"time"
+)
+
+// Compares freshly scraped pharmacies against the ones that already exist
+// in the database, records the differences into the scrape result and persists
+// all new and changed pharmacies.
+//
+// Existing pharmacies are only updated when at least one field has actually
+// changed. Closed pharmacies which reappear in the listing are reopened unless they
+// were merged into another pharmacy, while existing pharmacies missing from the
+// scraped set are marked as closed. Nothing is written if the changes don't pass
+// the sanity checks of the guard
+func syncPharmacies(ctx context.Context, repo db.PharmacyRepository, guard *SanityGuard, result *ScrapeResult, existing []entity.Pharmacy, scraped []entity.Pharmacy) error {
+	toSave, seen := mergePharmacies(result, existing, scraped)
+
+	toClose := make([]int64, 0)
+	missing := 0
+	for i := range existing {
+		if seen[i] || existing[i].ClosedAt != nil {
+			continue
+		}
+
+		// pharmacies which failed to parse are kept open, but
+		// they are still missing as far as the guard is concerned
+		missing++
+		if result.skippedIDs[existing[i].PharmacyID] {
+			continue
+		}
+
+		result.closed(&existing[i])
+		toClose = append(toClose, existing[i].ID)
+	}
+
+	if err := guard.Check(ctx, existing, scraped, missing); err != nil {
+		return err
+	}
+
+	// pharmacies are stored and closed at once, so that a failed scrape
+	// never leaves behind writes, which its scrape run wouldn't record
+	if len(toSave) > 0 || len(toClose) > 0 {
+		return repo.SyncAll(ctx, toSave, toClose, types.Time(time.Now().UTC()))
+	}
+
+	return nil
+}
+
+// Same as syncPharmacies, except that existing pharmacies
+// missing from the given set are left untouched
+func upsertPharmacies(ctx context.Context, repo db.PharmacyRepository, result *ScrapeResult, existing []entity.Pharmacy, pharmacies []entity.Pharmacy) error {
+	toSave, _ := mergePharmacies(result, existing, pharmacies)
+	if len(toSave) > 0 {
+		return repo.StoreAll(ctx, toSave)
+	}
+	return nil
+}
+
+// Matches scraped pharmacies with existing ones by their identity, records the
+// differences into the scrape result and returns pharmacies which need to be saved
+// along with flags of which existing pharmacies must be kept as they are
+func mergePharmacies(result *ScrapeResult, existing []entity.Pharmacy, scraped []entity.Pharmacy) ([]entity.Pharmacy, []bool) {
+	matches := matchIdentities(result, existing, scraped)
+	toSave := make([]entity.Pharmacy, 0)
+	seen := matches.held
+	for i := range scraped {
+		if matches.pending[i] {
+			result.skip(1)
+			continue
+		}
+
+		if matches.existing[i] < 0 {
+			result.inserted(&scraped[i], entity.DiffPharmacies(nil, &scraped[i]))
+			toSave = append(toSave, scraped[i])
+			continue
+		}
+
+		existingPharmacy := &existing[matches.existing[i]]
+		seen[matches.existing[i]] = true
+
+		// merged pharmacies are left closed, even though their chain still lists them
+		if existingPharmacy.MergedInto != nil {
+			result.unchanged()
+			continue
+		}
+
+		// the identity of a matched pharmacy is taken over by the scraped one,
+		// so that the pharmacy would be matched by its source ID from now on
+		pharmacy := scraped[i]
+		pharmacy.ID = existingPharmacy.ID
+
+		// postal codes are resolved with a separate lookup, which may fail,
+		// thus a missing postal code never overwrites a known one
+		if pharmacy.PostalCode == "" {
+			pharmacy.PostalCode = existingPharmacy.PostalCode
+		}
+		changes := entity.DiffPharmacies(existingPharmacy, &pharmacy)
+
+		if existingPharmacy.ClosedAt != nil {
+			result.reopened(&pharmacy, changes)
+			toSave = append(toSave, pharmacy)
+			continue
+		}
+
+		if len(changes) == 0 {
+			result.unchanged()
+			continue
+		}
+
+		result.updated(&pharmacy, changes)
+		toSave = append(toSave, pharmacy)
+	}
+
+	return toSave, seen
+}
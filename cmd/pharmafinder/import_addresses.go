@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/utils"
+)
+
+// Imports the offline address dataset used for postal code resolution
+//
+// Usage: pharmafinder import-addresses <dataset.csv>
+func importAddresses(args []string) int {
+	logger := utils.GetLogger("CMD")
+	flags := flag.NewFlagSet("import-addresses", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import-addresses <dataset.csv>\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Replaces the offline address dataset with addresses from given CSV file.")
+		fmt.Fprintln(flags.Output(), "The file must have a header row with a postal code column (postal_code, sihtnumber, ...)")
+		fmt.Fprintln(flags.Output(), "and either a street address column (address, aadress, ...) or street and house")
+		fmt.Fprintln(flags.Output(), "number columns (street, tanav, house, maja, ...). Settlement and county are optional.")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		logger.Error().Msgf("Failed to open address dataset: %v", err)
+		return 1
+	}
+	defer f.Close()
+
+	conn := db.ProvideDatabaseHandle()
+	defer conn.Close()
+
+	count, err := bg.ImportAddresses(context.Background(), f, db.ProvideAddressRepository(conn))
+	if err != nil {
+		logger.Error().Msgf("Failed to import address dataset: %v", err)
+		return 1
+	}
+
+	logger.Info().Msgf("Imported %d addresses from %s", count, flags.Arg(0))
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"pharmafinder"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/utils"
+)
+
+// Imports independent pharmacies from a JSON file or the embedded seed
+//
+// Usage: pharmafinder import-pharmacies [pharmacies.json]
+func importPharmacies(args []string) int {
+	logger := utils.GetLogger("CMD")
+	flags := flag.NewFlagSet("import-pharmacies", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s import-pharmacies [pharmacies.json]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Upserts independent pharmacies from given JSON file (embedded db/independent-pharmacies.json")
+		fmt.Fprintln(flags.Output(), "by default). Pharmacies are matched by chain and name, existing pharmacies missing from")
+		fmt.Fprintln(flags.Output(), "the file are left untouched, while closed pharmacies present in the file are reopened.")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var r io.Reader
+	source := "embedded seed"
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			logger.Error().Msgf("Failed to open pharmacy file: %v", err)
+			return 1
+		}
+		defer f.Close()
+		r, source = f, flags.Arg(0)
+	} else {
+		f, err := pharmafinder.PharmacyJSON.Open("db/independent-pharmacies.json")
+		if err != nil {
+			logger.Error().Msgf("Failed to open embedded db/independent-pharmacies.json file: %v", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	conn := db.ProvideDatabaseHandle()
+	defer conn.Close()
+
+	ctx := db.WithRevisionOrigin(context.Background(), db.RevisionOrigin{Source: entity.REVISION_SOURCE_IMPORT, Name: source})
+	result, err := bg.ImportIndependentPharmacies(ctx, r, db.ProvidePharmacyRepository(conn), db.ProvideChainRepository(conn))
+	if err != nil {
+		logger.Error().Msgf("Failed to import independent pharmacies: %v", err)
+		return 1
+	}
+
+	if err := db.ProvidePharmacyMatchRepository(conn).StoreAll(result.Matches); err != nil {
+		logger.Error().Msgf("Failed to queue pharmacy matches: %v", err)
+		return 1
+	} else if len(result.Matches) > 0 {
+		logger.Warn().Msgf("Queued %d pharmacy matches for manual confirmation", len(result.Matches))
+	}
+
+	logger.Info().Msgf("Imported independent pharmacies from %s: %d inserted, %d updated, %d unchanged, %d skipped",
+		source, result.Inserted, result.Updated, result.Unchanged, result.Skipped)
+	return 0
+}
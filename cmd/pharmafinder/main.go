@@ -2,10 +2,20 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"pharmafinder"
+	adminchains "pharmafinder/api/v1/admin/chains"
+	"pharmafinder/api/v1/admin/matches"
+	"pharmafinder/api/v1/admin/merges"
+	adminpharmacies "pharmafinder/api/v1/admin/pharmacies"
+	"pharmafinder/api/v1/admin/schedules"
+	"pharmafinder/api/v1/admin/scrapes"
+	"pharmafinder/api/v1/chains"
 	"pharmafinder/api/v1/pharmacies"
+	"pharmafinder/api/v1/pharmacies/history"
 	"pharmafinder/api/v1/pharmacies/ratings"
 	"pharmafinder/api/v1/pharmacies/reviews"
 	"pharmafinder/bg"
@@ -76,13 +86,31 @@ func NewHTTPServer(lc fx.Lifecycle, mux *mux.Router) *http.Server {
 // @securityDefinitions.apiKey	Bearer
 // @in 							header
 // @name 						Authorization
-// @description 				Review deletion authorization token
+// @description 				Admin authorization token
 
 // @externalDocs.description 		OpenAPI
 // @externalDocs.url 				https://swagger.io/resources/open-api
 func main() {
 	// Attempt to load .env files if they exist
 	godotenv.Load("deploy/.env.testing")
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import-addresses":
+			os.Exit(importAddresses(os.Args[2:]))
+		case "import-pharmacies":
+			os.Exit(importPharmacies(os.Args[2:]))
+		case "merge-pharmacies":
+			os.Exit(mergePharmacies(os.Args[2:]))
+		case "scrape":
+			os.Exit(scrape(os.Args[2:]))
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command '%s'\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+
 	fx.New(
 		fx.WithLogger(func() fxevent.Logger {
 			return &utils.FXZerologLogger{Logger: utils.GetLogger("FX")}
@@ -98,14 +126,28 @@ func main() {
 			db.ProvideDatabaseHandle,
 			db.ProvidePharmacyRepository,
 			db.ProvidePharmacyReviewRepository,
+			db.ProvideScrapeRunRepository,
+			db.ProvideAdvisoryLocker,
+			db.ProvidePostalCodeCacheRepository,
+			db.ProvideAddressRepository,
+			db.ProvideChainRepository,
+			db.ProvidePharmacyMatchRepository,
+			db.ProvidePharmacyMergeRepository,
+			db.ProvidePharmacyRevisionRepository,
 
 			// Utilities
 			utils.ProvideHTTPClient,
 
 			// Services
 			service.ProvideRecaptchaVerifier,
+			service.ProvideAdminAuthorizer,
 
 			// Background workers
+			bg.ProvideScrapeRecorder,
+			bg.ProvideScrapeRunner,
+			bg.ProvidePostalCodeResolver,
+			bg.ProvideAlerter,
+			bg.ProvideSanityGuard,
 			fx.Annotate(
 				bg.ProvideBenuScraper,
 				fx.ResultTags(`group:"scrapers"`),
@@ -122,10 +164,6 @@ func main() {
 				bg.ProvideEuroapteekScraper,
 				fx.ResultTags(`group:"scrapers"`),
 			),
-			fx.Annotate(
-				bg.ProvideIndependentScraper,
-				fx.ResultTags(`group:"scrapers"`),
-			),
 			fx.Annotate(
 				bg.NewCronJob,
 				fx.ParamTags(`group:"scrapers"`),
@@ -148,7 +186,55 @@ func main() {
 				ratings.ProvidePharmacyRatingController,
 				fx.ResultTags(`group:"routes"`),
 			),
+
+			// /pharmacies/{id}/history controller
+			fx.Annotate(
+				history.ProvidePharmacyHistoryController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /chains controller
+			fx.Annotate(
+				chains.ProvideChainController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/chains controller
+			fx.Annotate(
+				adminchains.ProvideChainAdminController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/pharmacies controller
+			fx.Annotate(
+				adminpharmacies.ProvideIndependentPharmacyController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/merges and /admin/pharmacies/{id}/merge controller
+			fx.Annotate(
+				merges.ProvidePharmacyMergeController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/matches controller
+			fx.Annotate(
+				matches.ProvidePharmacyMatchController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/scrapes controller
+			fx.Annotate(
+				scrapes.ProvideScrapeRunController,
+				fx.ResultTags(`group:"routes"`),
+			),
+
+			// /admin/schedules controller
+			fx.Annotate(
+				schedules.ProvideScrapeScheduleController,
+				fx.ResultTags(`group:"routes"`),
+			),
 		),
-		fx.Invoke(func(*http.Server, bg.CronJob) {}),
+		fx.Invoke(func(*http.Server, *bg.CronJob) {}),
 	).Run()
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/utils"
+	"strconv"
+)
+
+// Merges a duplicate pharmacy into another pharmacy
+//
+// Usage: pharmafinder merge-pharmacies [--by name] <source-id> <target-id>
+func mergePharmacies(args []string) int {
+	logger := utils.GetLogger("CMD")
+	flags := flag.NewFlagSet("merge-pharmacies", flag.ExitOnError)
+	mergedBy := flags.String("by", "cli", "name of the admin recorded in the merge audit log")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s merge-pharmacies [--by name] <source-id> <target-id>\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Merges the source pharmacy into the target pharmacy. Reviews and revisions of the source are")
+		fmt.Fprintln(flags.Output(), "moved to the target, the source is closed for good and kept as a tombstone, and its ID")
+		fmt.Fprintln(flags.Output(), "redirects to the target from now on. Merging is refused while the scraper of either chain is running.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	ids := make([]int64, 2)
+	for i, arg := range flags.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			logger.Error().Msgf("Malformed pharmacy ID '%s'", arg)
+			return 2
+		}
+		ids[i] = id
+	}
+
+	conn := db.ProvideDatabaseHandle()
+	defer conn.Close()
+
+	merge, err := bg.MergePharmacies(context.Background(), db.ProvidePharmacyRepository(conn), db.ProvidePharmacyMergeRepository(conn), ids[0], ids[1], *mergedBy)
+	if err != nil {
+		logger.Error().Msgf("Failed to merge pharmacy %d into %d: %v", ids[0], ids[1], err)
+		return 1
+	}
+
+	fmt.Printf("Merged %s (%d) into %d, %d reviews were moved\n", merge.Source.Name, merge.SourceID, merge.TargetID, merge.Reviews)
+	return 0
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"pharmafinder/bg"
+	"pharmafinder/db"
+	"pharmafinder/db/entity"
+	"pharmafinder/utils"
+	"strings"
+	"syscall"
+)
+
+// Outcome of a single scraper for the scrape command output
+type scrapeOutput struct {
+	Scraper string          `json:"scraper"`
+	Result  bg.ScrapeResult `json:"result"`
+	Error   *string         `json:"error"`
+}
+
+// Chains seeded by the chains table migration, used when
+// scraping against an empty in-memory store
+var defaultChains = []entity.Chain{
+	{ID: 1, Name: "Apotheka", DisplayName: "Apotheka", ScraperKind: utils.Ptr("apotheka"), Active: true},
+	{ID: 2, Name: "Südameapteek", DisplayName: "Südameapteek", ScraperKind: utils.Ptr("sudameapteek"), Active: true},
+	{ID: 3, Name: "Benu", DisplayName: "Benu Apteek", ScraperKind: utils.Ptr("benu"), Active: true},
+	{ID: 4, Name: "Euroapteek", DisplayName: "Euroapteek", ScraperKind: utils.Ptr("euroapteek"), Active: true},
+	{ID: 5, Name: "Kalamaja", DisplayName: "Kalamaja Apteek", Active: true},
+}
+
+func newScrapers(repo db.PharmacyRepository, chains db.ChainRepository, client utils.HttpClient, resolver *bg.PostalCodeResolver, guard *bg.SanityGuard) []bg.Scraper {
+	return []bg.Scraper{
+		bg.ProvideBenuScraper(repo, chains, client, resolver, guard),
+		bg.ProvideApothekaScraper(repo, chains, client, resolver, guard),
+		bg.ProvideSydameapteekScraper(repo, chains, client, resolver, guard),
+		bg.ProvideEuroapteekScraper(repo, chains, client, resolver, guard),
+	}
+}
+
+// Runs one or all scrapers and prints the changes they made
+// or, in case of a dry run, the changes they would make
+//
+// Usage: pharmafinder scrape [--dry-run] [--empty] [--json] [--record dir | --replay dir] [scraper...]
+func scrape(args []string) int {
+	logger := utils.GetLogger("CMD")
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "compute changes without writing anything to the database")
+	empty := flags.Bool("empty", false, "compare against an empty in-memory store instead of the database (implies --dry-run)")
+	jsonOutput := flags.Bool("json", false, "print changes as JSON")
+	recordDir := flags.String("record", "", "record HTTP traffic of every scraper into a cassette file in given directory")
+	replayDir := flags.String("replay", "", "replay HTTP traffic of every scraper from a cassette file in given directory")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s scrape [--dry-run] [--empty] [--json] [--record dir | --replay dir] [scraper...]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Runs given scrapers (all scrapers by default) and prints the changes made to pharmacies.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	*dryRun = *dryRun || *empty
+
+	if *recordDir != "" && *replayDir != "" {
+		logger.Error().Msg("Only one of --record and --replay can be used at a time")
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client := utils.ProvideHTTPClient()
+	var repo db.PharmacyRepository
+	var chains db.ChainRepository
+	var addresses db.AddressRepository
+	var cache db.PostalCodeCacheRepository
+	var runner *bg.ScrapeRunner
+	if *empty {
+		repo = bg.EmptyPharmacyRepository{}
+		chains = bg.StaticChainRepository{Chains: defaultChains}
+		addresses = bg.EmptyAddressRepository{}
+		cache = bg.EmptyPostalCodeCacheRepository{}
+	} else {
+		conn := db.ProvideDatabaseHandle()
+		defer conn.Close()
+
+		repo = db.ProvidePharmacyRepository(conn)
+		chains = db.ProvideChainRepository(conn)
+		addresses = db.ProvideAddressRepository(conn)
+		cache = db.ProvidePostalCodeCacheRepository(conn)
+		if *dryRun {
+			repo = bg.DryRunPharmacyRepository{PharmacyRepository: repo}
+			cache = bg.DryRunPostalCodeCacheRepository{PostalCodeCacheRepository: cache}
+		} else {
+			recorder := bg.ProvideScrapeRecorder(db.ProvideScrapeRunRepository(conn), db.ProvidePharmacyMatchRepository(conn))
+			runner = bg.ProvideScrapeRunner(recorder, db.ProvideAdvisoryLocker(conn))
+
+			// cancel in-flight scrapes on interrupt
+			go func() {
+				<-ctx.Done()
+				runner.Stop(context.Background())
+			}()
+		}
+	}
+
+	// rejected dry runs are only logged, since nothing would be written anyway
+	alerter := bg.ProvideAlerter(client)
+	if *dryRun {
+		alerter = bg.NewLogAlerter()
+	}
+	guard := bg.ProvideSanityGuard(alerter)
+
+	// scrapers are created separately for every HTTP client,
+	// so that each of them would have its own cassette
+	newScraper := func(name string, client utils.HttpClient) bg.Scraper {
+		resolver := bg.ProvidePostalCodeResolver(addresses, cache, client)
+		for _, scraper := range newScrapers(repo, chains, client, resolver, guard) {
+			if scraper.Name() == name {
+				return scraper
+			}
+		}
+		return nil
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		for _, scraper := range newScrapers(repo, chains, client, nil, nil) {
+			names = append(names, scraper.Name())
+		}
+	}
+
+	for _, name := range names {
+		if newScraper(name, client) == nil {
+			logger.Error().Msgf("Unknown scraper '%s'", name)
+			return 2
+		}
+	}
+
+	outputs := make([]scrapeOutput, 0)
+	failed := false
+	for _, name := range names {
+		var cassette *utils.CassetteHttpClient
+		var err error
+		if *recordDir != "" {
+			cassette, err = utils.NewCassetteHttpClient(utils.CassettePath(*recordDir, name), utils.CASSETTE_RECORD, client)
+		} else if *replayDir != "" {
+			cassette, err = utils.NewCassetteHttpClient(utils.CassettePath(*replayDir, name), utils.CASSETTE_REPLAY, nil)
+		}
+
+		if err != nil {
+			logger.Error().Msgf("Failed to open cassette: %v", err)
+			return 1
+		}
+
+		scraper := newScraper(name, client)
+		if cassette != nil {
+			scraper = newScraper(name, cassette)
+		}
+
+		var result bg.ScrapeResult
+		if runner != nil {
+			result, err = runner.Run(scraper)
+		} else {
+			result, err = scraper.Scrape(ctx)
+		}
+
+		output := scrapeOutput{Scraper: name, Result: result}
+		if err != nil {
+			failed = true
+			output.Error = utils.Ptr(err.Error())
+		} else if cassette != nil {
+			if err := cassette.Save(); err != nil {
+				logger.Error().Msgf("Failed to save cassette of %s scraper: %v", name, err)
+				failed = true
+			}
+		}
+		outputs = append(outputs, output)
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(outputs)
+	} else {
+		printScrapeOutputs(os.Stdout, outputs)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// Prints scrape outputs in human-readable format
+func printScrapeOutputs(w io.Writer, outputs []scrapeOutput) {
+	symbols := map[string]string{
+		"insert": "+",
+		"update": "~",
+		"reopen": "~",
+		"close":  "-",
+	}
+
+	for _, output := range outputs {
+		result := output.Result
+		fmt.Fprintf(w, "%s: %d inserted, %d updated, %d unchanged, %d skipped, %d closed\n",
+			output.Scraper, result.Inserted, result.Updated, result.Unchanged, result.Skipped, result.Closed)
+		if output.Error != nil {
+			fmt.Fprintf(w, "  error: %s\n", *output.Error)
+		}
+
+		for _, change := range result.Changes {
+			fmt.Fprintf(w, "  %s [%s] %s (%s)\n", symbols[change.Action], change.Action, change.Name, change.Chain)
+			for _, field := range change.Changes {
+				fmt.Fprintf(w, "      %s: %s -> %s\n", field.Field, quote(field.Old), quote(field.New))
+			}
+		}
+
+		for _, match := range result.Matches {
+			fmt.Fprintf(w, "  ? [match] %s (%s) might be pharmacy %d, confidence %.2f\n",
+				match.Pharmacy.Name, match.Chain, match.CandidateID, match.Confidence)
+		}
+	}
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
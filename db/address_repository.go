@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Amount of addresses inserted with a single statement
+const ADDRESS_INSERT_BATCH_SIZE = 1000
+
+type AddressRepository interface {
+	// Finds addresses by normalized street address and settlement.
+	// Empty settlement key matches addresses in any settlement
+	FindAddressesByKey(ctx context.Context, addressKey string, settlementKey string) Query[entity.Address]
+	// Atomically replaces the whole address dataset
+	ReplaceAll(ctx context.Context, addresses []entity.Address) error
+	Trx(conn any) AddressRepository
+}
+
+type AddressRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvideAddressRepository(conn *sqlx.DB) AddressRepository {
+	return AddressRepositorySQLX{conn: conn}
+}
+
+func (repo AddressRepositorySQLX) FindAddressesByKey(ctx context.Context, addressKey string, settlementKey string) Query[entity.Address] {
+	q := `
+	SELECT
+		*
+	FROM
+		addresses a
+	WHERE
+		a.address_key = $1
+	AND
+		($2 = '' OR a.settlement_key = $2)
+	`
+
+	args := []interface{}{addressKey, settlementKey}
+	return &SQLXQuery[entity.Address]{
+		ctx:       ctx,
+		uniqueKey: "id",
+		key:       "id",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo AddressRepositorySQLX) ReplaceAll(ctx context.Context, addresses []entity.Address) error {
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM addresses`); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(addresses); i += ADDRESS_INSERT_BATCH_SIZE {
+		batch := addresses[i:min(i+ADDRESS_INSERT_BATCH_SIZE, len(addresses))]
+		_, err := tx.NamedExecContext(
+			ctx,
+			`INSERT INTO addresses (street_address,settlement,county,postal_code,address_key,settlement_key)
+				VALUES (:street_address,:settlement,:county,:postal_code,:address_key,:settlement_key)`,
+			batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo AddressRepositorySQLX) Trx(conn any) AddressRepository {
+	return AddressRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Maximum time spent on releasing an advisory lock
+const ADVISORY_UNLOCK_TIMEOUT = 10 * time.Second
+
+// AdvisoryLocker provides cluster-wide named locks, which are
+// shared by all replicas connected to the same database
+type AdvisoryLocker interface {
+	// Attempts to acquire the lock with given name without blocking.
+	// If the lock is held by someone else, ok is false.
+	// Acquired lock must be released by calling unlock
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+type AdvisoryLockerSQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvideAdvisoryLocker(conn *sqlx.DB) AdvisoryLocker {
+	return AdvisoryLockerSQLX{conn: conn}
+}
+
+// Name of the lock, which is held while the scraper of given kind is running
+func ScraperLockName(kind string) string {
+	return "scraper:" + kind
+}
+
+// Acquires the locks of scrapers of given kinds until the end of the transaction,
+// so that the scrapers can't run while the transaction changes their pharmacies.
+// Returns ErrScraperRunning if any of the scrapers is running
+func lockScrapers(ctx context.Context, tx *sqlx.Tx, kinds []string) error {
+	for _, kind := range kinds {
+		var ok bool
+		if err := tx.GetContext(ctx, &ok, `SELECT pg_try_advisory_xact_lock($1)`, advisoryLockKey(ScraperLockName(kind))); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%w: %s", ErrScraperRunning, kind)
+		}
+	}
+	return nil
+}
+
+// Maps the lock name into 64-bit advisory lock key
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Session-level advisory locks are bound to a connection, thus a dedicated
+// connection is taken out of the pool for as long as the lock is held
+func (locker AdvisoryLockerSQLX) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := locker.conn.Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire a database connection: %v", err)
+	}
+
+	key := advisoryLockKey(name)
+	var ok bool
+	if err := conn.GetContext(ctx, &ok, `SELECT pg_try_advisory_lock($1)`, key); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock '%s': %v", name, err)
+	}
+
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// the lock must be released even if the context of the locked work was cancelled
+		ctx, cancel := context.WithTimeout(context.Background(), ADVISORY_UNLOCK_TIMEOUT)
+		defer cancel()
+
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			// the connection still holds the lock, discard it instead of
+			// returning it to the pool, so that the session is terminated
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
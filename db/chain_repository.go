@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/dto"
+	"pharmafinder/db/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ChainRepository interface {
+	// Finds chains along with the number of their open pharmacies
+	FindChains(includeInactive bool) Query[dto.ChainDTO]
+	FindChainByID(id int64) Query[entity.Chain]
+	FindChainByName(ctx context.Context, name string) Query[entity.Chain]
+	FindChainsByScraperKind(ctx context.Context, kind string) Query[entity.Chain]
+	Store(chain *entity.Chain) error
+	// Warning: fails with a foreign key violation if any pharmacies reference the chain
+	Delete(id int64) Query[entity.Chain]
+	Trx(conn any) ChainRepository
+}
+
+type ChainRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvideChainRepository(conn *sqlx.DB) ChainRepository {
+	return ChainRepositorySQLX{conn: conn}
+}
+
+func (repo ChainRepositorySQLX) FindChains(includeInactive bool) Query[dto.ChainDTO] {
+	q := `
+	SELECT
+		c.*,
+		COUNT(p.id) AS pharmacy_count
+	FROM
+		chains c
+	LEFT JOIN
+		pharmacies p
+	ON
+		p.chain = c."name"
+	AND
+		p.closed_at IS NULL
+	WHERE
+		($1 OR c.active)
+	GROUP BY
+		c.id
+	ORDER BY
+		c."name"
+	`
+
+	args := []interface{}{includeInactive}
+	return &SQLXQuery[dto.ChainDTO]{
+		uniqueKey: "id",
+		key:       "name",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ChainRepositorySQLX) FindChainByID(id int64) Query[entity.Chain] {
+	q := `
+	SELECT
+		*
+	FROM
+		chains c
+	WHERE
+		c.id = $1
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.Chain]{
+		uniqueKey: "id",
+		key:       "name",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ChainRepositorySQLX) FindChainByName(ctx context.Context, name string) Query[entity.Chain] {
+	q := `
+	SELECT
+		*
+	FROM
+		chains c
+	WHERE
+		c."name" = $1
+	`
+
+	args := []interface{}{name}
+	return &SQLXQuery[entity.Chain]{
+		ctx:       ctx,
+		uniqueKey: "id",
+		key:       "name",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ChainRepositorySQLX) FindChainsByScraperKind(ctx context.Context, kind string) Query[entity.Chain] {
+	q := `
+	SELECT
+		*
+	FROM
+		chains c
+	WHERE
+		c.scraper_kind = $1
+	`
+
+	args := []interface{}{kind}
+	return &SQLXQuery[entity.Chain]{
+		ctx:       ctx,
+		uniqueKey: "id",
+		key:       "name",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ChainRepositorySQLX) Store(chain *entity.Chain) error {
+	if chain.ID != 0 {
+		_, err := repo.conn.NamedExec(
+			`UPDATE chains SET
+				"name" = :name,
+				display_name = :display_name,
+				website = :website,
+				logo_url = :logo_url,
+				scraper_kind = :scraper_kind,
+				active = :active
+			WHERE
+				id = :id
+			`, chain)
+		return err
+	}
+
+	rows, err := repo.conn.NamedQuery(
+		`INSERT INTO chains ("name",display_name,website,logo_url,scraper_kind,active)
+			VALUES (:name,:display_name,:website,:logo_url,:scraper_kind,:active)
+		RETURNING *`,
+		chain)
+
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		_ = rows.StructScan(chain)
+	}
+
+	return nil
+}
+
+func (repo ChainRepositorySQLX) Delete(id int64) Query[entity.Chain] {
+	q := `
+	DELETE FROM chains
+	WHERE id = $1
+	RETURNING *
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.Chain]{
+		uniqueKey: "id",
+		key:       "name",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ChainRepositorySQLX) Trx(conn any) ChainRepository {
+	return ChainRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/url"
@@ -32,6 +33,8 @@ type Query[T any] interface {
 }
 
 type SQLXQuery[T any] struct {
+	// Optional context of the query, context.Background() is used if nil
+	ctx       context.Context
 	uniqueKey string
 	key       string
 	trx       *sqlx.DB
@@ -39,9 +42,16 @@ type SQLXQuery[T any] struct {
 	args      []interface{}
 }
 
+func (q *SQLXQuery[T]) context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
 func (q *SQLXQuery[T]) Query() (*T, error) {
 	var val T
-	err := q.trx.Get(&val, q.q, q.args...)
+	err := q.trx.GetContext(q.context(), &val, q.q, q.args...)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -54,7 +64,7 @@ func (q *SQLXQuery[T]) Query() (*T, error) {
 
 func (q *SQLXQuery[T]) QueryAll() ([]T, error) {
 	vals := []T{}
-	err := q.trx.Select(&vals, q.q, q.args...)
+	err := q.trx.SelectContext(q.context(), &vals, q.q, q.args...)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -65,6 +75,28 @@ func (q *SQLXQuery[T]) QueryAll() ([]T, error) {
 	return vals, nil
 }
 
+// In-memory query over a fixed set of values, useful
+// for repositories which are not backed by a database
+type StaticQuery[T any] struct {
+	Values []T
+}
+
+func (q *StaticQuery[T]) Query() (*T, error) {
+	if len(q.Values) == 0 {
+		return nil, nil
+	}
+	return &q.Values[0], nil
+}
+
+func (q *StaticQuery[T]) QueryAll() ([]T, error) {
+	return q.Values, nil
+}
+
+// Static queries are not paged, only the length of the query set is limited
+func (q *StaticQuery[T]) Page(uniqueKey interface{}, key interface{}, length int, desc bool) ([]T, error) {
+	return q.Values[:min(length, len(q.Values))], nil
+}
+
 // Utility function, which extracts pager
 // HTTP query parameters and returns them
 //
@@ -169,7 +201,7 @@ func (q *SQLXQuery[T]) Page(uniqueKey interface{}, key interface{}, length int,
 	}
 
 	data := []T{}
-	err := q.trx.Select(&data, outerQuery, args...)
+	err := q.trx.SelectContext(q.context(), &data, outerQuery, args...)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
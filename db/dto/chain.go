@@ -0,0 +1,17 @@
+package dto
+
+import "pharmafinder/db/entity"
+
+type ChainDTO struct {
+	entity.Chain
+	PharmacyCount int `db:"pharmacy_count" json:"pharmacyCount"`
+}
+
+type ChainCreationDTO struct {
+	Name        string  `json:"name" validate:"required,lte=32"`
+	DisplayName string  `json:"displayName" validate:"required,lte=64"`
+	Website     string  `json:"website" validate:"omitempty,url,lte=256"`
+	LogoURL     string  `json:"logoUrl" validate:"omitempty,url,lte=256"`
+	ScraperKind *string `json:"scraperKind" validate:"omitempty,lte=32"`
+	Active      bool    `json:"active"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "pharmafinder/db/entity"
+
+type IndependentPharmacyDTO struct {
+	Chain        string              `json:"chain" validate:"required,lte=32"`
+	Name         string              `json:"name" validate:"required,lte=256"`
+	Address      string              `json:"address" validate:"required,lte=64"`
+	City         string              `json:"city" validate:"required,lte=32"`
+	County       string              `json:"county" validate:"lte=32"`
+	PostalCode   string              `json:"postalCode" validate:"lte=6"`
+	Email        string              `json:"email" validate:"omitempty,email,lte=32"`
+	PhoneNumbers []string            `json:"phoneNumbers" validate:"dive,required,lte=32"`
+	Latitude     float64             `json:"lat" validate:"gte=-90,lte=90"`
+	Longitude    float64             `json:"lng" validate:"gte=-180,lte=180"`
+	OpeningHours entity.OpeningHours `json:"openingHours"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+)
+
+// Pharmacy along with its rating summary and chain metadata
+type PharmacyDetailsDTO struct {
+	entity.Pharmacy
+	ChainDisplayName string `db:"chain_display_name" json:"chainDisplayName"`
+	ChainWebsite     string `db:"chain_website" json:"chainWebsite"`
+	ChainLogoURL     string `db:"chain_logo_url" json:"chainLogoUrl"`
+
+	AvgRating   float64 `db:"avg_rating" json:"avgRating"`
+	AvgERating  float64 `db:"avg_e_rating" json:"avgERating"`
+	AvgTRating  float64 `db:"avg_t_rating" json:"avgTRating"`
+	ReviewCount int     `db:"review_count" json:"reviewCount"`
+	// Creation time of the latest review, nil if the pharmacy has no reviews
+	LastReviewAt *types.Time `db:"last_review_at" json:"lastReviewAt"`
+}
+
+// Pharmacy along with its great-circle distance from the queried point
+type PharmacyDistanceDTO struct {
+	entity.Pharmacy
+	DistanceMeters float64 `db:"distance_meters" json:"distanceMeters"`
+}
+
+// Pharmacy matching a search query along with its relevance
+type PharmacySearchResultDTO struct {
+	entity.Pharmacy
+	Rank float64 `db:"rank" json:"rank"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "pharmafinder/db/entity"
+
+type PharmacyMatchDTO struct {
+	entity.PharmacyMatch
+	// Existing pharmacy the scraped pharmacy might be
+	Candidate *entity.Pharmacy `json:"candidate"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type PharmacyMergeDTO struct {
+	// ID of the pharmacy to merge into
+	TargetID int64 `json:"targetId" validate:"required,gt=0"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+import "pharmafinder/db/entity"
+
+type ScrapeRunDetailsDTO struct {
+	entity.ScrapeRun
+	Changes []entity.ScrapeRunChange `json:"changes"`
+}
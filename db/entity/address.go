@@ -0,0 +1,13 @@
+package entity
+
+// Single address of the offline address dataset
+type Address struct {
+	ID            int64  `db:"id" json:"id"`
+	StreetAddress string `db:"street_address" json:"streetAddress"`
+	Settlement    string `db:"settlement" json:"settlement"`
+	County        string `db:"county" json:"county"`
+	PostalCode    string `db:"postal_code" json:"postalCode"`
+	// Normalized street address and settlement used for lookups
+	AddressKey    string `db:"address_key" json:"-"`
+	SettlementKey string `db:"settlement_key" json:"-"`
+}
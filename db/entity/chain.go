@@ -0,0 +1,15 @@
+package entity
+
+// Pharmacy chain. Pharmacies reference their chain by name
+type Chain struct {
+	ID          int64  `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	DisplayName string `db:"display_name" json:"displayName"`
+	Website     string `db:"website" json:"website"`
+	LogoURL     string `db:"logo_url" json:"logoUrl"`
+	// Name of the scraper which populates pharmacies of the chain,
+	// nil if pharmacies of the chain are not scraped
+	ScraperKind *string `db:"scraper_kind" json:"scraperKind"`
+	// Inactive chains are neither scraped nor listed
+	Active bool `db:"active" json:"active"`
+}
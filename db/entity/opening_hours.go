@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"pharmafinder/utils"
+	"time"
+	_ "time/tzdata"
+)
+
+// Opening hours of a pharmacy on a single day of the week.
+// Times are in "15:04" format in Estonian local time.
+// Closing time may be "24:00" for pharmacies open until midnight
+type WeeklyOpeningHours struct {
+	Day    time.Weekday `json:"day"`
+	Opens  string       `json:"opens"`
+	Closes string       `json:"closes"`
+}
+
+// Date-specific exception to the weekly opening hours,
+// e.g. shorter opening hours during a public holiday
+type OpeningHoursException struct {
+	Date   string `json:"date"` // "2006-01-02"
+	Opens  string `json:"opens,omitempty"`
+	Closes string `json:"closes,omitempty"`
+	Closed bool   `json:"closed"`
+}
+
+// Normalized opening hours of a pharmacy, stored as JSONB in the database
+type OpeningHours struct {
+	Weekly     []WeeklyOpeningHours    `json:"weekly,omitempty"`
+	Exceptions []OpeningHoursException `json:"exceptions,omitempty"`
+}
+
+var estonianTZ *time.Location = utils.Unwrap(time.LoadLocation("Europe/Tallinn"))
+
+func (h OpeningHours) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+func (h *OpeningHours) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	}
+
+	return fmt.Errorf("cannot scan type %T as entity.OpeningHours", src)
+}
+
+// Reports whether opening hours are known at all
+func (h *OpeningHours) IsKnown() bool {
+	return len(h.Weekly) > 0 || len(h.Exceptions) > 0
+}
+
+// Converts "15:04" formatted time into minutes since midnight
+func minutesOfDay(hhmm string) (int, bool) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(hhmm, "%d:%d", &hours, &minutes); err != nil {
+		return 0, false
+	}
+	return hours*60 + minutes, true
+}
+
+// Returns opening and closing time ranges (in minutes since midnight)
+// for given local date. Ranges closing after midnight have closing
+// time larger than 24 * 60
+func (h *OpeningHours) rangesOn(date time.Time) [][2]int {
+	ranges := make([][2]int, 0)
+	appendRange := func(opens string, closes string) {
+		o, ok1 := minutesOfDay(opens)
+		c, ok2 := minutesOfDay(closes)
+		if !ok1 || !ok2 {
+			return
+		}
+		if c <= o {
+			c += 24 * 60
+		}
+		ranges = append(ranges, [2]int{o, c})
+	}
+
+	// date-specific exceptions take precedence over everything else
+	dateStr := date.Format("2006-01-02")
+	hasException := false
+	for _, exception := range h.Exceptions {
+		if exception.Date != dateStr {
+			continue
+		}
+		hasException = true
+		if !exception.Closed {
+			appendRange(exception.Opens, exception.Closes)
+		}
+	}
+
+	if hasException {
+		return ranges
+	}
+
+	// public holidays without explicit exceptions follow the weekly opening
+	// hours, as pharmacies don't share a common rule for their holiday hours
+	for _, weekly := range h.Weekly {
+		if weekly.Day == date.Weekday() {
+			appendRange(weekly.Opens, weekly.Closes)
+		}
+	}
+
+	return ranges
+}
+
+// Checks if the pharmacy is open at given moment of time
+func (h *OpeningHours) IsOpenAt(t time.Time) bool {
+	local := t.In(estonianTZ)
+	minutes := local.Hour()*60 + local.Minute()
+
+	for _, r := range h.rangesOn(local) {
+		if r[0] <= minutes && minutes < r[1] {
+			return true
+		}
+	}
+
+	// check if opening hours of the previous day extend past midnight
+	for _, r := range h.rangesOn(local.AddDate(0, 0, -1)) {
+		if minutes+24*60 < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testOpeningHours OpeningHours = OpeningHours{
+	Weekly: []WeeklyOpeningHours{
+		{Day: time.Monday, Opens: "09:00", Closes: "21:00"},
+		{Day: time.Tuesday, Opens: "09:00", Closes: "21:00"},
+		{Day: time.Wednesday, Opens: "09:00", Closes: "21:00"},
+		{Day: time.Thursday, Opens: "09:00", Closes: "21:00"},
+		{Day: time.Friday, Opens: "09:00", Closes: "02:00"},
+		{Day: time.Saturday, Opens: "10:00", Closes: "18:00"},
+		{Day: time.Sunday, Opens: "10:00", Closes: "16:00"},
+	},
+	Exceptions: []OpeningHoursException{
+		{Date: "2026-12-31", Opens: "09:00", Closes: "15:00"},
+		{Date: "2026-01-01", Closed: true},
+	},
+}
+
+func tallinnTime(value string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", value, estonianTZ)
+	return t
+}
+
+func TestOpeningHours_Weekly(t *testing.T) {
+	// 2026-10-19 is a Monday
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-19 08:59")))
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-19 09:00")))
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-19 20:59")))
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-19 21:00")))
+
+	// UTC timestamps must be converted to Estonian time
+	assert.True(t, testOpeningHours.IsOpenAt(time.Date(2026, 10, 19, 17, 30, 0, 0, time.UTC)))
+	assert.False(t, testOpeningHours.IsOpenAt(time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC)))
+}
+
+func TestOpeningHours_PastMidnight(t *testing.T) {
+	// 2026-10-23 is a Friday
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-23 23:30")))
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-24 01:59")))
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-10-24 02:00")))
+}
+
+func TestOpeningHours_Exceptions(t *testing.T) {
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-12-31 14:59")))
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-12-31 15:00")))
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-01-01 12:00")))
+}
+
+func TestOpeningHours_PublicHolidays(t *testing.T) {
+	// 2026-06-24 (Wednesday) is Midsummer Day without an exception, weekly opening hours apply
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-06-24 09:30")))
+	assert.True(t, testOpeningHours.IsOpenAt(tallinnTime("2026-06-24 20:59")))
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-06-24 21:00")))
+
+	// 2026-01-01 is New Year's Day with an exception
+	assert.False(t, testOpeningHours.IsOpenAt(tallinnTime("2026-01-01 12:00")))
+}
+
+func TestOpeningHours_Unknown(t *testing.T) {
+	hours := OpeningHours{}
+	assert.False(t, hours.IsKnown())
+	assert.False(t, hours.IsOpenAt(tallinnTime("2026-10-19 12:00")))
+}
@@ -1,29 +1,107 @@
 package entity
 
-import "pharmafinder/types"
+import (
+	"encoding/json"
+	"pharmafinder/types"
+	"strconv"
+	"time"
 
-type PharmacyChain string
-
-const (
-	CHAIN_APOTHEKA        PharmacyChain = PharmacyChain("Apotheka")
-	CHAIN_SUDAMEAPTEEK                  = PharmacyChain("Südameapteek")
-	CHAIN_BENU                          = PharmacyChain("Benu")
-	CHAIN_EUROAPTEEK                    = PharmacyChain("Euroapteek")
-	CHAIN_KALAMAJA_APTEEK               = PharmacyChain("Kalamaja")
+	"github.com/lib/pq"
 )
 
 type Pharmacy struct {
-	ID          int64      `db:"id" json:"id"`
-	PharmacyID  int64      `db:"pharmacy_id" json:"-"`
-	Chain       string     `db:"chain" json:"chain"`
-	Name        string     `db:"name" json:"name"`
-	Address     string     `db:"address" json:"address"`
-	City        string     `db:"city" json:"city"`
-	County      string     `db:"county" json:"county"`
-	PostalCode  string     `db:"postal_code" json:"postalCode"`
-	Email       string     `db:"email" json:"email"`
-	PhoneNumber string     `db:"phone_number" json:"phoneNumber"`
-	ModTime     types.Time `db:"mod_time" json:"-"`
-	Latitude    float32    `db:"latitude" json:"lat"`
-	Longitude   float32    `db:"longitude" json:"lng"`
+	ID         int64      `db:"id" json:"id"`
+	PharmacyID int64      `db:"pharmacy_id" json:"-"`
+	Chain      string     `db:"chain" json:"chain"`
+	Name       string     `db:"name" json:"name"`
+	Address    string     `db:"address" json:"address"`
+	City       string     `db:"city" json:"city"`
+	County     string     `db:"county" json:"county"`
+	PostalCode string     `db:"postal_code" json:"postalCode"`
+	Email      string     `db:"email" json:"email"`
+	ModTime    types.Time `db:"mod_time" json:"-"`
+	Latitude   float64    `db:"latitude" json:"lat"`
+	Longitude  float64    `db:"longitude" json:"lng"`
+
+	PhoneNumbers PhoneNumbers `db:"phone_numbers" json:"phoneNumbers"`
+	OpeningHours OpeningHours `db:"opening_hours" json:"openingHours"`
+
+	// Timestamp of when the pharmacy disappeared from its chain's listing,
+	// nil if the pharmacy is open
+	ClosedAt *types.Time `db:"closed_at" json:"closedAt"`
+
+	// ID of the pharmacy this pharmacy was merged into, nil if the pharmacy
+	// wasn't merged. Merged pharmacies are kept closed, so that the scraper
+	// of their chain would keep matching them instead of inserting them again
+	MergedInto *int64 `db:"merged_into" json:"-"`
+
+	// Source IDs of scraped pharmacies, whose matches with this pharmacy were rejected.
+	// Only populated for pharmacies queried for a scrape, see FindPharmaciesByChain
+	RejectedMatches pq.Int64Array `db:"rejected_matches" json:"-"`
+}
+
+// Returns field-level differences between old and new
+// pharmacy records. If old is nil, all fields of new
+// except its identity are reported as changes
+func DiffPharmacies(old *Pharmacy, new *Pharmacy) FieldChanges {
+	changes := FieldChanges{}
+	if old == nil {
+		old = &Pharmacy{}
+	} else {
+		// the identity of a pharmacy only changes when it is matched by
+		// other means than its source ID, e.g. after it was renamed
+		if old.PharmacyID != new.PharmacyID {
+			changes = append(changes, FieldChange{
+				Field: "pharmacyId",
+				Old:   strconv.FormatInt(old.PharmacyID, 10),
+				New:   strconv.FormatInt(new.PharmacyID, 10),
+			})
+		}
+
+		if old.Chain != new.Chain {
+			changes = append(changes, FieldChange{Field: "chain", Old: old.Chain, New: new.Chain})
+		}
+	}
+
+	formatCoord := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	formatOpeningHours := func(v OpeningHours) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	formatClosedAt := func(v *types.Time) string {
+		if v == nil {
+			return ""
+		}
+		return time.Time(*v).UTC().Format(time.RFC3339)
+	}
+
+	fields := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"name", old.Name, new.Name},
+		{"address", old.Address, new.Address},
+		{"city", old.City, new.City},
+		{"county", old.County, new.County},
+		{"postalCode", old.PostalCode, new.PostalCode},
+		{"email", old.Email, new.Email},
+		{"phoneNumbers", old.PhoneNumbers.String(), new.PhoneNumbers.String()},
+		{"lat", formatCoord(old.Latitude), formatCoord(new.Latitude)},
+		{"lng", formatCoord(old.Longitude), formatCoord(new.Longitude)},
+		{"openingHours", formatOpeningHours(old.OpeningHours), formatOpeningHours(new.OpeningHours)},
+		{"closedAt", formatClosedAt(old.ClosedAt), formatClosedAt(new.ClosedAt)},
+	}
+
+	for _, field := range fields {
+		if field.old != field.new {
+			changes = append(changes, FieldChange{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+
+	return changes
 }
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"pharmafinder/types"
+)
+
+type PharmacyMatchStatus string
+
+const (
+	PHARMACY_MATCH_PENDING   PharmacyMatchStatus = PharmacyMatchStatus("pending")
+	PHARMACY_MATCH_CONFIRMED                     = PharmacyMatchStatus("confirmed")
+	PHARMACY_MATCH_REJECTED                      = PharmacyMatchStatus("rejected")
+)
+
+// Scraped state of a pharmacy, stored as JSONB in the database
+type PharmacySnapshot Pharmacy
+
+func (p PharmacySnapshot) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *PharmacySnapshot) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+
+	return fmt.Errorf("cannot scan type %T as entity.PharmacySnapshot", src)
+}
+
+// Possible match between a scraped pharmacy without a known identity and an
+// existing pharmacy, which was not confident enough to be applied automatically
+type PharmacyMatch struct {
+	ID    int64  `db:"id" json:"id"`
+	RunID *int64 `db:"run_id" json:"runId"`
+	Chain string `db:"chain" json:"chain"`
+	// ID of the scraped pharmacy as scraped
+	PharmacyID int64 `db:"pharmacy_id" json:"-"`
+	// ID of the existing pharmacy the scraped pharmacy might be
+	CandidateID int64            `db:"candidate_id" json:"candidateId"`
+	Confidence  float32          `db:"confidence" json:"confidence"`
+	Pharmacy    PharmacySnapshot `db:"pharmacy" json:"pharmacy"`
+	Status      string           `db:"status" json:"status"`
+	CreatedAt   types.Time       `db:"created_at" json:"createdAt"`
+	ResolvedAt  *types.Time      `db:"resolved_at" json:"resolvedAt"`
+	ResolvedBy  *string          `db:"resolved_by" json:"resolvedBy"`
+}
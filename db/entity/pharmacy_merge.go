@@ -0,0 +1,17 @@
+package entity
+
+import "pharmafinder/types"
+
+// Audit log entry of a pharmacy merged into another pharmacy
+type PharmacyMerge struct {
+	ID int64 `db:"id" json:"id"`
+	// ID of the merged pharmacy, which now redirects to the target
+	SourceID int64 `db:"source_id" json:"sourceId"`
+	TargetID int64 `db:"target_id" json:"targetId"`
+	// Merged pharmacy as it was before the merge
+	Source PharmacySnapshot `db:"source" json:"source"`
+	// Number of reviews moved to the target
+	Reviews  int        `db:"reviews" json:"reviews"`
+	MergedBy string     `db:"merged_by" json:"mergedBy"`
+	MergedAt types.Time `db:"merged_at" json:"mergedAt"`
+}
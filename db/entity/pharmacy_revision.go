@@ -0,0 +1,36 @@
+package entity
+
+import "pharmafinder/types"
+
+type RevisionSource string
+
+const (
+	REVISION_SOURCE_SCRAPER RevisionSource = RevisionSource("scraper")
+	REVISION_SOURCE_ADMIN                  = RevisionSource("admin")
+	REVISION_SOURCE_IMPORT                 = RevisionSource("import")
+	REVISION_SOURCE_UNKNOWN                = RevisionSource("unknown")
+)
+
+type RevisionAction string
+
+const (
+	REVISION_ACTION_INSERT RevisionAction = RevisionAction("insert")
+	REVISION_ACTION_UPDATE                = RevisionAction("update")
+	REVISION_ACTION_CLOSE                 = RevisionAction("close")
+	REVISION_ACTION_MERGE                 = RevisionAction("merge")
+)
+
+// Single change made to a pharmacy record along with its origin
+type PharmacyRevision struct {
+	ID         int64  `db:"id" json:"id"`
+	PharmacyID int64  `db:"pharmacy_id" json:"pharmacyId"`
+	Action     string `db:"action" json:"action"`
+	// Changed fields with their previous and new values
+	Changes FieldChanges `db:"changes" json:"changes"`
+	Source  string       `db:"source" json:"source"`
+	// Name of the scraper, admin or imported file, which made the change
+	SourceName *string `db:"source_name" json:"sourceName"`
+	// Scrape run, which made the change
+	RunID     *int64     `db:"run_id" json:"runId"`
+	CreatedAt types.Time `db:"created_at" json:"createdAt"`
+}
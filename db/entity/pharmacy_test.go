@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"pharmafinder/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPharmacies(t *testing.T) {
+	old := Pharmacy{ID: 1, PharmacyID: 10, Chain: "Benu", Name: "Benu Apteek", Address: "Kotzebue 9", PhoneNumbers: PhoneNumbers{{Number: "+3726000000", Type: PHONE_NUMBER_TYPE_LANDLINE}}, Latitude: 59.44}
+	new := old
+	new.PhoneNumbers = PhoneNumbers{{Number: "+3726000000", Type: PHONE_NUMBER_TYPE_LANDLINE}, {Number: "+37251234567", Type: PHONE_NUMBER_TYPE_MOBILE}}
+	new.Latitude = 59.45
+
+	assert.Equal(t, FieldChanges{
+		{Field: "phoneNumbers", Old: "+3726000000", New: "+3726000000, +37251234567"},
+		{Field: "lat", Old: "59.44", New: "59.45"},
+	}, DiffPharmacies(&old, &new))
+
+	assert.Empty(t, DiffPharmacies(&old, &old))
+}
+
+func TestDiffPharmacies_Identity(t *testing.T) {
+	old := Pharmacy{ID: 1, PharmacyID: 10, Chain: "Benu", Name: "Benu Apteek"}
+	new := old
+	new.PharmacyID = 11
+
+	assert.Equal(t, FieldChanges{{Field: "pharmacyId", Old: "10", New: "11"}}, DiffPharmacies(&old, &new))
+
+	// identity of inserted pharmacies is not reported
+	inserted := DiffPharmacies(nil, &new)
+	for _, change := range inserted {
+		assert.NotEqual(t, "pharmacyId", change.Field)
+		assert.NotEqual(t, "chain", change.Field)
+	}
+	assert.Contains(t, inserted, FieldChange{Field: "name", Old: "", New: "Benu Apteek"})
+}
+
+func TestDiffPharmacies_Closed(t *testing.T) {
+	closedAt := types.Time(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	old := Pharmacy{ID: 1}
+	new := Pharmacy{ID: 1, ClosedAt: &closedAt}
+
+	assert.Equal(t, FieldChanges{{Field: "closedAt", Old: "", New: "2026-10-18T12:00:00Z"}}, DiffPharmacies(&old, &new))
+}
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type PhoneNumberType string
+
+const (
+	PHONE_NUMBER_TYPE_LANDLINE PhoneNumberType = "landline"
+	PHONE_NUMBER_TYPE_MOBILE   PhoneNumberType = "mobile"
+	PHONE_NUMBER_TYPE_TOLLFREE PhoneNumberType = "tollfree"
+	// Foreign numbers, whose type can't be told from the number itself
+	PHONE_NUMBER_TYPE_UNKNOWN PhoneNumberType = "unknown"
+)
+
+type PhoneNumber struct {
+	// Phone number in E.164 format, e.g. "+3726413975"
+	Number string          `json:"number"`
+	Type   PhoneNumberType `json:"type"`
+}
+
+// Phone numbers of a pharmacy in the order they were listed by the source.
+// Stored in the pharmacy_phone_numbers table and read as a JSON array
+type PhoneNumbers []PhoneNumber
+
+func (n *PhoneNumbers) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = PhoneNumbers{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, n)
+	case string:
+		return json.Unmarshal([]byte(v), n)
+	}
+
+	return fmt.Errorf("cannot scan type %T as entity.PhoneNumbers", src)
+}
+
+// Numbers are always encoded as an array, even if there are none
+func (n PhoneNumbers) MarshalJSON() ([]byte, error) {
+	if n == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]PhoneNumber(n))
+}
+
+// Returns comma separated numbers, e.g. "+3726413975, +37251234567"
+func (n PhoneNumbers) String() string {
+	numbers := make([]string, len(n))
+	for i := range n {
+		numbers[i] = n[i].Number
+	}
+	return strings.Join(numbers, ", ")
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "pharmafinder/types"
+
+// Cached result of a postal code lookup.
+// Empty postal code denotes a lookup which found no results
+type PostalCodeCacheEntry struct {
+	Address    string     `db:"address" json:"address"`
+	PostalCode string     `db:"postal_code" json:"postalCode"`
+	ResolvedAt types.Time `db:"resolved_at" json:"resolvedAt"`
+	ExpiresAt  types.Time `db:"expires_at" json:"expiresAt"`
+}
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"pharmafinder/types"
+)
+
+type ScrapeOutcome string
+
+const (
+	SCRAPE_OUTCOME_RUNNING ScrapeOutcome = ScrapeOutcome("running")
+	SCRAPE_OUTCOME_SUCCESS               = ScrapeOutcome("success")
+	SCRAPE_OUTCOME_FAILED                = ScrapeOutcome("failed")
+	SCRAPE_OUTCOME_SKIPPED               = ScrapeOutcome("skipped")
+)
+
+type ScrapeAction string
+
+const (
+	SCRAPE_ACTION_INSERT ScrapeAction = ScrapeAction("insert")
+	SCRAPE_ACTION_UPDATE              = ScrapeAction("update")
+	SCRAPE_ACTION_CLOSE               = ScrapeAction("close")
+	SCRAPE_ACTION_REOPEN              = ScrapeAction("reopen")
+)
+
+// Single run of a scraper
+type ScrapeRun struct {
+	ID         int64       `db:"id" json:"id"`
+	Scraper    string      `db:"scraper" json:"scraper"`
+	StartedAt  types.Time  `db:"started_at" json:"startedAt"`
+	FinishedAt *types.Time `db:"finished_at" json:"finishedAt"`
+	Outcome    string      `db:"outcome" json:"outcome"`
+	Error      *string     `db:"error" json:"error"`
+	Inserted   int         `db:"inserted" json:"inserted"`
+	Updated    int         `db:"updated" json:"updated"`
+	Unchanged  int         `db:"unchanged" json:"unchanged"`
+	Skipped    int         `db:"skipped" json:"skipped"`
+	Closed     int         `db:"closed" json:"closed"`
+}
+
+// Describes a change of a single pharmacy field
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// List of field changes, stored as JSONB in the database
+type FieldChanges []FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *FieldChanges) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+
+	return fmt.Errorf("cannot scan type %T as entity.FieldChanges", src)
+}
+
+// Per-pharmacy change that was made during a scrape run
+type ScrapeRunChange struct {
+	ID         int64        `db:"id" json:"id"`
+	RunID      int64        `db:"run_id" json:"-"`
+	PharmacyID int64        `db:"pharmacy_id" json:"pharmacyId"`
+	Chain      string       `db:"chain" json:"chain"`
+	Name       string       `db:"name" json:"name"`
+	Action     string       `db:"action" json:"action"`
+	Changes    FieldChanges `db:"changes" json:"changes"`
+}
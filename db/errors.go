@@ -0,0 +1,34 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Returned when a pharmacy can't be changed, because the scraper of its chain is running
+var ErrScraperRunning = errors.New("scraper of the pharmacy's chain is running")
+
+// Returned when a pharmacy match can't be resolved, because it is not pending anymore
+var ErrMatchResolved = errors.New("pharmacy match has already been resolved")
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	PQ_FOREIGN_KEY_VIOLATION = pq.ErrorCode("23503")
+	PQ_UNIQUE_VIOLATION      = pq.ErrorCode("23505")
+)
+
+func hasErrorCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+// Reports whether the error was caused by a row, which is still referenced by other rows
+func IsForeignKeyViolation(err error) bool {
+	return hasErrorCode(err, PQ_FOREIGN_KEY_VIOLATION)
+}
+
+// Reports whether the error was caused by a duplicate value of a unique column
+func IsUniqueViolation(err error) bool {
+	return hasErrorCode(err, PQ_UNIQUE_VIOLATION)
+}
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE scrape_outcome_t AS ENUM ('running', 'success', 'failed');
+CREATE TABLE scrape_runs (
+    id BIGSERIAL PRIMARY KEY,
+    scraper VARCHAR(32) NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT now(),
+    finished_at TIMESTAMP,
+    outcome scrape_outcome_t NOT NULL DEFAULT 'running',
+    error TEXT,
+    inserted INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    unchanged INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0
+);
+
+CREATE TYPE scrape_action_t AS ENUM ('insert', 'update');
+CREATE TABLE scrape_run_changes (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES scrape_runs(id) ON DELETE CASCADE,
+    pharmacy_id BIGINT NOT NULL, -- ID of the pharmacy as scraped
+    chain VARCHAR(32) NOT NULL,
+    "name" VARCHAR(256) NOT NULL,
+    action scrape_action_t NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX idx_scrape_runs_scraper_started_at ON scrape_runs (scraper, started_at);
+CREATE INDEX idx_scrape_run_changes_run_id ON scrape_run_changes (run_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_scrape_run_changes_run_id;
+DROP INDEX idx_scrape_runs_scraper_started_at;
+DROP TABLE scrape_run_changes;
+DROP TYPE scrape_action_t;
+DROP TABLE scrape_runs;
+DROP TYPE scrape_outcome_t;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pharmacies ADD COLUMN closed_at TIMESTAMP;
+CREATE INDEX idx_pharmacies_closed_at ON pharmacies (closed_at);
+
+ALTER TABLE scrape_runs ADD COLUMN closed INT NOT NULL DEFAULT 0;
+ALTER TYPE scrape_action_t ADD VALUE 'close';
+ALTER TYPE scrape_action_t ADD VALUE 'reopen';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: PostgreSQL does not support removing values from enums,
+-- thus 'close' and 'reopen' scrape actions are left in place
+ALTER TABLE scrape_runs DROP COLUMN closed;
+DROP INDEX idx_pharmacies_closed_at;
+ALTER TABLE pharmacies DROP COLUMN closed_at;
+-- +goose StatementEnd
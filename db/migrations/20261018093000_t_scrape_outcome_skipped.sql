@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE scrape_outcome_t ADD VALUE 'skipped';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: PostgreSQL does not support removing values from enums,
+-- thus 'skipped' scrape outcome is left in place
+SELECT 1;
+-- +goose StatementEnd
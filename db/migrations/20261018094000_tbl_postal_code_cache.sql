@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE postal_code_cache (
+    "address" VARCHAR(512) PRIMARY KEY, -- normalized address
+    postal_code VARCHAR(16) NOT NULL DEFAULT '', -- empty if lookup found nothing
+    resolved_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE postal_code_cache;
+-- +goose StatementEnd
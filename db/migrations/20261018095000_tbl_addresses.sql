@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE addresses (
+    id BIGSERIAL PRIMARY KEY,
+    street_address VARCHAR(256) NOT NULL,
+    settlement VARCHAR(128) NOT NULL DEFAULT '',
+    county VARCHAR(64) NOT NULL DEFAULT '',
+    postal_code VARCHAR(16) NOT NULL,
+    address_key VARCHAR(256) NOT NULL,
+    settlement_key VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_addresses_address_key_settlement_key ON addresses (address_key, settlement_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_addresses_address_key_settlement_key;
+DROP TABLE addresses;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE chains (
+    id BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(32) NOT NULL UNIQUE,
+    display_name VARCHAR(64) NOT NULL,
+    website VARCHAR(256) NOT NULL DEFAULT '',
+    logo_url VARCHAR(256) NOT NULL DEFAULT '',
+    scraper_kind VARCHAR(32), -- Name of the scraper which populates pharmacies of the chain
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+INSERT INTO chains ("name", display_name, website, scraper_kind) VALUES
+    ('Apotheka', 'Apotheka', 'https://www.apotheka.ee', 'apotheka'),
+    ('Südameapteek', 'Südameapteek', 'https://www.sudameapteek.ee', 'sudameapteek'),
+    ('Benu', 'Benu Apteek', 'https://www.benu.ee', 'benu'),
+    ('Euroapteek', 'Euroapteek', 'https://www.euroapteek.ee', 'euroapteek'),
+    ('Kalamaja', 'Kalamaja Apteek', 'https://www.kalamajaapteek.ee', 'independent');
+
+-- chains which might have been added to the enum manually
+INSERT INTO chains ("name", display_name)
+    SELECT DISTINCT p.chain::TEXT, p.chain::TEXT FROM pharmacies p
+    ON CONFLICT ("name") DO NOTHING;
+
+ALTER TABLE pharmacies ALTER COLUMN chain TYPE VARCHAR(32) USING chain::TEXT;
+ALTER TABLE pharmacies ADD CONSTRAINT fk_pharmacies_chain
+    FOREIGN KEY (chain) REFERENCES chains ("name") ON UPDATE CASCADE;
+DROP TYPE chain_t;
+
+CREATE INDEX idx_chains_scraper_kind ON chains (scraper_kind);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: pharmacies of chains added after the migration prevent the rollback
+CREATE TYPE chain_t AS ENUM ('Apotheka', 'Südameapteek', 'Benu', 'Euroapteek', 'Kalamaja');
+ALTER TABLE pharmacies DROP CONSTRAINT fk_pharmacies_chain;
+ALTER TABLE pharmacies ALTER COLUMN chain TYPE chain_t USING chain::chain_t;
+DROP INDEX idx_chains_scraper_kind;
+DROP TABLE chains;
+-- +goose StatementEnd
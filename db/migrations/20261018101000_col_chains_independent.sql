@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- independent pharmacies are managed through the admin API instead of a scraper
+UPDATE chains SET scraper_kind = NULL WHERE scraper_kind = 'independent';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE chains SET scraper_kind = 'independent' WHERE "name" = 'Kalamaja';
+-- +goose StatementEnd
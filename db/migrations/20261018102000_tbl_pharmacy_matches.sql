@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE pharmacy_match_status_t AS ENUM ('pending', 'confirmed', 'rejected');
+CREATE TABLE pharmacy_matches (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT REFERENCES scrape_runs(id) ON DELETE SET NULL,
+    chain VARCHAR(32) NOT NULL REFERENCES chains ("name") ON UPDATE CASCADE ON DELETE CASCADE,
+    pharmacy_id BIGINT NOT NULL, -- ID of the pharmacy as scraped
+    candidate_id BIGINT NOT NULL REFERENCES pharmacies(id) ON DELETE CASCADE,
+    confidence REAL NOT NULL,
+    pharmacy JSONB NOT NULL, -- Pharmacy as scraped
+    status pharmacy_match_status_t NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMP,
+    resolved_by VARCHAR(64)
+);
+
+-- the same match is queued again on every scrape until it gets resolved
+CREATE UNIQUE INDEX idx_pharmacy_matches_pending ON pharmacy_matches (chain, pharmacy_id, candidate_id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_pharmacy_matches_pending;
+DROP TABLE pharmacy_matches;
+DROP TYPE pharmacy_match_status_t;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE pharmacy_merges (
+    id BIGSERIAL PRIMARY KEY,
+    source_id BIGINT NOT NULL, -- ID of the merged pharmacy, which redirects to the target
+    target_id BIGINT NOT NULL, -- ID of the pharmacy at the time of the merge
+    source JSONB NOT NULL, -- Merged pharmacy as it was before the merge
+    reviews INT NOT NULL, -- Number of moved reviews
+    merged_by VARCHAR(64) NOT NULL,
+    merged_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- every redirect points directly to an existing pharmacy, since
+-- redirects into a merged pharmacy are moved along with its reviews
+CREATE TABLE pharmacy_redirects (
+    source_id BIGINT PRIMARY KEY,
+    target_id BIGINT NOT NULL REFERENCES pharmacies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_pharmacy_redirects_target_id ON pharmacy_redirects (target_id);
+
+CREATE OR REPLACE FUNCTION resolve_pharmacy_id(_id BIGINT)
+RETURNS BIGINT AS $$
+	SELECT COALESCE((SELECT target_id FROM pharmacy_redirects WHERE source_id = _id), _id)
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION resolve_pharmacy_id;
+DROP INDEX idx_pharmacy_redirects_target_id;
+DROP TABLE pharmacy_redirects;
+DROP TABLE pharmacy_merges;
+-- +goose StatementEnd
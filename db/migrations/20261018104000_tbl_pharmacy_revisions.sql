@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE revision_source_t AS ENUM ('scraper', 'admin', 'import', 'unknown');
+CREATE TYPE revision_action_t AS ENUM ('insert', 'update', 'close');
+CREATE TABLE pharmacy_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    pharmacy_id BIGINT NOT NULL REFERENCES pharmacies(id) ON DELETE CASCADE,
+    action revision_action_t NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]', -- Changed fields with their previous and new values
+    source revision_source_t NOT NULL DEFAULT 'unknown',
+    source_name VARCHAR(64), -- Name of the scraper, admin or imported file
+    run_id BIGINT REFERENCES scrape_runs(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_pharmacy_revisions_pharmacy_id_created_at ON pharmacy_revisions (pharmacy_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_pharmacy_revisions_pharmacy_id_created_at;
+DROP TABLE pharmacy_revisions;
+DROP TYPE revision_action_t;
+DROP TYPE revision_source_t;
+-- +goose StatementEnd
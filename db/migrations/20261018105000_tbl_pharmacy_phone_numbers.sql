@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE phone_number_type_t AS ENUM ('landline', 'mobile', 'tollfree', 'unknown');
+CREATE TABLE pharmacy_phone_numbers (
+    pharmacy_id BIGINT NOT NULL REFERENCES pharmacies(id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL, -- Order in which the source listed the numbers
+    "number" VARCHAR(16) NOT NULL, -- E.164, e.g. +3726413975
+    "type" phone_number_type_t NOT NULL,
+    PRIMARY KEY (pharmacy_id, position)
+);
+
+INSERT INTO pharmacy_phone_numbers (pharmacy_id, position, "number", "type")
+SELECT
+    id,
+    0,
+    phone_number,
+    (CASE
+        WHEN phone_number ~ '^\+372800' THEN 'tollfree'
+        WHEN phone_number ~ '^\+372(5|8[1-4])' THEN 'mobile'
+        WHEN phone_number ~ '^\+372[3467]' THEN 'landline'
+        ELSE 'unknown'
+    END)::phone_number_type_t
+FROM
+    pharmacies
+WHERE
+    phone_number ~ '^\+[1-9][0-9]{7,14}$';
+
+ALTER TABLE pharmacies DROP COLUMN phone_number;
+
+-- Phone numbers of a pharmacy as a JSON array in the order they were listed
+CREATE OR REPLACE FUNCTION find_pharmacy_phone_numbers(_id BIGINT)
+RETURNS JSONB AS $$
+    SELECT
+        COALESCE(jsonb_agg(jsonb_build_object('number', ppn."number", 'type', ppn."type") ORDER BY ppn.position), '[]')
+    FROM
+        pharmacy_phone_numbers ppn
+    WHERE
+        ppn.pharmacy_id = _id
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION find_pharmacy_phone_numbers;
+ALTER TABLE pharmacies ADD COLUMN phone_number VARCHAR(16) NOT NULL DEFAULT '';
+UPDATE pharmacies p SET phone_number = ppn."number" FROM pharmacy_phone_numbers ppn WHERE ppn.pharmacy_id = p.id AND ppn.position = 0;
+ALTER TABLE pharmacies ALTER COLUMN phone_number DROP DEFAULT;
+DROP TABLE pharmacy_phone_numbers;
+DROP TYPE phone_number_type_t;
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+-- REAL coordinates are only accurate to about a meter. Coordinates are converted
+-- through their shortest text form, so that e.g. 59.4372 doesn't become 59.43719863891602
+ALTER TABLE pharmacies
+    ALTER COLUMN latitude TYPE DOUBLE PRECISION USING latitude::TEXT::DOUBLE PRECISION,
+    ALTER COLUMN longitude TYPE DOUBLE PRECISION USING longitude::TEXT::DOUBLE PRECISION;
+
+-- Keeps the location of a pharmacy in sync with its coordinates
+CREATE OR REPLACE FUNCTION sync_pharmacy_location()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO pharmacy_locations (pharmacy_id, "location")
+        VALUES (NEW.id, ST_SetSRID(ST_MakePoint(NEW.longitude, NEW.latitude), 4326)::geography)
+    ON CONFLICT (pharmacy_id) DO UPDATE SET "location" = EXCLUDED."location";
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Creates PostGIS backed pharmacy locations, if PostGIS is available on the server.
+-- Returns whether the locations are enabled. PostGIS installed after this migration
+-- is taken into use by calling the function by hand, e.g. SELECT enable_pharmacy_locations()
+CREATE OR REPLACE FUNCTION enable_pharmacy_locations()
+RETURNS BOOLEAN AS $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE "name" = 'postgis') THEN
+        RAISE NOTICE 'PostGIS is not available, pharmacy locations are not enabled';
+        RETURN FALSE;
+    END IF;
+
+    BEGIN
+        CREATE EXTENSION IF NOT EXISTS postgis;
+    EXCEPTION WHEN insufficient_privilege THEN
+        RAISE NOTICE 'Not allowed to create PostGIS extension, pharmacy locations are not enabled';
+        RETURN FALSE;
+    END;
+
+    CREATE TABLE IF NOT EXISTS pharmacy_locations (
+        pharmacy_id BIGINT PRIMARY KEY REFERENCES pharmacies(id) ON DELETE CASCADE,
+        "location" geography(Point, 4326) NOT NULL
+    );
+
+    -- geography index answers radius and distance queries, while
+    -- geometry index answers latitude/longitude bounds queries
+    CREATE INDEX IF NOT EXISTS idx_pharmacy_locations_location ON pharmacy_locations USING GIST ("location");
+    CREATE INDEX IF NOT EXISTS idx_pharmacy_locations_geometry ON pharmacy_locations USING GIST (("location"::geometry));
+
+    INSERT INTO pharmacy_locations (pharmacy_id, "location")
+        SELECT p.id, ST_SetSRID(ST_MakePoint(p.longitude, p.latitude), 4326)::geography FROM pharmacies p
+    ON CONFLICT (pharmacy_id) DO UPDATE SET "location" = EXCLUDED."location";
+
+    DROP TRIGGER IF EXISTS trg_pharmacies_location ON pharmacies;
+    CREATE TRIGGER trg_pharmacies_location
+        AFTER INSERT OR UPDATE OF latitude, longitude ON pharmacies
+        FOR EACH ROW EXECUTE FUNCTION sync_pharmacy_location();
+
+    RETURN TRUE;
+END
+$$ LANGUAGE plpgsql;
+
+SELECT enable_pharmacy_locations();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_pharmacies_location ON pharmacies;
+DROP TABLE IF EXISTS pharmacy_locations;
+DROP FUNCTION enable_pharmacy_locations;
+DROP FUNCTION sync_pharmacy_location;
+ALTER TABLE pharmacies
+    ALTER COLUMN latitude TYPE REAL,
+    ALTER COLUMN longitude TYPE REAL;
+-- +goose StatementEnd
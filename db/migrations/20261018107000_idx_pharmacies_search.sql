@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Lowercases the text and folds Estonian and other common accented letters
+-- into their base letters, e.g. 'Südameapteek Pärnu' becomes 'sudameapteek parnu'.
+-- Uppercase letters are folded as well, because lower() leaves them be in C locale
+CREATE OR REPLACE FUNCTION fold_estonian(_text TEXT)
+RETURNS TEXT AS $$
+    SELECT translate(
+        lower(_text),
+        'õäöüšžÕÄÖÜŠŽåáàâãéèêëíìîïóòôúùûçčćłńñśźżÅÁÀÂÃÉÈÊËÍÌÎÏÓÒÔÚÙÛÇČĆŁŃÑŚŹŻ',
+        'oaouszoaouszaaaaaeeeeiiiiooouuuccclnnszzaaaaaeeeeiiiiooouuuccclnnszz'
+    )
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+-- Folded text, against which pharmacies are searched
+CREATE OR REPLACE FUNCTION pharmacy_search_text(_chain TEXT, _name TEXT, _address TEXT, _city TEXT, _county TEXT)
+RETURNS TEXT AS $$
+    SELECT fold_estonian(_chain || ' ' || _name || ' ' || _address || ' ' || _city || ' ' || _county)
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+CREATE INDEX idx_pharmacies_search ON pharmacies
+    USING GIN (pharmacy_search_text(chain, "name", "address", city, county) gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_pharmacies_search;
+DROP FUNCTION pharmacy_search_text;
+DROP FUNCTION fold_estonian;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Merged pharmacies are kept as closed tombstones instead of being deleted, so that
+-- the scraper of their chain keeps matching them and doesn't insert them again
+ALTER TABLE pharmacies ADD COLUMN merged_into BIGINT REFERENCES pharmacies(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: pharmacies merged after the migration are deleted, as they were before
+DELETE FROM pharmacies WHERE merged_into IS NOT NULL;
+ALTER TABLE pharmacies DROP COLUMN merged_into;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE revision_action_t ADD VALUE 'merge';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: PostgreSQL does not support removing values from enums,
+-- thus 'merge' revision action is left in place
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rejected matches are looked up for every existing pharmacy on every scrape,
+-- so that the same match wouldn't be queued again after it was rejected
+CREATE INDEX idx_pharmacy_matches_rejected ON pharmacy_matches (candidate_id, chain, pharmacy_id) WHERE status = 'rejected';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_pharmacy_matches_rejected;
+-- +goose StatementEnd
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PharmacyMatchRepository interface {
+	FindPendingMatches() Query[entity.PharmacyMatch]
+	FindMatchByID(id int64) Query[entity.PharmacyMatch]
+	// Finds pending matches of a single scraped pharmacy
+	FindPendingMatchesByPharmacy(chain string, pharmacyID int64) Query[entity.PharmacyMatch]
+	// Queues given matches. Matches, which are already pending,
+	// are refreshed with the latest scraped state instead
+	StoreAll(matches []entity.PharmacyMatch) error
+	Resolve(ids []int64, status entity.PharmacyMatchStatus, resolvedBy string, resolvedAt types.Time) error
+	// Confirms the match and stores the scraped pharmacy over its candidate in a single
+	// transaction. Other pending matches of the scraped pharmacy and of the candidate are
+	// rejected, so that no other scraped pharmacy could take over the candidate later.
+	// Returns ErrScraperRunning if the scraper of the chain is running and
+	// ErrMatchResolved if the match is not pending anymore
+	Confirm(ctx context.Context, match entity.PharmacyMatch, pharmacy entity.Pharmacy, resolvedBy string, resolvedAt types.Time) error
+	Trx(conn any) PharmacyMatchRepository
+}
+
+type PharmacyMatchRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvidePharmacyMatchRepository(conn *sqlx.DB) PharmacyMatchRepository {
+	return PharmacyMatchRepositorySQLX{conn: conn}
+}
+
+func (repo PharmacyMatchRepositorySQLX) FindPendingMatches() Query[entity.PharmacyMatch] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_matches pm
+	WHERE
+		pm.status = 'pending'
+	`
+
+	return &SQLXQuery[entity.PharmacyMatch]{
+		uniqueKey: "id",
+		key:       "created_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      []interface{}{},
+	}
+}
+
+func (repo PharmacyMatchRepositorySQLX) FindMatchByID(id int64) Query[entity.PharmacyMatch] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_matches pm
+	WHERE
+		pm.id = $1
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.PharmacyMatch]{
+		uniqueKey: "id",
+		key:       "created_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyMatchRepositorySQLX) FindPendingMatchesByPharmacy(chain string, pharmacyID int64) Query[entity.PharmacyMatch] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_matches pm
+	WHERE
+		pm.chain = $1
+	AND
+		pm.pharmacy_id = $2
+	AND
+		pm.status = 'pending'
+	`
+
+	args := []interface{}{chain, pharmacyID}
+	return &SQLXQuery[entity.PharmacyMatch]{
+		uniqueKey: "id",
+		key:       "created_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyMatchRepositorySQLX) StoreAll(matches []entity.PharmacyMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	_, err := repo.conn.NamedExec(
+		`INSERT INTO pharmacy_matches (run_id,chain,pharmacy_id,candidate_id,confidence,pharmacy)
+			VALUES (:run_id,:chain,:pharmacy_id,:candidate_id,:confidence,:pharmacy)
+		ON CONFLICT (chain,pharmacy_id,candidate_id) WHERE status = 'pending' DO UPDATE SET
+			run_id = EXCLUDED.run_id,
+			confidence = EXCLUDED.confidence,
+			pharmacy = EXCLUDED.pharmacy`,
+		matches)
+	return err
+}
+
+func (repo PharmacyMatchRepositorySQLX) Resolve(ids []int64, status entity.PharmacyMatchStatus, resolvedBy string, resolvedAt types.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	q, args, err := sqlx.In(
+		`UPDATE pharmacy_matches SET status = ?, resolved_by = ?, resolved_at = ? WHERE id IN (?) AND status = 'pending'`,
+		string(status), resolvedBy, resolvedAt, ids)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.conn.Exec(repo.conn.Rebind(q), args...)
+	return err
+}
+
+func (repo PharmacyMatchRepositorySQLX) Confirm(ctx context.Context, match entity.PharmacyMatch, pharmacy entity.Pharmacy, resolvedBy string, resolvedAt types.Time) error {
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the scraper of the chain is kept from running until the match is confirmed,
+	// so that it wouldn't write the pharmacies the confirmation is about to change
+	kinds := make([]string, 0)
+	err = tx.SelectContext(ctx, &kinds, `SELECT c.scraper_kind FROM chains c WHERE c."name" = $1 AND c.scraper_kind IS NOT NULL`, match.Chain)
+	if err != nil {
+		return err
+	}
+
+	if err := lockScrapers(ctx, tx, kinds); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE pharmacy_matches SET status = $2, resolved_by = $3, resolved_at = $4 WHERE id = $1 AND status = 'pending'`,
+		match.ID, string(entity.PHARMACY_MATCH_CONFIRMED), resolvedBy, resolvedAt)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrMatchResolved
+	}
+
+	if err := storePharmacies(ctx, tx, []entity.Pharmacy{pharmacy}); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE pharmacy_matches SET status = $5, resolved_by = $6, resolved_at = $7
+			WHERE status = 'pending' AND ((chain = $1 AND pharmacy_id = $2) OR candidate_id = $3) AND id <> $4`,
+		match.Chain, match.PharmacyID, match.CandidateID, match.ID, string(entity.PHARMACY_MATCH_REJECTED), resolvedBy, resolvedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo PharmacyMatchRepositorySQLX) Trx(conn any) PharmacyMatchRepository {
+	return PharmacyMatchRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/entity"
+	"pharmafinder/types"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PharmacyMergeRepository interface {
+	FindMerges() Query[entity.PharmacyMerge]
+	// Finds merges into or out of given pharmacy
+	FindMergesByPharmacy(id int64) Query[entity.PharmacyMerge]
+	// Merges the source pharmacy into the target pharmacy in a single transaction. Reviews
+	// and revisions of the source are moved to the target, the merge is recorded as a
+	// revision of the target, the source is kept as a closed tombstone and
+	// its ID redirects to the target from now on. Returns ErrScraperRunning if the scraper
+	// of either chain is running. Returns the audit log entry of the merge
+	Merge(ctx context.Context, sourceID int64, targetID int64, mergedBy string, mergedAt types.Time) (*entity.PharmacyMerge, error)
+	Trx(conn any) PharmacyMergeRepository
+}
+
+type PharmacyMergeRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvidePharmacyMergeRepository(conn *sqlx.DB) PharmacyMergeRepository {
+	return PharmacyMergeRepositorySQLX{conn: conn}
+}
+
+func (repo PharmacyMergeRepositorySQLX) FindMerges() Query[entity.PharmacyMerge] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_merges pm
+	`
+
+	return &SQLXQuery[entity.PharmacyMerge]{
+		uniqueKey: "id",
+		key:       "merged_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      []interface{}{},
+	}
+}
+
+func (repo PharmacyMergeRepositorySQLX) FindMergesByPharmacy(id int64) Query[entity.PharmacyMerge] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_merges pm
+	WHERE
+		pm.source_id = $1
+	OR
+		pm.target_id = $1
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.PharmacyMerge]{
+		uniqueKey: "id",
+		key:       "merged_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyMergeRepositorySQLX) Merge(ctx context.Context, sourceID int64, targetID int64, mergedBy string, mergedAt types.Time) (*entity.PharmacyMerge, error) {
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// scrapers of both chains are kept from running until the merge is committed,
+	// so that they wouldn't write pharmacies the merge is about to change
+	kinds := make([]string, 0)
+	err = tx.SelectContext(
+		ctx,
+		&kinds,
+		`SELECT DISTINCT c.scraper_kind FROM pharmacies p INNER JOIN chains c ON c."name" = p.chain
+			WHERE p.id IN ($1, $2) AND c.scraper_kind IS NOT NULL ORDER BY c.scraper_kind`,
+		sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockScrapers(ctx, tx, kinds); err != nil {
+		return nil, err
+	}
+
+	var source entity.Pharmacy
+	if err := tx.GetContext(ctx, &source, `SELECT p.*, find_pharmacy_phone_numbers(p.id) AS phone_numbers FROM pharmacies p WHERE p.id = $1 AND p.merged_into IS NULL FOR UPDATE`, sourceID); err != nil {
+		return nil, err
+	}
+
+	var target int64
+	if err := tx.GetContext(ctx, &target, `SELECT p.id FROM pharmacies p WHERE p.id = $1 AND p.merged_into IS NULL FOR UPDATE`, targetID); err != nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE pharmacy_reviews SET pharmacy_id = $2 WHERE pharmacy_id = $1`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// the history of the source is carried on by the target
+	if _, err := tx.ExecContext(ctx, `UPDATE pharmacy_revisions SET pharmacy_id = $2 WHERE pharmacy_id = $1`, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	changes := entity.FieldChanges{{Field: "mergedPharmacy", New: strconv.FormatInt(sourceID, 10)}}
+	if err := storeRevision(ctx, tx, targetID, entity.REVISION_ACTION_MERGE, changes); err != nil {
+		return nil, err
+	}
+
+	// pharmacies previously merged into the source now redirect to the target
+	if _, err := tx.ExecContext(ctx, `UPDATE pharmacy_redirects SET target_id = $2 WHERE target_id = $1`, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO pharmacy_redirects (source_id, target_id) VALUES ($1, $2)`, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	// the source is kept as a closed tombstone, which the scraper of its chain keeps matching
+	if _, err := tx.ExecContext(ctx, `UPDATE pharmacies SET merged_into = $2 WHERE merged_into = $1`, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE pharmacies SET merged_into = $2, closed_at = COALESCE(closed_at, $3) WHERE id = $1`, sourceID, targetID, mergedAt); err != nil {
+		return nil, err
+	}
+
+	merge := entity.PharmacyMerge{
+		SourceID: sourceID,
+		TargetID: targetID,
+		Source:   entity.PharmacySnapshot(source),
+		Reviews:  int(reviews),
+		MergedBy: mergedBy,
+		MergedAt: mergedAt,
+	}
+
+	rows, err := tx.NamedQuery(
+		`INSERT INTO pharmacy_merges (source_id,target_id,source,reviews,merged_by,merged_at)
+			VALUES (:source_id,:target_id,:source,:reviews,:merged_by,:merged_at)
+		RETURNING *`,
+		merge)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		_ = rows.StructScan(&merge)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &merge, nil
+}
+
+func (repo PharmacyMergeRepositorySQLX) Trx(conn any) PharmacyMergeRepository {
+	return PharmacyMergeRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
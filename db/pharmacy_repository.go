@@ -1,49 +1,342 @@
 package db
 
 import (
+	"context"
+	"fmt"
 	"pharmafinder/db/dto"
 	"pharmafinder/db/entity"
 	"pharmafinder/types"
+	"pharmafinder/utils"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PharmacyRepository interface {
-	FindPharmaciesInCoordinateBounds(sw types.Point, ne types.Point) Query[entity.Pharmacy]
-	FindPharmaciesByChain(chain entity.PharmacyChain) Query[entity.Pharmacy]
-	FindPharmacyByChainAndPharmacyID(pharmacyID int64, chain entity.PharmacyChain) Query[entity.Pharmacy]
+	FindPharmacies(filter PharmacyFilter) Query[entity.Pharmacy]
+	// Finds pharmacies ordered by their great-circle distance from given point.
+	// If maxMeters is not nil, pharmacies farther than that are left out
+	FindNearestPharmacies(point types.Point, maxMeters *float64, includeClosed bool) Query[dto.PharmacyDistanceDTO]
+	// Finds pharmacies, whose chain, name, address, city or county match all of the
+	// given terms, ranked by similarity. Accents and typos are tolerated. If bounds
+	// are given, pharmacies within them are ranked above the others
+	SearchPharmacies(terms []string, bounds *types.Bounds, includeClosed bool) Query[dto.PharmacySearchResultDTO]
+	// Finds given pharmacy, unless it has been merged into another pharmacy
+	FindPharmacyByID(id int64) Query[entity.Pharmacy]
+	// Finds details of given pharmacy or the pharmacy it was merged into
+	FindPharmacyDetailsByID(id int64) Query[dto.PharmacyDetailsDTO]
+	// Finds pharmacies of chains, which are not populated by any scraper
+	FindIndependentPharmacies() Query[entity.Pharmacy]
+	// Finds all pharmacies of the chain, including closed and merged ones,
+	// along with the source IDs of their rejected matches
+	FindPharmaciesByChain(ctx context.Context, chain string) Query[entity.Pharmacy]
+	FindPharmacyByChainAndPharmacyID(ctx context.Context, pharmacyID int64, chain string) Query[entity.Pharmacy]
+	// Finds ratings of given pharmacy or the pharmacy it was merged into
 	FindPharmacyRatingsByID(id int64) Query[dto.PharmacyRatingDTO]
 	FindPharmacyRatings(sw types.Point, ne types.Point) Query[dto.PharmacyTierRatingDTO]
-	StoreAll(pharmacies []entity.Pharmacy) error
+	StoreAll(ctx context.Context, pharmacies []entity.Pharmacy) error
+	// Marks pharmacies with given IDs as closed. Closed pharmacies
+	// are reopened by storing them with nil ClosedAt value
+	CloseAll(ctx context.Context, ids []int64, closedAt types.Time) error
+	// Stores pharmacies and marks pharmacies with given IDs as closed
+	// within a single transaction, so that either all or none are written
+	SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error
 	Trx(conn any) PharmacyRepository
 }
 
+type PharmacySort string
+
+const (
+	PHARMACY_SORT_ID      PharmacySort = ""
+	PHARMACY_SORT_NAME    PharmacySort = "name"
+	PHARMACY_SORT_CITY    PharmacySort = "city"
+	PHARMACY_SORT_RATING  PharmacySort = "rating"
+	PHARMACY_SORT_REVIEWS PharmacySort = "reviews"
+)
+
+var PHARMACY_SORTS = []PharmacySort{PHARMACY_SORT_ID, PHARMACY_SORT_NAME, PHARMACY_SORT_CITY, PHARMACY_SORT_RATING, PHARMACY_SORT_REVIEWS}
+
+// Criteria of the pharmacy listing. Zero values don't filter anything
+type PharmacyFilter struct {
+	Bounds        *types.Bounds
+	IncludeClosed bool
+	Chains        []string
+	// Settlement and county are matched case and accent insensitively
+	City   string
+	County string
+	// Only pharmacies with reviews of given HRT kind and prescription type are included.
+	// The rating filters then only consider reviews of these kinds
+	HRTKind          string
+	PrescriptionType string
+	// Minimum average rating of the pharmacy
+	MinRating  *float64
+	HasReviews *bool
+	Sort       PharmacySort
+	Desc       bool
+}
+
+func (filter PharmacyFilter) needsReviews() bool {
+	return filter.HRTKind != "" || filter.PrescriptionType != "" || filter.MinRating != nil || filter.HasReviews != nil ||
+		filter.Sort == PHARMACY_SORT_RATING || filter.Sort == PHARMACY_SORT_REVIEWS
+}
+
 type PharmacyRepositorySQLX struct {
 	conn *sqlx.DB
+	// Whether PostGIS backed pharmacy_locations table is available,
+	// otherwise spatial queries fall back to plain latitude/longitude columns
+	spatial bool
 }
 
 func ProvidePharmacyRepository(conn *sqlx.DB) PharmacyRepository {
-	return PharmacyRepositorySQLX{conn: conn}
+	logger := utils.GetLogger("DB")
+	spatial, err := hasPharmacyLocations(conn)
+	if err != nil {
+		logger.Warn().Msgf("Failed to check whether PostGIS pharmacy locations are enabled: %v", err)
+	} else if !spatial {
+		logger.Info().Msg("PostGIS pharmacy locations are not enabled, falling back to plain coordinate queries")
+	}
+
+	return PharmacyRepositorySQLX{conn: conn, spatial: spatial}
 }
 
-func (repo PharmacyRepositorySQLX) FindPharmaciesInCoordinateBounds(sw types.Point, ne types.Point) Query[entity.Pharmacy] {
+// Reports whether pharmacy locations were enabled by enable_pharmacy_locations() function
+func hasPharmacyLocations(conn *sqlx.DB) (bool, error) {
+	var enabled bool
+	err := conn.Get(&enabled, `SELECT to_regclass('pharmacy_locations') IS NOT NULL`)
+	return enabled, err
+}
+
+// Returns condition matching pharmacies p within given bounds along with its values
+func (repo PharmacyRepositorySQLX) boundsCondition(bounds types.Bounds) (string, []interface{}) {
+	if repo.spatial {
+		return `p.id IN (
+			SELECT
+				pl.pharmacy_id
+			FROM
+				pharmacy_locations pl
+			WHERE
+				pl."location"::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)
+		)`, []interface{}{bounds.SW.Lng, bounds.SW.Lat, bounds.NE.Lng, bounds.NE.Lat}
+	}
+
+	return `p.latitude >= ?
+		AND
+			p.longitude >= ?
+		AND
+			p.latitude <= ?
+		AND
+			p.longitude <= ?`, []interface{}{bounds.SW.Lat, bounds.SW.Lng, bounds.NE.Lat, bounds.NE.Lng}
+}
+
+// Pharmacies are filtered by all of the criteria set in the filter
+func (repo PharmacyRepositorySQLX) FindPharmacies(filter PharmacyFilter) Query[entity.Pharmacy] {
+	builder := Select(
+		`p.*`,
+		`find_pharmacy_phone_numbers(p.id) AS phone_numbers`,
+	).From(`pharmacies p`)
+
+	if filter.Bounds != nil {
+		condition, args := repo.boundsCondition(*filter.Bounds)
+		builder.Where(condition, args...)
+	}
+	builder.Where(`p.merged_into IS NULL`)
+	if !filter.IncludeClosed {
+		builder.Where(`p.closed_at IS NULL`)
+	}
+	if len(filter.Chains) > 0 {
+		builder.Where(`p.chain = ANY(?)`, pq.Array(filter.Chains))
+	}
+	if filter.City != "" {
+		builder.Where(`fold_estonian(p.city) = fold_estonian(?)`, filter.City)
+	}
+	if filter.County != "" {
+		builder.Where(`fold_estonian(p.county) = fold_estonian(?)`, filter.County)
+	}
+
+	// review statistics are only aggregated when needed, over reviews of given kinds
+	if filter.needsReviews() {
+		reviews := Select(
+			`AVG(pr.stars) AS avg_rating`,
+			`COUNT(pr.id) AS review_count`,
+		).From(`pharmacy_reviews pr`).Where(`pr.pharmacy_id = p.id`)
+
+		if filter.HRTKind != "" {
+			reviews.Where(`pr.hrt_kind = ?`, filter.HRTKind)
+		}
+		if filter.PrescriptionType != "" {
+			reviews.Where(`pr.prescription_type = ?::prescription_t`, filter.PrescriptionType)
+		}
+
+		subquery, args := reviews.Fragment()
+		builder.Join(`CROSS JOIN LATERAL (`+subquery+`) r`, args...)
+
+		// only pharmacies with reviews of the given kinds are of interest
+		if filter.HRTKind != "" || filter.PrescriptionType != "" {
+			builder.Where(`r.review_count > 0`)
+		}
+		if filter.MinRating != nil {
+			builder.Where(`r.avg_rating >= ?`, *filter.MinRating)
+		}
+		if filter.HasReviews != nil {
+			if *filter.HasReviews {
+				builder.Where(`r.review_count > 0`)
+			} else {
+				builder.Where(`r.review_count = 0`)
+			}
+		}
+	}
+
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	switch filter.Sort {
+	case PHARMACY_SORT_NAME:
+		builder.OrderBy(`p."name" ` + direction)
+	case PHARMACY_SORT_CITY:
+		builder.OrderBy(`p.city ` + direction).OrderBy(`p."name" ` + direction)
+	case PHARMACY_SORT_RATING:
+		builder.OrderBy(`COALESCE(r.avg_rating, 0) ` + direction)
+	case PHARMACY_SORT_REVIEWS:
+		builder.OrderBy(`r.review_count ` + direction)
+	}
+	builder.OrderBy(`p.id ` + direction)
+
+	q, args := builder.Build()
+	return &SQLXQuery[entity.Pharmacy]{
+		uniqueKey: "id",
+		key:       "id",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+// With PostGIS, distances are measured on the WGS 84 spheroid. Otherwise they are computed
+// with the haversine formula on a sphere of 6371 km, which is accurate enough at city scale
+func (repo PharmacyRepositorySQLX) FindNearestPharmacies(point types.Point, maxMeters *float64, includeClosed bool) Query[dto.PharmacyDistanceDTO] {
+	var q string
+	if repo.spatial {
+		q = `
+		SELECT
+			p.*,
+			find_pharmacy_phone_numbers(p.id) AS phone_numbers,
+			ST_Distance(pl."location", ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography) AS distance_meters
+		FROM
+			pharmacies p
+		INNER JOIN
+			pharmacy_locations pl
+		ON
+			pl.pharmacy_id = p.id
+		WHERE
+			p.merged_into IS NULL
+		AND
+			($3 OR p.closed_at IS NULL)
+		`
+		if maxMeters != nil {
+			q += `
+		AND
+			ST_DWithin(pl."location", ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $4)
+			`
+		}
+	} else {
+		q = `
+		SELECT
+			*
+		FROM (
+			SELECT
+				p.*,
+				find_pharmacy_phone_numbers(p.id) AS phone_numbers,
+				2 * 6371000 * ASIN(LEAST(1, SQRT(
+					POWER(SIN(RADIANS(p.latitude - $1::DOUBLE PRECISION) / 2), 2) +
+					COS(RADIANS($1::DOUBLE PRECISION)) * COS(RADIANS(p.latitude)) *
+					POWER(SIN(RADIANS(p.longitude - $2::DOUBLE PRECISION) / 2), 2)
+				))) AS distance_meters
+			FROM
+				pharmacies p
+			WHERE
+				p.merged_into IS NULL
+			AND
+				($3 OR p.closed_at IS NULL)
+		) d
+		`
+		if maxMeters != nil {
+			q += `
+		WHERE
+			d.distance_meters <= $4
+			`
+		}
+	}
+
+	args := []interface{}{point.Lat, point.Lng, includeClosed}
+	if maxMeters != nil {
+		args = append(args, *maxMeters)
+	}
+
+	return &SQLXQuery[dto.PharmacyDistanceDTO]{
+		uniqueKey: "id",
+		key:       "distance_meters",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyRepositorySQLX) SearchPharmacies(terms []string, bounds *types.Bounds, includeClosed bool) Query[dto.PharmacySearchResultDTO] {
+	const searchText = `pharmacy_search_text(p.chain, p."name", p."address", p.city, p.county)`
+
+	builder := Select(
+		`p.*`,
+		`find_pharmacy_phone_numbers(p.id) AS phone_numbers`,
+	).From(`pharmacies p`).Where(`p.merged_into IS NULL`)
+
+	if !includeClosed {
+		builder.Where(`p.closed_at IS NULL`)
+	}
+
+	similarities := make([]string, len(terms))
+	rankArgs := make([]interface{}, 0, len(terms)+4)
+	for i, term := range terms {
+		// short terms have too few trigrams to be matched by similarity
+		builder.Where(searchText+` LIKE '%' || fold_estonian(?) || '%' OR fold_estonian(?) <% `+searchText, term, term)
+		similarities[i] = `word_similarity(fold_estonian(?), ` + searchText + `)`
+		rankArgs = append(rankArgs, term)
+	}
+
+	rank := strings.Join(similarities, " + ")
+	if bounds != nil {
+		condition, args := repo.boundsCondition(*bounds)
+		rank = fmt.Sprintf(`%s + (CASE WHEN %s THEN %d ELSE 0 END)`, rank, condition, len(terms))
+		rankArgs = append(rankArgs, args...)
+	}
+	builder.Column(rank+` AS "rank"`, rankArgs...)
+
+	q, args := builder.Build()
+	return &SQLXQuery[dto.PharmacySearchResultDTO]{
+		uniqueKey: "id",
+		key:       "rank",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyRepositorySQLX) FindPharmacyByID(id int64) Query[entity.Pharmacy] {
 	q := `
 	SELECT
-		*
+		p.*,
+		find_pharmacy_phone_numbers(p.id) AS phone_numbers
 	FROM
 		pharmacies p
 	WHERE
-		p.latitude >= $1
+		p.id = $1
 	AND
-		p.longitude >= $2
-	AND
-		p.latitude <= $3
-	AND
-		p.longitude <= $4
+		p.merged_into IS NULL
 	`
 
-	args := []interface{}{sw.Lat, sw.Lng, ne.Lat, ne.Lng}
-
+	args := []interface{}{id}
 	return &SQLXQuery[entity.Pharmacy]{
 		uniqueKey: "id",
 		key:       "id",
@@ -53,18 +346,103 @@ func (repo PharmacyRepositorySQLX) FindPharmaciesInCoordinateBounds(sw types.Poi
 	}
 }
 
-func (repo PharmacyRepositorySQLX) FindPharmaciesByChain(chain entity.PharmacyChain) Query[entity.Pharmacy] {
+func (repo PharmacyRepositorySQLX) FindPharmacyDetailsByID(id int64) Query[dto.PharmacyDetailsDTO] {
 	q := `
 	SELECT
-		*
+		p.*,
+		find_pharmacy_phone_numbers(p.id) AS phone_numbers,
+		c.display_name AS chain_display_name,
+		c.website AS chain_website,
+		c.logo_url AS chain_logo_url,
+		COALESCE(r.avg_rating, 0) AS avg_rating,
+		COALESCE(r.avg_e_rating, 0) AS avg_e_rating,
+		COALESCE(r.avg_t_rating, 0) AS avg_t_rating,
+		r.review_count,
+		r.last_review_at
+	FROM
+		pharmacies p
+	INNER JOIN
+		chains c
+	ON
+		c."name" = p.chain
+	CROSS JOIN LATERAL (
+		SELECT
+			AVG(pr.stars) AS avg_rating,
+			AVG(pr.stars) FILTER (WHERE pr.hrt_kind = 'e') AS avg_e_rating,
+			AVG(pr.stars) FILTER (WHERE pr.hrt_kind = 't') AS avg_t_rating,
+			COUNT(pr.id) AS review_count,
+			MAX(pr.created_at) AS last_review_at
+		FROM
+			pharmacy_reviews pr
+		WHERE
+			pr.pharmacy_id = p.id
+	) r
+	WHERE
+		p.id = resolve_pharmacy_id($1)
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[dto.PharmacyDetailsDTO]{
+		uniqueKey: "id",
+		key:       "id",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyRepositorySQLX) FindIndependentPharmacies() Query[entity.Pharmacy] {
+	q := `
+	SELECT
+		p.*,
+		find_pharmacy_phone_numbers(p.id) AS phone_numbers
+	FROM
+		pharmacies p
+	INNER JOIN
+		chains c
+	ON
+		c."name" = p.chain
+	WHERE
+		c.scraper_kind IS NULL
+	AND
+		p.merged_into IS NULL
+	`
+
+	return &SQLXQuery[entity.Pharmacy]{
+		uniqueKey: "id",
+		key:       "id",
+		trx:       repo.conn,
+		q:         q,
+		args:      []interface{}{},
+	}
+}
+
+func (repo PharmacyRepositorySQLX) FindPharmaciesByChain(ctx context.Context, chain string) Query[entity.Pharmacy] {
+	q := `
+	SELECT
+		p.*,
+		find_pharmacy_phone_numbers(p.id) AS phone_numbers,
+		ARRAY(
+			SELECT
+				pm.pharmacy_id
+			FROM
+				pharmacy_matches pm
+			WHERE
+				pm.candidate_id = p.id
+			AND
+				pm.chain = p.chain
+			AND
+				pm.status = 'rejected'
+		) AS rejected_matches
 	FROM
 		pharmacies p
 	WHERE
 		p.chain = $1
 	`
 
-	args := []interface{}{string(chain)}
+	args := []interface{}{chain}
 	return &SQLXQuery[entity.Pharmacy]{
+		ctx:       ctx,
 		uniqueKey: "id",
 		key:       "id",
 		trx:       repo.conn,
@@ -73,10 +451,11 @@ func (repo PharmacyRepositorySQLX) FindPharmaciesByChain(chain entity.PharmacyCh
 	}
 }
 
-func (repo PharmacyRepositorySQLX) FindPharmacyByChainAndPharmacyID(pharmacyID int64, chain entity.PharmacyChain) Query[entity.Pharmacy] {
+func (repo PharmacyRepositorySQLX) FindPharmacyByChainAndPharmacyID(ctx context.Context, pharmacyID int64, chain string) Query[entity.Pharmacy] {
 	q := `
 	SELECT
-		*
+		p.*,
+		find_pharmacy_phone_numbers(p.id) AS phone_numbers
 	FROM
 		pharmacies p
 	WHERE
@@ -85,8 +464,9 @@ func (repo PharmacyRepositorySQLX) FindPharmacyByChainAndPharmacyID(pharmacyID i
 		p.chain = $2
 	`
 
-	args := []interface{}{pharmacyID, string(chain)}
+	args := []interface{}{pharmacyID, chain}
 	return &SQLXQuery[entity.Pharmacy]{
+		ctx:       ctx,
 		uniqueKey: "id",
 		key:       "pharmacy_id",
 		trx:       repo.conn,
@@ -96,7 +476,7 @@ func (repo PharmacyRepositorySQLX) FindPharmacyByChainAndPharmacyID(pharmacyID i
 }
 
 func (repo PharmacyRepositorySQLX) FindPharmacyRatingsByID(id int64) Query[dto.PharmacyRatingDTO] {
-	q := `SELECT * FROM find_pharmacy_ratings($1)`
+	q := `SELECT * FROM find_pharmacy_ratings(resolve_pharmacy_id($1))`
 
 	args := []interface{}{id}
 	return &SQLXQuery[dto.PharmacyRatingDTO]{
@@ -109,6 +489,7 @@ func (repo PharmacyRepositorySQLX) FindPharmacyRatingsByID(id int64) Query[dto.P
 }
 
 func (repo PharmacyRepositorySQLX) FindPharmacyRatings(sw types.Point, ne types.Point) Query[dto.PharmacyTierRatingDTO] {
+	condition, args := repo.boundsCondition(types.Bounds{SW: sw, NE: ne})
 	q := `SELECT
 			p.id,
 			p."name",
@@ -134,13 +515,9 @@ func (repo PharmacyRepositorySQLX) FindPharmacyRatings(sw types.Point, ne types.
 		AND
 			tpr.hrt_kind = 't'
 		WHERE
-			p.latitude >= $1
-		AND
-			p.latitude <= $2
-		AND
-			p.longitude >= $3
+			p.merged_into IS NULL
 		AND
-			p.longitude <= $4
+			` + condition + `
 		GROUP BY
 			p.id,
 			p."name"
@@ -150,24 +527,80 @@ func (repo PharmacyRepositorySQLX) FindPharmacyRatings(sw types.Point, ne types.
 			COALESCE(AVG(tpr."stars"), 0) DESC,
 			p."name"`
 
-	args := []interface{}{sw.Lat, ne.Lat, sw.Lng, ne.Lng}
-
 	return &SQLXQuery[dto.PharmacyTierRatingDTO]{
 		uniqueKey: "id",
 		key:       "name",
 		trx:       repo.conn,
-		q:         q,
+		q:         sqlx.Rebind(sqlx.DOLLAR, q),
 		args:      args,
 	}
 }
 
-func (repo PharmacyRepositorySQLX) StoreAll(pharmacies []entity.Pharmacy) error {
-	// Separate entities which shall be inserted
-	// and entities which shall be updated
-	toInsert := make([]entity.Pharmacy, 0)
-	for _, entity := range pharmacies {
-		if entity.ID != 0 {
-			_, err := repo.conn.NamedExec(
+// Pharmacies are stored within a single transaction along with their revisions,
+// which are attributed to the revision origin of the context
+func (repo PharmacyRepositorySQLX) StoreAll(ctx context.Context, pharmacies []entity.Pharmacy) error {
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := storePharmacies(ctx, tx, pharmacies); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo PharmacyRepositorySQLX) CloseAll(ctx context.Context, ids []int64, closedAt types.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := closePharmacies(ctx, tx, ids, closedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo PharmacyRepositorySQLX) SyncAll(ctx context.Context, pharmacies []entity.Pharmacy, closeIDs []int64, closedAt types.Time) error {
+	tx, err := repo.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := storePharmacies(ctx, tx, pharmacies); err != nil {
+		return err
+	}
+
+	if len(closeIDs) > 0 {
+		if err := closePharmacies(ctx, tx, closeIDs, closedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Inserts new pharmacies and updates existing ones along with their revisions
+func storePharmacies(ctx context.Context, tx *sqlx.Tx, pharmacies []entity.Pharmacy) error {
+	for _, pharmacy := range pharmacies {
+		if pharmacy.ID != 0 {
+			var old entity.Pharmacy
+			if err := tx.GetContext(ctx, &old, `SELECT p.*, find_pharmacy_phone_numbers(p.id) AS phone_numbers FROM pharmacies p WHERE p.id = $1 FOR UPDATE`, pharmacy.ID); err != nil {
+				return err
+			}
+
+			_, err := tx.NamedExecContext(
+				ctx,
 				`UPDATE pharmacies SET
 					pharmacy_id = :pharmacy_id,
 					chain = :chain,
@@ -177,32 +610,102 @@ func (repo PharmacyRepositorySQLX) StoreAll(pharmacies []entity.Pharmacy) error
 					county = :county,
 					postal_code = :postal_code,
 					email = :email,
-					phone_number = :phone_number,
 					mod_time = :mod_time,
 					latitude = :latitude,
-					longitude = :longitude
+					longitude = :longitude,
+					opening_hours = :opening_hours,
+					closed_at = :closed_at
 				WHERE
 					id = :id
-				`, entity)
+				`, pharmacy)
 			if err != nil {
 				return err
 			}
-		} else {
-			toInsert = append(toInsert, entity)
+
+			if err := storePhoneNumbers(ctx, tx, pharmacy.ID, pharmacy.PhoneNumbers); err != nil {
+				return err
+			}
+
+			if changes := entity.DiffPharmacies(&old, &pharmacy); len(changes) > 0 {
+				if err := storeRevision(ctx, tx, pharmacy.ID, entity.REVISION_ACTION_UPDATE, changes); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		rows, err := sqlx.NamedQueryContext(
+			ctx,
+			tx,
+			`INSERT INTO pharmacies (pharmacy_id,chain,"name","address",city,county,postal_code,email,mod_time,latitude,longitude,opening_hours,closed_at)
+				VALUES (:pharmacy_id,:chain,:name,:address,:city,:county,:postal_code,:email,:mod_time,:latitude,:longitude,:opening_hours,:closed_at)
+			RETURNING id`,
+			pharmacy)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			err = rows.Scan(&pharmacy.ID)
+		}
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if err := storePhoneNumbers(ctx, tx, pharmacy.ID, pharmacy.PhoneNumbers); err != nil {
+			return err
+		}
+
+		if err := storeRevision(ctx, tx, pharmacy.ID, entity.REVISION_ACTION_INSERT, entity.DiffPharmacies(nil, &pharmacy)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replaces phone numbers of given pharmacy, keeping their order
+func storePhoneNumbers(ctx context.Context, tx *sqlx.Tx, pharmacyID int64, numbers entity.PhoneNumbers) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pharmacy_phone_numbers WHERE pharmacy_id = $1`, pharmacyID); err != nil {
+		return err
+	}
+
+	for i, number := range numbers {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO pharmacy_phone_numbers (pharmacy_id, position, "number", "type") VALUES ($1, $2, $3, $4)`,
+			pharmacyID, i, number.Number, number.Type)
+		if err != nil {
+			return err
 		}
 	}
 
-	if len(toInsert) > 0 {
-		_, err := repo.conn.NamedExec(
-			`INSERT INTO pharmacies (pharmacy_id,chain,"name","address",city,county,postal_code,email,phone_number,mod_time,latitude,longitude)
-				VALUES (:pharmacy_id,:chain,:name,:address,:city,:county,:postal_code,:email,:phone_number,:mod_time,:latitude,:longitude)`,
-			toInsert)
+	return nil
+}
+
+// Closes open pharmacies with given IDs along with their revisions
+func closePharmacies(ctx context.Context, tx *sqlx.Tx, ids []int64, closedAt types.Time) error {
+	q, args, err := sqlx.In(`UPDATE pharmacies SET closed_at = ? WHERE id IN (?) AND closed_at IS NULL RETURNING id`, closedAt, ids)
+	if err != nil {
+		return err
+	}
+
+	closed := make([]int64, 0)
+	if err := tx.SelectContext(ctx, &closed, tx.Rebind(q), args...); err != nil {
 		return err
 	}
 
+	changes := entity.DiffPharmacies(&entity.Pharmacy{}, &entity.Pharmacy{ClosedAt: &closedAt})
+	for _, id := range closed {
+		if err := storeRevision(ctx, tx, id, entity.REVISION_ACTION_CLOSE, changes); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (repo PharmacyRepositorySQLX) Trx(conn any) PharmacyRepository {
-	return PharmacyRepositorySQLX{conn: conn.(*sqlx.DB)}
+	return PharmacyRepositorySQLX{conn: conn.(*sqlx.DB), spatial: repo.spatial}
 }
@@ -6,6 +6,8 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// Pharmacy IDs are resolved through redirects of merged pharmacies,
+// so that reviews can still be accessed with the ID of a merged pharmacy
 type PharmacyReviewRepository interface {
 	FindReviewForPharmacy(id int64) Query[entity.PharmacyReview]
 	FindReviewByID(pharmaID int64, reviewID int64) Query[entity.PharmacyReview]
@@ -29,7 +31,7 @@ func (repo PharmacyReviewRepositorySQLX) FindReviewForPharmacy(id int64) Query[e
 	FROM
 		pharmacy_reviews pr
 	WHERE
-		pr.pharmacy_id = $1
+		pr.pharmacy_id = resolve_pharmacy_id($1)
 	`
 
 	args := []interface{}{id}
@@ -50,7 +52,7 @@ func (repo PharmacyReviewRepositorySQLX) FindReviewByID(pharmaID int64, reviewID
 	FROM
 		pharmacy_reviews pr
 	WHERE
-		pr.pharmacy_id = $1
+		pr.pharmacy_id = resolve_pharmacy_id($1)
 	AND
 		pr.id = $2
 	`
@@ -86,7 +88,7 @@ func (repo PharmacyReviewRepositorySQLX) Store(review *entity.PharmacyReview) er
 
 	rows, err := repo.conn.NamedQuery(
 		`INSERT INTO pharmacy_reviews (pharmacy_id,prescription_type,stars,hrt_kind,nationality,review,created_at,updated_at,modification_code)
-			VALUES (:pharmacy_id,:prescription_type,:stars,:hrt_kind,:nationality,:review,:created_at,:updated_at,:modification_code)
+			VALUES (resolve_pharmacy_id(:pharmacy_id),:prescription_type,:stars,:hrt_kind,:nationality,:review,:created_at,:updated_at,:modification_code)
 		RETURNING *`,
 		review)
 
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Origin of changes made to pharmacies, which is recorded into their revision history
+type RevisionOrigin struct {
+	Source entity.RevisionSource
+	// Name of the scraper, admin or imported file
+	Name string
+	// Scrape run making the changes, if any
+	RunID *int64
+}
+
+type revisionOriginKey struct{}
+
+// Returns a copy of the context, which attributes pharmacy
+// changes made with it to the given origin
+func WithRevisionOrigin(ctx context.Context, origin RevisionOrigin) context.Context {
+	return context.WithValue(ctx, revisionOriginKey{}, origin)
+}
+
+// Returns the origin of pharmacy changes made with given context
+func RevisionOriginFromContext(ctx context.Context) RevisionOrigin {
+	if origin, ok := ctx.Value(revisionOriginKey{}).(RevisionOrigin); ok {
+		return origin
+	}
+	return RevisionOrigin{Source: entity.REVISION_SOURCE_UNKNOWN}
+}
+
+type PharmacyRevisionRepository interface {
+	// Finds revisions of given pharmacy or the pharmacy it was merged into
+	FindRevisionsByPharmacy(id int64) Query[entity.PharmacyRevision]
+	Trx(conn any) PharmacyRevisionRepository
+}
+
+type PharmacyRevisionRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvidePharmacyRevisionRepository(conn *sqlx.DB) PharmacyRevisionRepository {
+	return PharmacyRevisionRepositorySQLX{conn: conn}
+}
+
+func (repo PharmacyRevisionRepositorySQLX) FindRevisionsByPharmacy(id int64) Query[entity.PharmacyRevision] {
+	q := `
+	SELECT
+		*
+	FROM
+		pharmacy_revisions pr
+	WHERE
+		pr.pharmacy_id = resolve_pharmacy_id($1)
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.PharmacyRevision]{
+		uniqueKey: "id",
+		key:       "created_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PharmacyRevisionRepositorySQLX) Trx(conn any) PharmacyRevisionRepository {
+	return PharmacyRevisionRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
+
+// Records a revision of pharmacy with given ID within the transaction, which made the change.
+// The origin of the revision is taken from the context
+func storeRevision(ctx context.Context, tx *sqlx.Tx, pharmacyID int64, action entity.RevisionAction, changes entity.FieldChanges) error {
+	origin := RevisionOriginFromContext(ctx)
+	var name *string
+	if origin.Name != "" {
+		name = &origin.Name
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO pharmacy_revisions (pharmacy_id,action,changes,source,source_name,run_id) VALUES ($1,$2,$3,$4,$5,$6)`,
+		pharmacyID, string(action), changes, string(origin.Source), name, origin.RunID)
+	return err
+}
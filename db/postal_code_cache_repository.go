@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"pharmafinder/db/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PostalCodeCacheRepository interface {
+	FindByAddress(ctx context.Context, address string) Query[entity.PostalCodeCacheEntry]
+	// Inserts the entry or replaces an existing entry of the same address
+	Store(ctx context.Context, entry entity.PostalCodeCacheEntry) error
+	Trx(conn any) PostalCodeCacheRepository
+}
+
+type PostalCodeCacheRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvidePostalCodeCacheRepository(conn *sqlx.DB) PostalCodeCacheRepository {
+	return PostalCodeCacheRepositorySQLX{conn: conn}
+}
+
+func (repo PostalCodeCacheRepositorySQLX) FindByAddress(ctx context.Context, address string) Query[entity.PostalCodeCacheEntry] {
+	q := `
+	SELECT
+		*
+	FROM
+		postal_code_cache pcc
+	WHERE
+		pcc."address" = $1
+	`
+
+	args := []interface{}{address}
+	return &SQLXQuery[entity.PostalCodeCacheEntry]{
+		ctx:       ctx,
+		uniqueKey: "address",
+		key:       "resolved_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo PostalCodeCacheRepositorySQLX) Store(ctx context.Context, entry entity.PostalCodeCacheEntry) error {
+	_, err := repo.conn.NamedExecContext(
+		ctx,
+		`INSERT INTO postal_code_cache ("address",postal_code,resolved_at,expires_at)
+			VALUES (:address,:postal_code,:resolved_at,:expires_at)
+		ON CONFLICT ("address") DO UPDATE SET
+			postal_code = EXCLUDED.postal_code,
+			resolved_at = EXCLUDED.resolved_at,
+			expires_at = EXCLUDED.expires_at
+		`, entry)
+	return err
+}
+
+func (repo PostalCodeCacheRepositorySQLX) Trx(conn any) PostalCodeCacheRepository {
+	return PostalCodeCacheRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
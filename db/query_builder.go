@@ -0,0 +1,130 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQL fragment along with the values of its ? placeholders
+type sqlFragment struct {
+	sql  string
+	args []interface{}
+}
+
+// SelectBuilder composes SELECT statements from optional parts. Values are
+// never written into SQL, instead every fragment refers to its values with
+// ? placeholders, which are numbered in the order they appear in the
+// statement once the statement is built. Fragments must thus not contain
+// question marks for any other purpose (e.g. JSONB ? operators)
+type SelectBuilder struct {
+	columns    []sqlFragment
+	from       string
+	joins      []sqlFragment
+	conditions []sqlFragment
+	groupBy    []string
+	orderBy    []sqlFragment
+}
+
+func Select(columns ...string) *SelectBuilder {
+	builder := &SelectBuilder{}
+	for _, column := range columns {
+		builder.Column(column)
+	}
+	return builder
+}
+
+// Adds a column expression to the selected columns
+func (builder *SelectBuilder) Column(column string, args ...interface{}) *SelectBuilder {
+	builder.columns = append(builder.columns, sqlFragment{sql: column, args: args})
+	return builder
+}
+
+func (builder *SelectBuilder) From(from string) *SelectBuilder {
+	builder.from = from
+	return builder
+}
+
+// Adds a join clause, e.g. "LEFT JOIN chains c ON c.name = p.chain"
+func (builder *SelectBuilder) Join(join string, args ...interface{}) *SelectBuilder {
+	builder.joins = append(builder.joins, sqlFragment{sql: join, args: args})
+	return builder
+}
+
+// Adds a condition, which is combined with other conditions with AND
+func (builder *SelectBuilder) Where(condition string, args ...interface{}) *SelectBuilder {
+	builder.conditions = append(builder.conditions, sqlFragment{sql: condition, args: args})
+	return builder
+}
+
+func (builder *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	builder.groupBy = append(builder.groupBy, columns...)
+	return builder
+}
+
+// Adds an ordering expression including its direction, e.g. "p.name DESC"
+func (builder *SelectBuilder) OrderBy(expression string, args ...interface{}) *SelectBuilder {
+	builder.orderBy = append(builder.orderBy, sqlFragment{sql: expression, args: args})
+	return builder
+}
+
+// Returns the statement with $n placeholders and its arguments. Panics if
+// the number of placeholders of a fragment doesn't match its values
+func (builder *SelectBuilder) Build() (string, []interface{}) {
+	q, args := builder.Fragment()
+	return sqlx.Rebind(sqlx.DOLLAR, q), args
+}
+
+// Returns the statement with ? placeholders and its arguments,
+// so that it could be embedded into another statement as a subquery
+func (builder *SelectBuilder) Fragment() (string, []interface{}) {
+	var sb strings.Builder
+	args := make([]interface{}, 0)
+
+	write := func(fragments []sqlFragment, separator string) {
+		for i, fragment := range fragments {
+			if n := strings.Count(fragment.sql, "?"); n != len(fragment.args) {
+				panic(fmt.Errorf("SQL fragment '%s' has %d placeholders, but %d values", fragment.sql, n, len(fragment.args)))
+			}
+
+			if i > 0 {
+				sb.WriteString(separator)
+			}
+			sb.WriteString(fragment.sql)
+			args = append(args, fragment.args...)
+		}
+	}
+
+	sb.WriteString("SELECT\n\t")
+	write(builder.columns, ",\n\t")
+	sb.WriteString("\nFROM\n\t")
+	sb.WriteString(builder.from)
+
+	if len(builder.joins) > 0 {
+		sb.WriteString("\n")
+		write(builder.joins, "\n")
+	}
+
+	if len(builder.conditions) > 0 {
+		conditions := make([]sqlFragment, len(builder.conditions))
+		for i, condition := range builder.conditions {
+			conditions[i] = sqlFragment{sql: "(" + condition.sql + ")", args: condition.args}
+		}
+
+		sb.WriteString("\nWHERE\n\t")
+		write(conditions, "\nAND\n\t")
+	}
+
+	if len(builder.groupBy) > 0 {
+		sb.WriteString("\nGROUP BY\n\t")
+		sb.WriteString(strings.Join(builder.groupBy, ",\n\t"))
+	}
+
+	if len(builder.orderBy) > 0 {
+		sb.WriteString("\nORDER BY\n\t")
+		write(builder.orderBy, ",\n\t")
+	}
+
+	return sb.String(), args
+}
@@ -0,0 +1,54 @@
+package db_test
+
+import (
+	"pharmafinder/db"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder_Build(t *testing.T) {
+	reviews := db.Select(`COUNT(pr.id) AS review_count`).
+		From(`pharmacy_reviews pr`).
+		Where(`pr.pharmacy_id = p.id`).
+		Where(`pr.hrt_kind = ?`, "e")
+	subquery, subqueryArgs := reviews.Fragment()
+
+	q, args := db.Select(`p.*`).
+		Column(`p.latitude - ? AS lat_offset`, 59.4).
+		From(`pharmacies p`).
+		Join(`CROSS JOIN LATERAL (`+subquery+`) r`, subqueryArgs...).
+		Where(`p.chain = ? OR p.city = ?`, "Benu", "Tartu").
+		Where(`r.review_count > ?`, 0).
+		OrderBy(`p."name" DESC`).
+		OrderBy(`p.id DESC`).
+		Build()
+
+	assert.Equal(t, `SELECT
+	p.*,
+	p.latitude - $1 AS lat_offset
+FROM
+	pharmacies p
+CROSS JOIN LATERAL (SELECT
+	COUNT(pr.id) AS review_count
+FROM
+	pharmacy_reviews pr
+WHERE
+	(pr.pharmacy_id = p.id)
+AND
+	(pr.hrt_kind = $2)) r
+WHERE
+	(p.chain = $3 OR p.city = $4)
+AND
+	(r.review_count > $5)
+ORDER BY
+	p."name" DESC,
+	p.id DESC`, q)
+	assert.Equal(t, []interface{}{59.4, "e", "Benu", "Tartu", 0}, args)
+}
+
+func TestSelectBuilder_PlaceholderMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		db.Select(`p.*`).From(`pharmacies p`).Where(`p.chain = ?`).Build()
+	})
+}
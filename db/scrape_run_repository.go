@@ -0,0 +1,156 @@
+package db
+
+import (
+	"pharmafinder/db/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ScrapeRunRepository interface {
+	FindScrapeRuns() Query[entity.ScrapeRun]
+	FindScrapeRunsByScraper(scraper string) Query[entity.ScrapeRun]
+	FindScrapeRunByID(id int64) Query[entity.ScrapeRun]
+	FindScrapeRunChanges(runID int64) Query[entity.ScrapeRunChange]
+	Store(run *entity.ScrapeRun) error
+	StoreChanges(changes []entity.ScrapeRunChange) error
+	Trx(conn any) ScrapeRunRepository
+}
+
+type ScrapeRunRepositorySQLX struct {
+	conn *sqlx.DB
+}
+
+func ProvideScrapeRunRepository(conn *sqlx.DB) ScrapeRunRepository {
+	return ScrapeRunRepositorySQLX{conn: conn}
+}
+
+func (repo ScrapeRunRepositorySQLX) FindScrapeRuns() Query[entity.ScrapeRun] {
+	q := `
+	SELECT
+		*
+	FROM
+		scrape_runs sr
+	`
+
+	return &SQLXQuery[entity.ScrapeRun]{
+		uniqueKey: "id",
+		key:       "started_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      []interface{}{},
+	}
+}
+
+func (repo ScrapeRunRepositorySQLX) FindScrapeRunsByScraper(scraper string) Query[entity.ScrapeRun] {
+	q := `
+	SELECT
+		*
+	FROM
+		scrape_runs sr
+	WHERE
+		sr.scraper = $1
+	`
+
+	args := []interface{}{scraper}
+	return &SQLXQuery[entity.ScrapeRun]{
+		uniqueKey: "id",
+		key:       "started_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ScrapeRunRepositorySQLX) FindScrapeRunByID(id int64) Query[entity.ScrapeRun] {
+	q := `
+	SELECT
+		*
+	FROM
+		scrape_runs sr
+	WHERE
+		sr.id = $1
+	`
+
+	args := []interface{}{id}
+	return &SQLXQuery[entity.ScrapeRun]{
+		uniqueKey: "id",
+		key:       "started_at",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ScrapeRunRepositorySQLX) FindScrapeRunChanges(runID int64) Query[entity.ScrapeRunChange] {
+	q := `
+	SELECT
+		*
+	FROM
+		scrape_run_changes src
+	WHERE
+		src.run_id = $1
+	`
+
+	args := []interface{}{runID}
+	return &SQLXQuery[entity.ScrapeRunChange]{
+		uniqueKey: "id",
+		key:       "id",
+		trx:       repo.conn,
+		q:         q,
+		args:      args,
+	}
+}
+
+func (repo ScrapeRunRepositorySQLX) Store(run *entity.ScrapeRun) error {
+	if run.ID != 0 {
+		_, err := repo.conn.NamedExec(
+			`UPDATE scrape_runs SET
+				scraper = :scraper,
+				started_at = :started_at,
+				finished_at = :finished_at,
+				outcome = :outcome,
+				error = :error,
+				inserted = :inserted,
+				updated = :updated,
+				unchanged = :unchanged,
+				skipped = :skipped,
+				closed = :closed
+			WHERE
+				id = :id
+			`, run)
+		return err
+	}
+
+	rows, err := repo.conn.NamedQuery(
+		`INSERT INTO scrape_runs (scraper,started_at,finished_at,outcome,error,inserted,updated,unchanged,skipped,closed)
+			VALUES (:scraper,:started_at,:finished_at,:outcome,:error,:inserted,:updated,:unchanged,:skipped,:closed)
+		RETURNING *`,
+		run)
+
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		_ = rows.StructScan(run)
+	}
+
+	return nil
+}
+
+func (repo ScrapeRunRepositorySQLX) StoreChanges(changes []entity.ScrapeRunChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	_, err := repo.conn.NamedExec(
+		`INSERT INTO scrape_run_changes (run_id,pharmacy_id,chain,"name",action,changes)
+			VALUES (:run_id,:pharmacy_id,:chain,:name,:action,:changes)`,
+		changes)
+	return err
+}
+
+func (repo ScrapeRunRepositorySQLX) Trx(conn any) ScrapeRunRepository {
+	return ScrapeRunRepositorySQLX{conn: conn.(*sqlx.DB)}
+}
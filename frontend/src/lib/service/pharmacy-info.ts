@@ -1,5 +1,10 @@
 import type { HttpError } from "$lib/http-error";
 
+export class PhoneNumber {
+    number: string | undefined;
+    type: "landline" | "mobile" | "tollfree" | "unknown" | undefined;
+}
+
 export class PharmacyInfo {
     id: number | undefined;
     chain: string | undefined;
@@ -8,7 +13,7 @@ export class PharmacyInfo {
     city: string | undefined
     county: string | undefined
     postalCode: number | undefined;
-    phoneNumber: string | undefined;
+    phoneNumbers: PhoneNumber[] | undefined;
     email: string | undefined;
     lat: number | undefined;
     lng: number | undefined;
@@ -32,4 +37,41 @@ export async function getPharmacies(): Promise<PharmacyInfo[]> {
             console.log(e);
             return [];
         }) /* TODO: Implement a better error signaling system so that the user can see errors */
-}
\ No newline at end of file
+}
+export class PharmacyDetails extends PharmacyInfo {
+    chainDisplayName: string | undefined;
+    chainWebsite: string | undefined;
+    chainLogoUrl: string | undefined;
+    avgRating: number | undefined;
+    avgERating: number | undefined;
+    avgTRating: number | undefined;
+    reviewCount: number | undefined;
+    lastReviewAt: string | null | undefined;
+}
+
+/**
+ * Retrieve a single pharmacy along with its rating summary
+ *
+ * @param id ID of the pharmacy
+ * @returns a promise to PharmacyDetails or null if the pharmacy doesn't exist
+ */
+export async function getPharmacy(id: number): Promise<PharmacyDetails | null> {
+    return await fetch(`/api/v1/pharmacies/${id}`)
+        .then(async res => {
+            if (res.status == 404)
+                return null;
+
+            if (res.status != 200) {
+                let err: HttpError = await res.json();
+                console.log(err);
+                throw new Error(`Failed to fetch pharmacy ${id}: ${err.msg}`);
+            }
+
+            let data: PharmacyDetails = await res.json()
+            return data
+        })
+        .catch(e => {
+            console.log(e);
+            return null;
+        })
+}
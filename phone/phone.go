@@ -0,0 +1,123 @@
+// Parses phone numbers listed by pharmacy chains into E.164 numbers
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"pharmafinder/db/entity"
+	"regexp"
+	"strings"
+)
+
+// Matches a single phone number within free text. Numbers may be grouped with
+// spaces, dashes, dots or parentheses, while other characters separate numbers
+var numberRegex = regexp.MustCompile(`(?:\+|\b00)?\d[\d \-.()]*\d`)
+
+// Characters used to group the digits of a phone number
+var groupingReplacer = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// Returned when a listed number is not a valid phone number
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Parses all phone numbers listed in given string, e.g. "+372 641 3975 / 5123 4567".
+// Numbers without a country code are assumed to be Estonian. Valid numbers are returned
+// even if some of the listed numbers are invalid, in which case an error is also returned
+func Parse(raw string) (entity.PhoneNumbers, error) {
+	numbers := make(entity.PhoneNumbers, 0)
+	seen := make(map[string]bool)
+	var errs []error
+	for _, match := range numberRegex.FindAllString(raw, -1) {
+		matchNumbers, err := parseNumbers(match)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, number := range matchNumbers {
+			if !seen[number.Number] {
+				seen[number.Number] = true
+				numbers = append(numbers, number)
+			}
+		}
+	}
+
+	return numbers, errors.Join(errs...)
+}
+
+// Parses a matched number. Numbers separated only by spaces, e.g. "641 3975 5123 4567",
+// end up in a single match, which is too long to be a number. Such a match is split into
+// runs of its space separated digit groups, so that every run is a valid number
+func parseNumbers(match string) (entity.PhoneNumbers, error) {
+	number, err := parseNumber(match)
+	if err == nil {
+		return entity.PhoneNumbers{number}, nil
+	}
+
+	if numbers, ok := splitNumbers(strings.Fields(match)); ok {
+		return numbers, nil
+	}
+	return nil, err
+}
+
+// Splits digit groups into consecutive runs of valid numbers, preferring longer runs.
+// Returns false if the groups can't be split so that every group belongs to a number
+func splitNumbers(groups []string) (entity.PhoneNumbers, bool) {
+	if len(groups) == 0 {
+		return entity.PhoneNumbers{}, true
+	}
+
+	for n := len(groups); n > 0; n-- {
+		number, err := parseNumber(strings.Join(groups[:n], " "))
+		if err != nil {
+			continue
+		}
+
+		if rest, ok := splitNumbers(groups[n:]); ok {
+			return append(entity.PhoneNumbers{number}, rest...), true
+		}
+	}
+	return nil, false
+}
+
+// Parses a single phone number into E.164 format
+func parseNumber(raw string) (entity.PhoneNumber, error) {
+	digits := groupingReplacer.Replace(raw)
+	if strings.HasPrefix(digits, "00") {
+		digits = "+" + digits[2:]
+	}
+
+	if !strings.HasPrefix(digits, "+") {
+		// Estonian numbers are sometimes written with the country code but without the plus sign
+		if national := strings.TrimPrefix(digits, "372"); len(digits) > 8 && national != digits {
+			digits = national
+		}
+		return parseEstonianNumber(raw, digits)
+	} else if national, ok := strings.CutPrefix(digits, "+372"); ok {
+		return parseEstonianNumber(raw, national)
+	}
+
+	// E.164 numbers have at most 15 digits, including the country code
+	if len(digits) < 9 || len(digits) > 16 || digits[1] == '0' {
+		return entity.PhoneNumber{}, fmt.Errorf("%w '%s'", ErrInvalidNumber, raw)
+	}
+	return entity.PhoneNumber{Number: digits, Type: entity.PHONE_NUMBER_TYPE_UNKNOWN}, nil
+}
+
+// Parses and classifies an Estonian national number according to the Estonian numbering plan
+func parseEstonianNumber(raw string, national string) (entity.PhoneNumber, error) {
+	number := entity.PhoneNumber{Number: "+372" + national}
+	switch {
+	case strings.HasPrefix(national, "800") && (len(national) == 7 || len(national) == 8):
+		number.Type = entity.PHONE_NUMBER_TYPE_TOLLFREE
+	case strings.HasPrefix(national, "5") && (len(national) == 7 || len(national) == 8):
+		number.Type = entity.PHONE_NUMBER_TYPE_MOBILE
+	case len(national) == 8 && national[0] == '8' && national[1] >= '1' && national[1] <= '4':
+		number.Type = entity.PHONE_NUMBER_TYPE_MOBILE
+	case len(national) == 7 && strings.ContainsRune("3467", rune(national[0])):
+		number.Type = entity.PHONE_NUMBER_TYPE_LANDLINE
+	default:
+		return entity.PhoneNumber{}, fmt.Errorf("%w '%s'", ErrInvalidNumber, raw)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,81 @@
+package phone_test
+
+import (
+	"pharmafinder/db/entity"
+	"pharmafinder/phone"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_EstonianNumbers(t *testing.T) {
+	numbers, err := phone.Parse("+372 641-3975")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{{Number: "+3726413975", Type: entity.PHONE_NUMBER_TYPE_LANDLINE}}, numbers)
+
+	numbers, err = phone.Parse("5123 4567")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{{Number: "+37251234567", Type: entity.PHONE_NUMBER_TYPE_MOBILE}}, numbers)
+
+	numbers, err = phone.Parse("(372) 8282 0101")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{{Number: "+37282820101", Type: entity.PHONE_NUMBER_TYPE_MOBILE}}, numbers)
+
+	numbers, err = phone.Parse("800 1234")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{{Number: "+3728001234", Type: entity.PHONE_NUMBER_TYPE_TOLLFREE}}, numbers)
+}
+
+func TestParse_MultipleNumbers(t *testing.T) {
+	numbers, err := phone.Parse("641 3975 / +372 5123 4567, 6413975")
+
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{
+		{Number: "+3726413975", Type: entity.PHONE_NUMBER_TYPE_LANDLINE},
+		{Number: "+37251234567", Type: entity.PHONE_NUMBER_TYPE_MOBILE},
+	}, numbers)
+}
+
+func TestParse_NumbersSeparatedBySpaces(t *testing.T) {
+	expected := entity.PhoneNumbers{
+		{Number: "+3726413975", Type: entity.PHONE_NUMBER_TYPE_LANDLINE},
+		{Number: "+37251234567", Type: entity.PHONE_NUMBER_TYPE_MOBILE},
+	}
+
+	numbers, err := phone.Parse("641 3975 5123 4567")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, numbers)
+
+	numbers, err = phone.Parse("6413975 51234567")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, numbers)
+
+	numbers, err = phone.Parse("+372 641 3975 +372 5123 4567")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, numbers)
+
+	numbers, err = phone.Parse("641 3975 12")
+	assert.ErrorIs(t, err, phone.ErrInvalidNumber)
+	assert.Empty(t, numbers)
+}
+
+func TestParse_ForeignNumbers(t *testing.T) {
+	numbers, err := phone.Parse("+358 9 123 4567; 00371 6712 3456")
+
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PhoneNumbers{
+		{Number: "+35891234567", Type: entity.PHONE_NUMBER_TYPE_UNKNOWN},
+		{Number: "+37167123456", Type: entity.PHONE_NUMBER_TYPE_UNKNOWN},
+	}, numbers)
+}
+
+func TestParse_InvalidNumbers(t *testing.T) {
+	numbers, err := phone.Parse("1234, 5123 4567")
+
+	assert.ErrorIs(t, err, phone.ErrInvalidNumber)
+	assert.Equal(t, entity.PhoneNumbers{{Number: "+37251234567", Type: entity.PHONE_NUMBER_TYPE_MOBILE}}, numbers)
+
+	numbers, err = phone.Parse("")
+	assert.NoError(t, err)
+	assert.Empty(t, numbers)
+}
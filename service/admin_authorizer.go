@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"pharmafinder/utils"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type AdminAuthorizer interface {
+	// Checks if provided request headers contain a valid admin
+	// bearer token in the Authorization header
+	//
+	// If the token is valid, returns the name of the admin and true,
+	// otherwise empty string and false
+	Authorize(header http.Header) (string, bool)
+}
+
+type AdminAuthorizerImpl struct {
+	// admin name -> token
+	tokens map[string]string
+	logger zerolog.Logger
+}
+
+// Admin tokens are read from ADMIN_TOKENS environment variable,
+// which is a comma separated list of name:token pairs, e.g.
+//
+//	ADMIN_TOKENS=alice:secret1,bob:secret2
+func ProvideAdminAuthorizer() AdminAuthorizer {
+	logger := utils.GetLogger("SERVICE")
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(utils.Getenv("ADMIN_TOKENS", ""), ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || token == "" {
+			continue
+		}
+		tokens[name] = token
+	}
+
+	if len(tokens) == 0 {
+		logger.Warn().Msg("No admin tokens configured, admin endpoints are inaccessible")
+	}
+
+	return AdminAuthorizerImpl{
+		tokens: tokens,
+		logger: logger,
+	}
+}
+
+func (authorizer AdminAuthorizerImpl) Authorize(header http.Header) (string, bool) {
+	token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	for name, expected := range authorizer.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return name, true
+		}
+	}
+
+	authorizer.logger.Warn().Msg("Invalid admin token provided")
+	return "", false
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuthorizer_ValidToken(t *testing.T) {
+	os.Setenv("ADMIN_TOKENS", "alice:secret1, bob:secret2")
+
+	authorizer := ProvideAdminAuthorizer()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret2")
+
+	name, ok := authorizer.Authorize(header)
+	assert.True(t, ok)
+	assert.Equal(t, "bob", name)
+
+	os.Clearenv()
+}
+
+func TestAdminAuthorizer_InvalidToken(t *testing.T) {
+	os.Setenv("ADMIN_TOKENS", "alice:secret1")
+
+	authorizer := ProvideAdminAuthorizer()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret2")
+
+	_, ok := authorizer.Authorize(header)
+	assert.False(t, ok)
+
+	header.Set("Authorization", "secret1")
+	_, ok = authorizer.Authorize(header)
+	assert.False(t, ok)
+
+	os.Clearenv()
+}
+
+func TestAdminAuthorizer_NoTokensConfigured(t *testing.T) {
+	os.Setenv("ADMIN_TOKENS", "")
+
+	authorizer := ProvideAdminAuthorizer()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer ")
+
+	_, ok := authorizer.Authorize(header)
+	assert.False(t, ok)
+
+	os.Clearenv()
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,11 +10,13 @@ import (
 	"pharmafinder/utils"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
 const RECAPTCHA_VERIFY_ENDPOINT = "https://www.google.com/recaptcha/api/siteverify"
+const RECAPTCHA_VERIFY_TIMEOUT = 10 * time.Second
 
 type RecaptchaVerifier interface {
 	// Verify if provided grecaptcha response is valid by making an
@@ -53,18 +56,24 @@ func (verifier RecaptchaVerifierImpl) Verify(response string) bool {
 		Response: response,
 	}
 
-	req, err := http.NewRequest("POST", RECAPTCHA_VERIFY_ENDPOINT, bytes.NewReader([]byte(fmt.Sprintf("secret=%s&response=%s", reqBody.Secret, reqBody.Response))))
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	// verification is done while handling a request, thus it must
+	// not take longer than the request itself, retries included
+	ctx, cancel := context.WithTimeout(context.Background(), RECAPTCHA_VERIFY_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", RECAPTCHA_VERIFY_ENDPOINT, bytes.NewReader([]byte(fmt.Sprintf("secret=%s&response=%s", reqBody.Secret, reqBody.Response))))
 	if err != nil {
 		verifier.logger.Error().Msgf("Failed to create a new http.Request instance for verifying reCaptcha response: %v", err)
 		return false
 	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := verifier.client.Do(req)
 	if err != nil {
 		verifier.logger.Error().Msgf("Failed to make a request to %s: %v", RECAPTCHA_VERIFY_ENDPOINT, err)
 		return false
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		verifier.logger.Error().Msgf("ReCaptcha verification endpoint returned non-200 status code %d", resp.StatusCode)
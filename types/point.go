@@ -2,6 +2,12 @@ package types
 
 // Represents a geographical point on the map
 type Point struct {
-	Lat float32 `json:"lat"`
-	Lng float32 `json:"lng"`
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Represents a rectangular area on the map
+type Bounds struct {
+	SW Point `json:"sw"`
+	NE Point `json:"ne"`
 }
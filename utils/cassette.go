@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Version of the cassette file format
+const CASSETTE_VERSION = 1
+
+type CassetteMode int
+
+const (
+	// Requests are answered from the cassette without any network access
+	CASSETTE_REPLAY CassetteMode = iota
+	// Requests are made with the wrapped client and recorded into the cassette
+	CASSETTE_RECORD
+)
+
+// Response headers, which are never recorded
+var cassetteSkippedHeaders = []string{"Set-Cookie", "Date"}
+
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type CassetteResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	// Path of a file containing the response body relative to the
+	// cassette file. Useful for hand-crafted cassettes with large bodies
+	BodyFile string `json:"bodyFile,omitempty"`
+}
+
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type Cassette struct {
+	Version      int                   `json:"version"`
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// HttpClient implementation, which records request/response pairs into a
+// cassette file or deterministically replays them from a previously recorded one
+type CassetteHttpClient struct {
+	path     string
+	mode     CassetteMode
+	client   HttpClient
+	cassette Cassette
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// Creates a cassette client for the cassette file at given path. In replay
+// mode the cassette is loaded from the file, while in record mode requests are
+// made with provided client and the cassette is written to the file on Save
+func NewCassetteHttpClient(path string, mode CassetteMode, client HttpClient) (*CassetteHttpClient, error) {
+	c := &CassetteHttpClient{
+		path:     path,
+		mode:     mode,
+		client:   client,
+		cassette: Cassette{Version: CASSETTE_VERSION},
+	}
+
+	if mode == CASSETTE_RECORD {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, &c.cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %v", path, err)
+	}
+
+	if c.cassette.Version != CASSETTE_VERSION {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s, expected %d", c.cassette.Version, path, CASSETTE_VERSION)
+	}
+
+	c.used = make([]bool, len(c.cassette.Interactions))
+	return c, nil
+}
+
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+func (c *CassetteHttpClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	request := CassetteRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   body,
+	}
+
+	if c.mode == CASSETTE_RECORD {
+		return c.record(req, request)
+	}
+	return c.replay(request)
+}
+
+func (c *CassetteHttpClient) record(req *http.Request, request CassetteRequest) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := resp.Header.Clone()
+	for _, name := range cassetteSkippedHeaders {
+		header.Del(name)
+	}
+
+	c.mu.Lock()
+	c.cassette.Interactions = append(c.cassette.Interactions, CassetteInteraction{
+		Request: request,
+		Response: CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       string(respBody),
+		},
+	})
+	c.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Answers the request with the first unused matching interaction. If all
+// matching interactions have been used, the last of them is reused
+func (c *CassetteHttpClient) replay(request CassetteRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	match := -1
+	for i, interaction := range c.cassette.Interactions {
+		if interaction.Request != request {
+			continue
+		}
+
+		match = i
+		if !c.used[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s in cassette %s", request.Method, request.URL, c.path)
+	}
+	c.used[match] = true
+
+	response := c.cassette.Interactions[match].Response
+	body := []byte(response.Body)
+	if response.BodyFile != "" {
+		var err error
+		body, err = os.ReadFile(filepath.Join(filepath.Dir(c.path), response.BodyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body file of cassette %s: %v", c.path, err)
+		}
+	}
+
+	header := response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}, nil
+}
+
+// Writes recorded interactions into the cassette file.
+// Does nothing in replay mode
+func (c *CassetteHttpClient) Save() error {
+	if c.mode != CASSETTE_RECORD {
+		return nil
+	}
+
+	// HTML bodies are kept readable by not escaping them
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	c.mu.Lock()
+	err := enc.Encode(c.cassette)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, buf.Bytes(), 0o644)
+}
+
+// Returns the cassette file path of given name in given directory
+func CassettePath(dir string, name string) string {
+	return filepath.Join(dir, strings.ToLower(name)+".json")
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// HttpClient stub, which echoes request bodies back
+type echoClient struct {
+	calls int
+}
+
+func (c *echoClient) Do(req *http.Request) (*http.Response, error) {
+	c.calls++
+	body := req.Method + " " + req.URL.Path
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		body += " " + string(data)
+	}
+
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"text/plain"}, "Set-Cookie": {"session=secret"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func doRequest(t *testing.T, client HttpClient, method string, url string, body string) (*http.Response, string) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, _ := http.NewRequest(method, url, reader)
+	resp, err := client.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestCassetteHttpClient_RecordAndReplay(t *testing.T) {
+	path := CassettePath(t.TempDir(), "Benu")
+	assert.Equal(t, "benu.json", filepath.Base(path))
+
+	stub := &echoClient{}
+	recorder, err := NewCassetteHttpClient(path, CASSETTE_RECORD, stub)
+	assert.NoError(t, err)
+
+	_, body := doRequest(t, recorder, "GET", "https://example.com/a", "")
+	assert.Equal(t, "GET /a", body)
+	_, body = doRequest(t, recorder, "POST", "https://example.com/b", "x=1")
+	assert.Equal(t, "POST /b x=1", body)
+	assert.NoError(t, recorder.Save())
+	assert.Equal(t, 2, stub.calls)
+
+	player, err := NewCassetteHttpClient(path, CASSETTE_REPLAY, nil)
+	assert.NoError(t, err)
+
+	resp, body := doRequest(t, player, "POST", "https://example.com/b", "x=1")
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "POST /b x=1", body)
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Set-Cookie"))
+
+	// interactions can be replayed more than once
+	_, body = doRequest(t, player, "GET", "https://example.com/a", "")
+	assert.Equal(t, "GET /a", body)
+	_, body = doRequest(t, player, "GET", "https://example.com/a", "")
+	assert.Equal(t, "GET /a", body)
+	assert.Equal(t, 2, stub.calls)
+}
+
+func TestCassetteHttpClient_UnmatchedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	os.WriteFile(path, []byte(`{"version":1,"interactions":[{"request":{"method":"GET","url":"https://example.com/a"},"response":{"status":200,"body":"a"}}]}`), 0o644)
+
+	player, err := NewCassetteHttpClient(path, CASSETTE_REPLAY, nil)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "https://example.com/a", strings.NewReader("b"))
+	_, err = player.Do(req)
+	assert.Error(t, err)
+
+	req, _ = http.NewRequest("GET", "https://example.com/b", nil)
+	_, err = player.Do(req)
+	assert.Error(t, err)
+}
+
+func TestCassetteHttpClient_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.html"), []byte("<html></html>"), 0o644)
+	os.WriteFile(filepath.Join(dir, "cassette.json"), []byte(`{"version":1,"interactions":[{"request":{"method":"GET","url":"https://example.com/"},"response":{"status":200,"bodyFile":"page.html"}}]}`), 0o644)
+
+	player, err := NewCassetteHttpClient(filepath.Join(dir, "cassette.json"), CASSETTE_REPLAY, nil)
+	assert.NoError(t, err)
+
+	_, body := doRequest(t, player, "GET", "https://example.com/", "")
+	assert.Equal(t, "<html></html>", body)
+}
+
+func TestCassetteHttpClient_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	os.WriteFile(path, []byte(`{"version":2,"interactions":[]}`), 0o644)
+
+	_, err := NewCassetteHttpClient(path, CASSETTE_REPLAY, nil)
+	assert.Error(t, err)
+}
@@ -1,12 +1,235 @@
 package utils
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
 
 // Small abstraction interface for better DI reasons
 type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// Returned when requests to a host are rejected because
+// the host has failed too many times in a row
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type ResilientHttpClientConfig struct {
+	// Maximum time for establishing a TCP connection
+	ConnectTimeout time.Duration
+	// Maximum time of a single attempt including reading the response body
+	Timeout time.Duration
+	// Maximum amount of retries after the first attempt
+	MaxRetries int
+	// Delay before the first retry, which is doubled on every subsequent retry
+	RetryBaseDelay time.Duration
+	// Upper bound for the retry delay, also caps Retry-After values
+	RetryMaxDelay time.Duration
+	// Minimum interval between requests to the same host
+	HostInterval time.Duration
+	// Amount of consecutive failed requests after which the circuit is opened
+	BreakerThreshold int
+	// Time during which requests to a failing host are rejected
+	BreakerCooldown time.Duration
+}
+
+// Per-host rate limiter and circuit breaker state
+type hostState struct {
+	nextSlot  time.Time
+	failures  int
+	openUntil time.Time
+}
+
+// HttpClient implementation with exponential backoff retries on
+// 5xx and 429 responses, per-host rate limiting and circuit breaking
+type ResilientHttpClient struct {
+	client HttpClient
+	config ResilientHttpClientConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// Reads the client configuration from environment variables
 func ProvideHTTPClient() HttpClient {
-	return http.DefaultClient
+	config := ResilientHttpClientConfig{
+		ConnectTimeout:   getenvDuration("HTTP_CONNECT_TIMEOUT", 5*time.Second),
+		Timeout:          getenvDuration("HTTP_TIMEOUT", 30*time.Second),
+		MaxRetries:       getenvInt("HTTP_MAX_RETRIES", 3),
+		RetryBaseDelay:   getenvDuration("HTTP_RETRY_BASE_DELAY", 500*time.Millisecond),
+		RetryMaxDelay:    getenvDuration("HTTP_RETRY_MAX_DELAY", 30*time.Second),
+		HostInterval:     getenvDuration("HTTP_HOST_INTERVAL", 200*time.Millisecond),
+		BreakerThreshold: getenvInt("HTTP_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  getenvDuration("HTTP_BREAKER_COOLDOWN", time.Minute),
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	return NewResilientHttpClient(&http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}, config)
+}
+
+func NewResilientHttpClient(client HttpClient, config ResilientHttpClientConfig) *ResilientHttpClient {
+	return &ResilientHttpClient{
+		client: client,
+		config: config,
+		hosts:  make(map[string]*hostState),
+	}
+}
+
+func (c *ResilientHttpClient) host(host string) *hostState {
+	state, ok := c.hosts[host]
+	if !ok {
+		state = &hostState{}
+		c.hosts[host] = state
+	}
+	return state
+}
+
+// Reserves the next free request slot of given host and
+// returns the time when the request may be made
+func (c *ResilientHttpClient) reserve(host string) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	state := c.host(host)
+	if now.Before(state.openUntil) {
+		return time.Time{}, fmt.Errorf("%w for host %s", ErrCircuitOpen, host)
+	}
+
+	slot := state.nextSlot
+	if slot.Before(now) {
+		slot = now
+	}
+	state.nextSlot = slot.Add(c.config.HostInterval)
+	return slot, nil
+}
+
+// Records the outcome of a request to given host
+func (c *ResilientHttpClient) record(host string, success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.host(host)
+	if success {
+		state.failures = 0
+		return
+	}
+
+	state.failures++
+	if c.config.BreakerThreshold > 0 && state.failures >= c.config.BreakerThreshold {
+		state.openUntil = time.Now().Add(c.config.BreakerCooldown)
+	}
+}
+
+// Waits for given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Calculates the delay before given retry attempt (starting from 0),
+// Retry-After header of the previous response takes precedence if present
+func (c *ResilientHttpClient) retryDelay(attempt int, resp *http.Response) time.Duration {
+	delay := c.config.RetryBaseDelay << attempt
+	if resp != nil {
+		if after := parseRetryAfter(resp.Header.Get("Retry-After")); after > 0 {
+			delay = after
+		}
+	}
+
+	if delay > c.config.RetryMaxDelay || delay < 0 {
+		delay = c.config.RetryMaxDelay
+	}
+	return delay
+}
+
+// Parses Retry-After header value, which is either
+// delay in seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+func (c *ResilientHttpClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+
+	// requests with a body can only be retried if the body can be recreated
+	maxRetries := c.config.MaxRetries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		slot, err := c.reserve(host)
+		if err != nil {
+			return nil, err
+		}
+		if err := sleepContext(ctx, time.Until(slot)); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		failed := err != nil || isRetryableStatus(resp.StatusCode)
+		c.record(host, !failed)
+
+		if !failed || attempt >= maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := c.retryDelay(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
 }
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// HttpClient stub, which returns responses with given status codes in order
+type statusClient struct {
+	statuses []int
+	headers  []http.Header
+	bodies   []string
+	calls    int
+}
+
+func (c *statusClient) Do(req *http.Request) (*http.Response, error) {
+	i := c.calls
+	c.calls++
+	if i >= len(c.statuses) {
+		return nil, errors.New("connection refused")
+	}
+
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		c.bodies = append(c.bodies, string(body))
+	}
+
+	header := http.Header{}
+	if i < len(c.headers) && c.headers[i] != nil {
+		header = c.headers[i]
+	}
+	return &http.Response{
+		StatusCode: c.statuses[i],
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+func testConfig() ResilientHttpClientConfig {
+	return ResilientHttpClientConfig{
+		MaxRetries:       3,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    10 * time.Millisecond,
+		BreakerThreshold: 100,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+func TestResilientHttpClient_RetriesServerErrors(t *testing.T) {
+	stub := &statusClient{statuses: []int{503, 500, 200}}
+	client := NewResilientHttpClient(stub, testConfig())
+
+	req, _ := http.NewRequest("POST", "https://example.com/", strings.NewReader("secret=xD"))
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 3, stub.calls)
+	assert.Equal(t, []string{"secret=xD", "secret=xD", "secret=xD"}, stub.bodies)
+}
+
+func TestResilientHttpClient_DoesNotRetryClientErrors(t *testing.T) {
+	stub := &statusClient{statuses: []int{404, 200}}
+	client := NewResilientHttpClient(stub, testConfig())
+
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestResilientHttpClient_GivesUpAfterMaxRetries(t *testing.T) {
+	stub := &statusClient{statuses: []int{502, 502, 502, 502, 200}}
+	client := NewResilientHttpClient(stub, testConfig())
+
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 502, resp.StatusCode)
+	assert.Equal(t, 4, stub.calls)
+}
+
+func TestResilientHttpClient_HonorsRetryAfter(t *testing.T) {
+	config := testConfig()
+	config.RetryMaxDelay = time.Second
+	client := NewResilientHttpClient(&statusClient{}, config)
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"1"}}}
+	assert.Equal(t, time.Second, client.retryDelay(0, resp))
+
+	// Retry-After is capped by the maximum retry delay
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, time.Second, client.retryDelay(0, resp))
+
+	// without Retry-After the delay grows exponentially
+	assert.Equal(t, 4*time.Millisecond, client.retryDelay(2, nil))
+}
+
+func TestResilientHttpClient_CircuitBreaker(t *testing.T) {
+	config := testConfig()
+	config.MaxRetries = 0
+	config.BreakerThreshold = 2
+	stub := &statusClient{statuses: []int{500, 500, 200}}
+	client := NewResilientHttpClient(stub, config)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "https://example.com/", nil)
+		client.Do(req)
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, stub.calls)
+
+	// other hosts are not affected
+	req, _ = http.NewRequest("GET", "https://example.org/", nil)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestResilientHttpClient_RateLimitsPerHost(t *testing.T) {
+	config := testConfig()
+	config.HostInterval = 20 * time.Millisecond
+	stub := &statusClient{statuses: []int{200, 200, 200}}
+	client := NewResilientHttpClient(stub, config)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "https://example.com/", nil)
+		client.Do(req)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestResilientHttpClient_CancelledWhileWaiting(t *testing.T) {
+	config := testConfig()
+	config.RetryBaseDelay = time.Minute
+	config.RetryMaxDelay = time.Minute
+	stub := &statusClient{statuses: []int{503, 200}}
+	client := NewResilientHttpClient(stub, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+	_, err := client.Do(req)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, stub.calls)
+}
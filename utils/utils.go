@@ -1,6 +1,10 @@
 package utils
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 // Miscellaneous utility functions //
 
@@ -37,3 +41,24 @@ func Getenv(env string, fallback string) string {
 	}
 	return fallback
 }
+
+// Gets value of the environment variable env as a duration in Go
+// duration format, e.g. "15s". Fallback is used if the value
+// does not exist or cannot be parsed
+func getenvDuration(env string, fallback time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(env))
+	if err != nil || val < 0 {
+		return fallback
+	}
+	return val
+}
+
+// Gets value of the environment variable env as an integer.
+// Fallback is used if the value does not exist or cannot be parsed
+func getenvInt(env string, fallback int) int {
+	val, err := strconv.Atoi(os.Getenv(env))
+	if err != nil {
+		return fallback
+	}
+	return val
+}